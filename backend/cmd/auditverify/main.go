@@ -0,0 +1,41 @@
+// Comentario: recorre la cadena de hashes del registro de auditoría (audit_events) y comprueba que no se ha
+// editado, borrado ni insertado ningún evento. Sale con código 1 si la cadena está rota.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"password-danie/internal/config"
+	sqliteRepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/usecase"
+	"password-danie/pkg/db"
+)
+
+func main() {
+	dsn := flag.String("dsn", "", "DSN de SQLite (por defecto SQLITE_DSN)")
+	batch := flag.Int("batch", 500, "eventos leídos por consulta")
+	flag.Parse()
+
+	if *dsn == "" {
+		*dsn = config.Load().SQLiteDSN
+	}
+	sqlDB, err := db.OpenSQLite(*dsn)
+	if err != nil {
+		log.Fatalf("open sqlite: %v", err)
+	}
+	defer sqlDB.Close()
+
+	rep, err := usecase.NewAudit(sqliteRepo.NewAuditSQLite(sqlDB)).Verify(*batch)
+	if err != nil {
+		log.Fatalf("verify: %v", err)
+	}
+	if rep.BrokenAt != 0 {
+		fmt.Printf("audit chain BROKEN at event %d after %d valid events: %s\n", rep.BrokenAt, rep.Checked, rep.Reason)
+		sqlDB.Close()
+		os.Exit(1)
+	}
+	fmt.Printf("audit chain OK (%d events)\n", rep.Checked)
+}
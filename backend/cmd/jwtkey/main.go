@@ -0,0 +1,50 @@
+// Comentario: genera una clave privada de firma JWT (Ed25519 por defecto, -alg ES256 para P-256)
+// en PKCS#8 base64, lista para JWT_SIGNING_KEYS. Imprime también su kid.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"password-danie/internal/security"
+)
+
+func main() {
+	alg := flag.String("alg", security.AlgEdDSA, "EdDSA o ES256")
+	flag.Parse()
+
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch *alg {
+	case security.AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case security.AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		log.Fatalf("unsupported alg %q", *alg)
+	}
+	if err != nil {
+		log.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		log.Fatalf("marshal key: %v", err)
+	}
+	key, err := security.NewSigningKey(signer)
+	if err != nil {
+		log.Fatalf("signing key: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "kid: %s\n", key.ID)
+	fmt.Println(base64.StdEncoding.EncodeToString(der))
+}
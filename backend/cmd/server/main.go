@@ -1,33 +1,40 @@
-// Comentario: punto de entrada. Carga .env, abre SQLite, aplica migraciones, cablea repos/usecases y levanta Gin.
+// Comentario: punto de entrada. Carga config (.env), abre SQLite, aplica migraciones, cablea repos/usecases y levanta Gin.
 package main
 
 import (
 	"log"
-	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/go-webauthn/webauthn/webauthn"
 
+	"password-danie/internal/blobstore"
+	"password-danie/internal/breach"
+	"password-danie/internal/config"
 	api "password-danie/internal/http"
 	"password-danie/internal/repository"
+	memoryRepo "password-danie/internal/repository/memory"
 	sqliteRepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/security"
 	"password-danie/internal/usecase"
 	"password-danie/pkg/db"
 )
 
 func main() {
-	_ = godotenv.Load()
+	cfg := config.Load() // carga .env y aplica valores por defecto
 
-	dsn := os.Getenv("SQLITE_DSN")
-	if dsn == "" {
-		dsn = "data/app.db"
+	// Claves maestras: sin claves válidas no se arranca (no hay claves por defecto).
+	provider, err := security.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("key provider: %v", err)
 	}
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if err := security.Init(provider); err != nil {
+		log.Fatalf("load keys: %v", err)
 	}
+	log.Printf("keys loaded from %s", provider.Name())
+	security.SetTokenPolicy(security.TokenPolicy{Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience, Leeway: cfg.JWTLeeway})
 
-	sqlDB, err := db.OpenSQLite(dsn)
+	sqlDB, err := db.OpenSQLite(cfg.SQLiteDSN)
 	if err != nil {
 		log.Fatalf("open sqlite: %v", err)
 	}
@@ -40,25 +47,136 @@ func main() {
 
 	// Repos
 	var (
-		userRepo   repository.UserRepo   = sqliteRepo.NewUserSQLite(sqlDB)
-		secretRepo repository.SecretRepo = sqliteRepo.NewSecretSQLite(sqlDB)
+		userRepo   repository.UserRepo         = sqliteRepo.NewUserSQLite(sqlDB)
+		secretRepo repository.SecretRepo       = sqliteRepo.NewSecretSQLite(sqlDB)
+		keyRepo    repository.KeyRepo          = sqliteRepo.NewKeySQLite(sqlDB)
+		rotRepo    repository.RotationRepo     = sqliteRepo.NewRotationSQLite(sqlDB)
+		tokenRepo  repository.RefreshTokenRepo = sqliteRepo.NewRefreshTokenSQLite(sqlDB)
+		sessRepo   repository.SessionRepo      = sqliteRepo.NewSessionSQLite(sqlDB)
+		mfaRepo    repository.MFARepo          = sqliteRepo.NewMFASQLite(sqlDB)
+		passRepo   repository.WebAuthnRepo     = sqliteRepo.NewWebAuthnSQLite(sqlDB)
+		auditRepo  repository.AuditRepo        = sqliteRepo.NewAuditSQLite(sqlDB)
+		attachRepo repository.AttachmentRepo   = sqliteRepo.NewAttachmentSQLite(sqlDB)
 	)
 
 	// Casos de uso
-	authUC := usecase.NewAuth(userRepo)
-	vaultUC := usecase.NewVault(secretRepo)
-	resetUC := usecase.NewPasswordReset(userRepo)
+	hasher, err := security.NewArgon2idHasher(security.Argon2idParams{
+		Memory: cfg.Argon2Memory, Iterations: cfg.Argon2Time, Parallelism: cfg.Argon2Threads,
+	})
+	if err != nil {
+		log.Fatalf("password hasher: %v", err)
+	}
+	// Volcado de contraseñas filtradas: en modo warn se avisa, en block se rechaza la contraseña
+	// maestra. Las entradas del vault solo se marcan.
+	var breaches breach.Dataset
+	if cfg.BreachDataset != "" && cfg.BreachMode != "off" {
+		if cfg.BreachMode != "warn" && cfg.BreachMode != "block" {
+			log.Fatalf("BREACH_MODE must be off, warn or block")
+		}
+		if breaches, err = breach.Open(cfg.BreachDataset); err != nil {
+			log.Fatalf("breach dataset: %v", err)
+		}
+		cfg.PasswordPolicy.Breaches = breaches
+		cfg.PasswordPolicy.BlockBreached = cfg.BreachMode == "block"
+		log.Printf("breach dataset loaded (%s)", cfg.BreachMode)
+	}
+	auditUC := usecase.NewAudit(auditRepo)
+	sessionsUC := usecase.NewSessions(sessRepo, tokenRepo, 30*time.Second)
+	// Contadores de fallos: en SQLite sobreviven a reinicios; en memoria solo valen con una réplica.
+	var attemptRepo repository.AttemptRepo
+	switch cfg.ThrottleStore {
+	case "sqlite":
+		attemptRepo = sqliteRepo.NewAttemptSQLite(sqlDB)
+	case "memory":
+		attemptRepo = memoryRepo.NewAttemptMemory()
+	default:
+		log.Fatalf("THROTTLE_STORE must be sqlite or memory")
+	}
+	throttle := usecase.NewThrottle(attemptRepo, cfg.AccountThrottle, cfg.IPThrottle)
+	mfaUC := usecase.NewMFA(mfaRepo, userRepo, cfg.TOTPIssuer, cfg.MFAChallengeTTL)
+	rp, err := webauthn.New(&webauthn.Config{RPID: cfg.WebAuthnRPID, RPDisplayName: cfg.WebAuthnRPName, RPOrigins: cfg.WebAuthnOrigins})
+	if err != nil {
+		log.Fatalf("webauthn: %v", err)
+	}
+	passkeysUC := usecase.NewPasskeys(passRepo, userRepo, rp, cfg.MFAChallengeTTL)
+	preloginKey, err := usecase.PreloginKey(keyRepo)
+	if err != nil {
+		log.Fatalf("prelogin key: %v", err)
+	}
+	authUC := usecase.NewAuth(userRepo, tokenRepo, sessionsUC, mfaUC, passkeysUC, throttle, auditUC, hasher, cfg.PasswordPolicy, preloginKey,
+		cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	vaultUC := usecase.NewVault(secretRepo, keyRepo, userRepo, breaches, auditUC)
+	blobs, err := blobstore.NewFS(cfg.AttachmentsDir)
+	if err != nil {
+		log.Fatalf("attachments dir: %v", err)
+	}
+	attachUC := usecase.NewAttachments(attachRepo, vaultUC, blobs, cfg.Attachments, auditUC)
+	historyUC := usecase.NewHistory(vaultUC, cfg.History)
+	resetUC := usecase.NewPasswordReset(userRepo, secretRepo, sessionsUC, throttle, auditUC, hasher, cfg.PasswordPolicy)
+	rotationUC := usecase.NewKeyRotation(rotRepo, 100, 50*time.Millisecond)
+
+	// Rotación de clave maestra: si AES_KEY cambió de versión (o quedó un trabajo a medias),
+	// re-cifra por lotes en segundo plano mientras el servidor atiende peticiones.
+	job, err := rotationUC.Prepare()
+	if err != nil {
+		log.Fatalf("key rotation: %v", err)
+	}
+	if job != nil {
+		go func() {
+			log.Printf("key rotation #%d -> v%d started (phase=%s)", job.ID, job.TargetVersion, job.Phase)
+			if err := rotationUC.Run(job); err != nil {
+				log.Printf("key rotation #%d failed: %v", job.ID, err)
+				return
+			}
+			log.Printf("key rotation #%d done (%d rows)", job.ID, job.Processed)
+		}()
+	}
+
+	// Entradas antiguas con campos en claro: se cifran en segundo plano (idempotente).
+	go func() {
+		n, err := vaultUC.UpgradeLegacy(100)
+		if err != nil {
+			log.Printf("vault upgrade: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("vault upgrade: %d entries sealed", n)
+		}
+	}()
+
+	// Papelera: lo que lleva más de TRASH_RETENTION se borra del todo, con sus adjuntos e historial.
+	if cfg.TrashRetention > 0 && cfg.TrashPurgeEvery > 0 {
+		go func() {
+			for {
+				n, err := vaultUC.PurgeExpired(cfg.TrashRetention, 100)
+				if err != nil {
+					log.Printf("trash purge: %v", err)
+				} else if n > 0 {
+					log.Printf("trash purge: %d entries deleted", n)
+				}
+				time.Sleep(cfg.TrashPurgeEvery)
+			}
+		}()
+	}
 
 	// HTTP
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 
 	ready := func() error { return sqlDB.Ping() }
-	api.RegisterRoutes(r, authUC, vaultUC, ready) 
+	api.RegisterRoutes(r, authUC, sessionsUC, vaultUC, ready)
 	api.RegisterResetRoutes(r, resetUC)
+	api.RegisterMFARoutes(r, authUC, mfaUC, sessionsUC)
+	api.RegisterWebAuthnRoutes(r, authUC, passkeysUC, sessionsUC)
+	api.RegisterGenerateRoutes(r, sessionsUC)
+	api.RegisterAuditRoutes(r, auditUC, sessionsUC)
+	api.RegisterAttachmentRoutes(r, attachUC, sessionsUC)
+	api.RegisterHistoryRoutes(r, historyUC, sessionsUC)
+	api.RegisterTrashRoutes(r, vaultUC, sessionsUC)
+	api.RegisterAdminRoutes(r, throttle, auditUC, cfg.AdminToken)
 
-	log.Printf("listening on :%s (dsn=%s)", port, dsn)
-	if err := r.Run(":" + port); err != nil {
+	log.Printf("listening on :%s (dsn=%s)", cfg.Port, cfg.SQLiteDSN)
+	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("http server: %v", err)
 	}
 }
@@ -0,0 +1,156 @@
+// Package breach consulta sin red un volcado local de Have I Been Pwned (SHA-1): un fichero único
+// ordenado por hash ("HASH:COUNT" por línea) o un directorio de shards por prefijo de 5 caracteres
+// ("PREFIX.txt" con líneas "SUFIJO:COUNT", el formato de la API de rangos). Las búsquedas son binarias
+// sobre el fichero en disco: O(log n) lecturas, sin cargarlo en memoria.
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Dataset dice cuántas veces aparece una contraseña en filtraciones conocidas (0 = ninguna).
+type Dataset interface {
+	Count(password string) (int, error)
+}
+
+// Open detecta el formato: si path es un directorio se usan shards, si no un fichero ordenado.
+func Open(path string) (Dataset, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &shardDir{dir: path}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sortedFile{f: f, size: info.Size()}, nil
+}
+
+func hashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// sortedFile es el volcado completo "pwned-passwords-sha1-ordered-by-hash".
+type sortedFile struct {
+	f    *os.File
+	size int64
+}
+
+func (s *sortedFile) Count(password string) (int, error) {
+	return searchLines(s.f, s.size, hashPassword(password))
+}
+
+func (s *sortedFile) Close() error { return s.f.Close() }
+
+// shardDir es un directorio con un fichero por prefijo (00000.txt ... FFFFF.txt).
+type shardDir struct {
+	dir string
+}
+
+func (d *shardDir) Count(password string) (int, error) {
+	h := hashPassword(password)
+	prefix, suffix := h[:5], h[5:]
+	for _, name := range []string{prefix + ".txt", prefix, strings.ToLower(prefix) + ".txt"} {
+		f, err := os.Open(filepath.Join(d.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return searchLines(f, info.Size(), suffix)
+	}
+	// Sin shard para el prefijo: ningún hash con ese prefijo está en el volcado.
+	return 0, nil
+}
+
+// maxLine acota lo que se lee por línea: 40 hex + ':' + contador + "\r\n" cabe de sobra.
+const maxLine = 128
+
+// searchLines hace búsqueda binaria de key en un fichero de líneas "KEY:COUNT" ordenadas por KEY.
+// Invariante: lo siempre es el inicio de una línea y, si key está, su línea empieza en [lo, hi).
+func searchLines(r io.ReaderAt, size int64, key string) (int, error) {
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := lineStart(r, mid, lo)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		line, next, err := readLine(r, start, size)
+		if err != nil {
+			return 0, err
+		}
+		k, count, _ := strings.Cut(line, ":")
+		switch c := strings.Compare(strings.ToUpper(k), key); {
+		case c == 0:
+			n, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil {
+				return 0, fmt.Errorf("breach dataset: bad count in %q", line)
+			}
+			return n, nil
+		case c < 0:
+			lo = next
+		default:
+			hi = start
+		}
+	}
+	return 0, nil
+}
+
+// lineStart devuelve el inicio de la primera línea que empieza en off o después.
+func lineStart(r io.ReaderAt, off, lo int64) (int64, error) {
+	if off == lo {
+		return off, nil
+	}
+	buf := make([]byte, maxLine)
+	n, err := r.ReadAt(buf, off-1)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if i := strings.IndexByte(string(buf[:n]), '\n'); i >= 0 {
+		return off + int64(i), nil
+	}
+	if err == io.EOF {
+		return off + int64(n), nil
+	}
+	return 0, errors.New("breach dataset: line too long")
+}
+
+// readLine lee la línea que empieza en off y devuelve también el inicio de la siguiente.
+func readLine(r io.ReaderAt, off, size int64) (string, int64, error) {
+	buf := make([]byte, maxLine)
+	n, err := r.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	s := string(buf[:n])
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimRight(s[:i], "\r"), off + int64(i) + 1, nil
+	}
+	if off+int64(n) >= size {
+		return strings.TrimRight(s, "\r"), size, nil
+	}
+	return "", 0, errors.New("breach dataset: line too long")
+}
@@ -4,18 +4,46 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"password-danie/internal/policy"
+	"password-danie/internal/usecase"
 )
 
 type Config struct {
 	Port            string
 	SQLiteDSN       string
-	JWTSecret       string
+	JWTSigningKeys  string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
 	AESKey          string
+	JWTIssuer       string
+	JWTAudience     string
+	JWTLeeway       time.Duration
+	Argon2Memory    uint32 // KiB
+	Argon2Time      uint32
+	Argon2Threads   uint8
+	PasswordPolicy  policy.Policy
+	BreachDataset   string // volcado HIBP local (fichero ordenado o directorio de shards)
+	BreachMode      string // off | warn | block
+	TOTPIssuer      string
+	MFAChallengeTTL time.Duration
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+	ThrottleStore   string // sqlite | memory
+	AccountThrottle usecase.ThrottlePolicy
+	IPThrottle      usecase.ThrottlePolicy
+	AdminToken      string
+	AttachmentsDir  string
+	Attachments     usecase.AttachmentLimits
+	History         usecase.HistoryPolicy
+	TrashRetention  time.Duration
+	TrashPurgeEvery time.Duration
 }
 
 func Load() *Config {
@@ -23,18 +51,85 @@ func Load() *Config {
 
 	port := getEnv("PORT", "8080")
 	sqlite := getEnv("SQLITE_DSN", "data/app.db")
-	jwtSecret := getEnv("JWT_SECRET", "dev-secret-change-me")
+	jwtKeys := getEnv("JWT_SIGNING_KEYS", "") // sin valor por defecto: lo valida security.Init
 	accessTTL := getEnvDuration("ACCESS_TOKEN_TTL", "15m")
 	refreshTTL := getEnvDuration("REFRESH_TOKEN_TTL", "168h") // 7d
-	aesKey := getEnv("AES_KEY", "0123456789abcdef0123456789abcdef")
+	aesKey := getEnv("AES_KEY", "")
+	issuer := getEnv("JWT_ISSUER", "password-danie")
+	audience := getEnv("JWT_AUDIENCE", "password-danie-api")
+	leeway := getEnvDuration("JWT_LEEWAY", "30s") // margen de reloj para exp/nbf/iat
+	// Argon2id para contraseñas de cuenta (por defecto 64 MiB, 3 pasadas, 2 hilos)
+	argonMemory := getEnvUint("ARGON2_MEMORY_KIB", 64*1024, 32)
+	argonTime := getEnvUint("ARGON2_ITERATIONS", 3, 32)
+	argonThreads := getEnvUint("ARGON2_PARALLELISM", 2, 8)
+	// Política de contraseña maestra (longitud en caracteres, clases de 4, puntuación 0..4)
+	pol := policy.Default()
+	pol.MinLength = getEnvInt("PASSWORD_MIN_LENGTH", pol.MinLength)
+	pol.MinClasses = getEnvInt("PASSWORD_MIN_CLASSES", pol.MinClasses)
+	pol.MinScore = getEnvInt("PASSWORD_MIN_SCORE", pol.MinScore)
+	// Contraseñas filtradas: sin volcado no se comprueba nada
+	breachDataset := getEnv("BREACH_DATASET", "")
+	breachMode := getEnv("BREACH_MODE", "warn")
+	// Segundo factor: nombre en las apps de autenticación y vida del reto entre contraseña y código
+	totpIssuer := getEnv("TOTP_ISSUER", "password-danie")
+	mfaTTL := getEnvDuration("MFA_CHALLENGE_TTL", "5m")
+	// Passkeys: el RP ID es el dominio del frontend y los orígenes deben coincidir exactamente con el navegador
+	rpID := getEnv("WEBAUTHN_RP_ID", "localhost")
+	rpName := getEnv("WEBAUTHN_RP_NAME", "password-danie")
+	origins := getEnvList("WEBAUTHN_ORIGINS", "http://localhost:5173")
+	// Fuerza bruta: fallos tolerados por cuenta e IP antes de la espera exponencial y bloqueo máximo
+	throttleStore := getEnv("THROTTLE_STORE", "sqlite")
+	acct, ipPol := usecase.DefaultAccountThrottle(), usecase.DefaultIPThrottle()
+	acct.FreeAttempts = getEnvInt("LOGIN_MAX_FAILURES", acct.FreeAttempts)
+	ipPol.FreeAttempts = getEnvInt("LOGIN_IP_MAX_FAILURES", ipPol.FreeAttempts)
+	acct.MaxDelay = getEnvDuration("LOCKOUT_MAX", acct.MaxDelay.String())
+	ipPol.MaxDelay = getEnvDuration("LOGIN_IP_LOCKOUT_MAX", ipPol.MaxDelay.String())
+	// Sin ADMIN_TOKEN no se montan las rutas de administración
+	adminToken := getEnv("ADMIN_TOKEN", "")
+	// Adjuntos: directorio de blobs cifrados, tamaño máximo por fichero y cuota por usuario (bytes en claro)
+	attachDir := getEnv("ATTACHMENTS_DIR", "data/attachments")
+	attach := usecase.DefaultAttachmentLimits()
+	attach.MaxFileSize = int64(getEnvInt("ATTACHMENT_MAX_BYTES", int(attach.MaxFileSize)))
+	attach.Quota = int64(getEnvInt("ATTACHMENT_QUOTA_BYTES", int(attach.Quota)))
+	// Historial de versiones: cuántas se guardan por entrada y durante cuánto (0 = sin límite)
+	history := usecase.DefaultHistoryPolicy()
+	history.MaxRevisions = getEnvInt("HISTORY_MAX_REVISIONS", history.MaxRevisions)
+	history.MaxAge = getEnvDuration("HISTORY_MAX_AGE", history.MaxAge.String())
+	history.AllFields = getEnv("HISTORY_ALL_FIELDS", "false") == "true"
+	// Papelera: tiempo hasta el borrado definitivo (0 = nunca) y cada cuánto se revisa
+	trashRetention := getEnvDuration("TRASH_RETENTION", "720h") // 30d
+	trashPurgeEvery := getEnvDuration("TRASH_PURGE_INTERVAL", "1h")
 
 	return &Config{
 		Port:            port,
 		SQLiteDSN:       sqlite,
-		JWTSecret:       jwtSecret,
+		JWTSigningKeys:  jwtKeys,
 		AccessTokenTTL:  accessTTL,
 		RefreshTokenTTL: refreshTTL,
 		AESKey:          aesKey,
+		JWTIssuer:       issuer,
+		JWTAudience:     audience,
+		JWTLeeway:       leeway,
+		Argon2Memory:    uint32(argonMemory),
+		Argon2Time:      uint32(argonTime),
+		Argon2Threads:   uint8(argonThreads),
+		PasswordPolicy:  pol,
+		BreachDataset:   breachDataset,
+		BreachMode:      breachMode,
+		TOTPIssuer:      totpIssuer,
+		MFAChallengeTTL: mfaTTL,
+		WebAuthnRPID:    rpID,
+		WebAuthnRPName:  rpName,
+		WebAuthnOrigins: origins,
+		ThrottleStore:   throttleStore,
+		AccountThrottle: acct,
+		IPThrottle:      ipPol,
+		AdminToken:      adminToken,
+		AttachmentsDir:  attachDir,
+		Attachments:     attach,
+		History:         history,
+		TrashRetention:  trashRetention,
+		TrashPurgeEvery: trashPurgeEvery,
 	}
 }
 
@@ -45,6 +140,17 @@ func getEnv(key, def string) string {
 	return def
 }
 
+// getEnvList lee una lista separada por comas (se ignoran espacios y elementos vacíos).
+func getEnvList(key, def string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnv(key, def), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func getEnvDuration(key, def string) time.Duration {
 	s := getEnv(key, def)
 	d, err := time.ParseDuration(s)
@@ -54,3 +160,30 @@ func getEnvDuration(key, def string) time.Duration {
 	}
 	return d
 }
+
+func getEnvInt(key string, def int) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		log.Printf("invalid integer for %s, using default %d", key, def)
+		return def
+	}
+	return n
+}
+
+// getEnvUint lee un entero sin signo que quepa en bitSize bits; fuera de rango usa def, como getEnvInt.
+func getEnvUint(key string, def uint64, bitSize int) uint64 {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		log.Printf("invalid %d-bit unsigned integer for %s, using default %d", bitSize, key, def)
+		return def
+	}
+	return n
+}
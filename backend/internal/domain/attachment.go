@@ -0,0 +1,19 @@
+// Package domain define entidades del dominio. Attachment es un fichero adjunto a una entrada del vault.
+package domain
+
+import "time"
+
+// Attachment: el contenido va cifrado en el almacén de blobs con una clave propia (FileKey), que a su vez
+// se guarda envuelta con la clave de datos del usuario. Name y ContentType se guardan sellados.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"-"`
+	SecretID    int64     `json:"secret_id"`
+	BlobKey     string    `json:"-"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"` // bytes en claro; es lo que cuenta para la cuota
+	KeyCipher   string    `json:"-"`
+	KeyIV       string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
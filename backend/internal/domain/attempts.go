@@ -0,0 +1,16 @@
+// Package domain define entidades del dominio. Attempts es el contador de fallos de una cuenta o IP.
+package domain
+
+import "time"
+
+type Attempts struct {
+	Key         string     `json:"key"`
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until"`
+}
+
+// Locked dice si el bloqueo sigue vigente en now.
+func (a *Attempts) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
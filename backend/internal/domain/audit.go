@@ -0,0 +1,73 @@
+// Package domain define entidades del dominio. AuditEvent es un evento del registro de auditoría encadenado por hashes.
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Acciones auditadas.
+const (
+	AuditLogin          = "auth.login"
+	AuditResetRequest   = "auth.reset_request"
+	AuditResetConfirm   = "auth.reset_confirm"
+	AuditSecretCreate   = "vault.create"
+	AuditSecretUpdate   = "vault.update"
+	AuditSecretDelete   = "vault.delete"
+	AuditSecretRestore  = "vault.restore"
+	AuditSecretPurge    = "vault.purge"
+	AuditSecretReveal   = "vault.reveal"
+	AuditSecretOTP      = "vault.otp"
+	AuditAttachUpload   = "vault.attachment_upload"
+	AuditAttachGet      = "vault.attachment_download"
+	AuditAttachDelete   = "vault.attachment_delete"
+	AuditHistoryReveal  = "vault.history_reveal"
+	AuditHistoryRestore = "vault.history_restore"
+	AuditResultSuccess  = "success"
+	AuditResultFailure  = "failure"
+)
+
+// AuditGenesis es el prev_hash del primer evento.
+var AuditGenesis = strings.Repeat("0", 64)
+
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"` // 0 si no corresponde a ninguna cuenta (p. ej. login con un email desconocido)
+	Actor      string    `json:"actor"`   // email del usuario o el introducido en el intento
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type,omitempty"`
+	TargetID   int64     `json:"target_id,omitempty"`
+	Result     string    `json:"result"`
+	Detail     string    `json:"detail,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// AuditTime es el formato de created_at: se guarda como texto para que el hash se pueda recalcular igual.
+const AuditTime = time.RFC3339Nano
+
+// Seal encadena el evento tras prev y calcula su hash.
+func (e *AuditEvent) Seal(prev string) {
+	e.CreatedAt = e.CreatedAt.UTC()
+	e.PrevHash = prev
+	e.Hash = e.Digest()
+}
+
+// Digest es SHA-256 sobre prev_hash y los campos del evento, cada uno con su longitud delante para que
+// no se puedan mover bytes de un campo a otro.
+func (e *AuditEvent) Digest() string {
+	h := sha256.New()
+	for _, f := range []string{
+		e.PrevHash, strconv.FormatInt(e.UserID, 10), e.Actor, e.IP, e.UserAgent, e.Action,
+		e.TargetType, strconv.FormatInt(e.TargetID, 10), e.Result, e.Detail, e.CreatedAt.UTC().Format(AuditTime),
+	} {
+		h.Write([]byte(strconv.Itoa(len(f)) + ":" + f))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
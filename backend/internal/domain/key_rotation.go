@@ -0,0 +1,29 @@
+// Package domain define entidades del dominio. KeyRotation guarda el progreso de una rotación de clave maestra.
+package domain
+
+import "time"
+
+// Fases y estados de una rotación.
+const (
+	RotationPhaseUserKeys = "user_keys" // re-envolver claves de datos
+	RotationPhaseSecrets  = "secrets"   // re-cifrar secretos legados (SchemeMasterKey)
+	RotationPhaseTOTP     = "totp"      // re-cifrar secretos TOTP
+	RotationPhaseDone     = "done"
+
+	RotationRunning = "running"
+	RotationDone    = "done"
+	RotationFailed  = "failed"
+)
+
+type KeyRotation struct {
+	ID            int64
+	TargetVersion int
+	Phase         string
+	LastID        int64 // cursor dentro de la fase (user_id o secret id)
+	Processed     int
+	Status        string
+	Error         string
+	StartedAt     time.Time
+	UpdatedAt     time.Time
+	FinishedAt    time.Time
+}
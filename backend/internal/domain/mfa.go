@@ -0,0 +1,28 @@
+// Package domain define entidades del dominio. TOTP y MFAChallenge modelan el segundo factor de login.
+package domain
+
+import "time"
+
+// TOTP es el segundo factor de un usuario. El secreto va cifrado con la clave maestra; LastStep es
+// el último paso de tiempo aceptado (protección contra reutilizar un código).
+type TOTP struct {
+	UserID       int64
+	SecretCipher string
+	SecretIV     string
+	KeyVersion   int
+	LastStep     int64
+	EnabledAt    *time.Time // nil = alta sin confirmar
+	CreatedAt    time.Time
+}
+
+func (t *TOTP) Enabled() bool { return t.EnabledAt != nil }
+
+// MFAChallenge es el paso intermedio del login: contraseña correcta, falta el segundo factor.
+type MFAChallenge struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    *time.Time
+}
@@ -0,0 +1,17 @@
+// Package domain define entidades del dominio. RefreshToken es un refresh token opaco del que solo se guarda el hash.
+package domain
+
+import "time"
+
+// FamilyID agrupa la cadena de
+// rotaciones que nace en un login: si se reutiliza un token ya usado, se revoca toda la familia.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
@@ -3,17 +3,68 @@ package domain
 
 import "time"
 
+// Esquemas de cifrado de la contraseña de un Secret.
+const (
+	SchemeMasterKey = 0 // legado: cifrada directamente con AES_KEY
+	SchemeDataKey   = 1 // sobre: cifrada con la clave de datos del usuario
+	SchemeClient    = 2 // zero-knowledge: blob opaco cifrado en el cliente
+	SchemeSealed    = 3 // sobre: contraseña y campos sensibles cifrados con la clave de datos
+	SchemeBound     = 4 // como SchemeSealed, con cada cifrado ligado (AAD) a usuario, entrada y campo
+)
+
+// Tipos de entrada. Login usa las columnas de siempre (usuario, contraseña, URL); el resto guarda sus
+// campos propios en Fields (ver el esquema de cada tipo en usecase).
+const (
+	ItemLogin    = "login"
+	ItemNote     = "note"
+	ItemCard     = "card"
+	ItemIdentity = "identity"
+	ItemSSHKey   = "ssh_key"
+	ItemAPIKey   = "api_key"
+)
+
+// Tipos de campo personalizado. Hidden se muestra como la contraseña (solo al revelar) y linked apunta a
+// otro campo de la entrada por su nombre (username, password o un campo del tipo).
+const (
+	CustomText    = "text"
+	CustomHidden  = "hidden"
+	CustomBoolean = "boolean"
+	CustomLinked  = "linked"
+)
+
+// CustomField es un campo personalizado; ID lo asigna el servidor y no cambia al renombrar o reordenar.
+type CustomField struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Value string `json:"value,omitempty"`
+}
+
 type Secret struct {
-	ID             int64     `json:"id"`
-	UserID         int64     `json:"user_id"`
-	Username       string    `json:"username"`
-	URL            string    `json:"url"`
-	URLDomain      string    `json:"url_domain"` 
-	Notes          string    `json:"notes"`
-	Icon           string    `json:"icon"`
-	Title          string    `json:"title"`      
-	PasswordCipher string    `json:"-"`        
-	PasswordIV     string    `json:"-"`        
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             int64             `json:"id"`
+	UserID         int64             `json:"user_id"`
+	Type           string            `json:"type"`
+	Username       string            `json:"username"`
+	URL            string            `json:"url"`
+	URLDomain      string            `json:"url_domain"`
+	Notes          string            `json:"notes"`
+	Icon           string            `json:"icon"`
+	Title          string            `json:"title"`
+	PasswordCipher string            `json:"-"`
+	PasswordIV     string            `json:"-"`
+	EncScheme      int               `json:"-"`
+	KeyVersion     int               `json:"-"`                       // versión de la clave maestra (solo SchemeMasterKey)
+	Blob           string            `json:"blob,omitempty"`          // solo SchemeClient
+	DomainIndex    string            `json:"-"`                       // índice ciego de URLDomain (SchemeSealed/Bound)
+	SearchTokens   []string          `json:"-"`                       // índices ciegos de búsqueda (SchemeSealed/Bound)
+	Breached       bool              `json:"breached"`                // la contraseña aparece en filtraciones conocidas
+	OTP            string            `json:"-"`                       // URI otpauth:// con la semilla TOTP/HOTP (sellada)
+	HasOTP         bool              `json:"has_otp"`                 // los códigos se piden a /entries/:id/totp
+	Fields         map[string]string `json:"fields,omitempty"`        // campos del tipo; los secretos solo salen al revelar
+	Payload        string            `json:"-"`                       // Fields sellados (JSON)
+	CustomFields   []CustomField     `json:"custom_fields,omitempty"` // en orden; los hidden salen sin valor salvo al revelar
+	CustomPayload  string            `json:"-"`                       // CustomFields sellados (JSON)
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`    // en la papelera desde entonces
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
 }
@@ -0,0 +1,15 @@
+// Package domain define entidades del dominio. SecretRevision es una versión anterior de una entrada del vault.
+package domain
+
+import "time"
+
+// SecretRevision guarda los valores que tenía una entrada antes de un cambio, solo de los campos que
+// cambiaron. Payload va sellado con la clave de datos del usuario y Changed se rellena al descifrarlo.
+type SecretRevision struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	SecretID  int64     `json:"secret_id"`
+	Changed   []string  `json:"changed"`
+	Payload   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
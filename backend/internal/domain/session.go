@@ -0,0 +1,13 @@
+// Package domain define entidades del dominio. Session es un login: agrupa sus access tokens (claim sid) y su familia de refresh tokens.
+package domain
+
+import "time"
+
+type Session struct {
+	ID        string
+	UserID    int64
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (s *Session) Revoked() bool { return s.RevokedAt != nil }
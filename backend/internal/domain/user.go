@@ -1,14 +1,40 @@
-// Package domain define entidades del dominio. User incluye soporte de reset de contraseña.
+// Package domain define entidades del dominio. User incluye soporte de reset de contraseña y modo zero-knowledge.
 package domain
 
 import "time"
 
+// Modos de vault: en "server" el servidor cifra; en "zero_knowledge" solo guarda blobs cifrados por el cliente.
+const (
+	VaultModeServer        = "server"
+	VaultModeZeroKnowledge = "zero_knowledge"
+)
+
+// Algoritmos KDF que el cliente puede usar para derivar su clave a partir de la contraseña maestra.
+const (
+	KDFPBKDF2SHA256 = "pbkdf2-sha256"
+	KDFArgon2id     = "argon2id"
+)
+
+// KDFParams son los parámetros que el cliente necesita para volver a derivar su clave (el servidor solo los guarda).
+type KDFParams struct {
+	Algorithm   string `json:"algorithm"`
+	Iterations  int    `json:"iterations"`
+	Memory      int    `json:"memory,omitempty"`      // KiB (argon2id)
+	Parallelism int    `json:"parallelism,omitempty"` // hilos (argon2id)
+	Salt        string `json:"salt"`                  // base64
+}
+
 type User struct {
 	ID             int64     `json:"id"`
 	Email          string    `json:"email"`
-	PasswordHash   string    `json:"-"` 
-	ResetToken     string    `json:"-"` 
-	ResetExpiresAt time.Time `json:"-"` 
+	PasswordHash   string    `json:"-"`
+	ResetToken     string    `json:"-"`
+	ResetExpiresAt time.Time `json:"-"`
+	VaultMode      string    `json:"vault_mode"`
+	KDF            KDFParams `json:"-"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
+
+// ZeroKnowledge indica si el servidor no debe ver nunca el contenido del vault del usuario.
+func (u *User) ZeroKnowledge() bool { return u.VaultMode == VaultModeZeroKnowledge }
@@ -0,0 +1,12 @@
+// Package domain define entidades del dominio. UserKey es la clave de datos de un usuario envuelta con la clave maestra.
+package domain
+
+import "time"
+
+type UserKey struct {
+	UserID     int64
+	WrappedKey string
+	WrappedIV  string
+	KeyVersion int // versión de la clave maestra que la envuelve
+	CreatedAt  time.Time
+}
@@ -0,0 +1,37 @@
+// Package domain define entidades del dominio. WebAuthnCredential es una passkey registrada por un usuario.
+package domain
+
+import "time"
+
+type WebAuthnCredential struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"-"`
+	CredentialID    []byte     `json:"-"`
+	PublicKey       []byte     `json:"-"` // clave COSE tal cual la entrega el autenticador
+	AttestationType string     `json:"-"`
+	Transports      []string   `json:"transports"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	Name            string     `json:"name"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
+
+// Propósitos de una ceremonia WebAuthn.
+const (
+	WebAuthnRegister = "register" // alta de una passkey (sesión iniciada)
+	WebAuthnLogin    = "login"    // login sin contraseña con passkey descubrible
+	WebAuthnMFA      = "mfa"      // segundo factor tras la contraseña
+)
+
+// WebAuthnSession guarda el estado (reto incluido) de una ceremonia entre begin y finish.
+type WebAuthnSession struct {
+	ID        int64
+	TokenHash string
+	UserID    int64
+	Purpose   string
+	Data      string // JSON de la sesión de la librería WebAuthn
+	ExpiresAt time.Time
+}
@@ -1,12 +1,72 @@
 // Package dto contiene structs de petición/respuesta para el borde HTTP del módulo de auth.
 package dto
 
+import (
+	"encoding/json"
+
+	"password-danie/internal/domain"
+)
+
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"` // longitud y fortaleza las valida policy
+	// Modo zero-knowledge: Password es el hash de autenticación que el cliente deriva con KDF.
+	ZeroKnowledge bool              `json:"zero_knowledge"`
+	KDF           *domain.KDFParams `json:"kdf"`
+}
+
+type PreloginRequest struct {
+	Email string `json:"email" binding:"required,email"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// Code admite un código TOTP de 6 dígitos o un código de recuperación.
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Ceremonias WebAuthn: credential es la respuesta de navigator.credentials.* tal cual (JSON con base64url).
+type WebAuthnRegisterRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Name         string          `json:"name" binding:"max=64"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnLoginRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type WebAuthnMFARequest struct {
+	MFAToken     string          `json:"mfa_token" binding:"required"`
+	SessionToken string          `json:"session_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+// AdminUnlockRequest: al menos uno de los dos.
+type AdminUnlockRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+	IP    string `json:"ip" binding:"omitempty,ip"`
+}
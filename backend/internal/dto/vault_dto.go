@@ -1,13 +1,24 @@
 // Package dto contiene structs de petición/respuesta para el módulo de vault.
 package dto
 
+// En modo zero-knowledge solo se envía Blob (cifrado en el cliente); el resto de campos no aplica.
+// Type elige el tipo de entrada (login por defecto); Fields lleva los campos propios del tipo.
 type CreateSecretRequest struct {
-	Username      string  `json:"username" binding:"required"`
-	PasswordPlain string  `json:"password_plain" binding:"required"`
+	Type          string  `json:"type"`
+	Username      string  `json:"username"`
+	PasswordPlain string  `json:"password_plain"`
 	URL           string  `json:"url"`
 	Notes         string  `json:"notes"`
 	Icon          string  `json:"icon"`
 	Title         *string `json:"title"` 
+	Blob          *string `json:"blob"`
+	OTPURI        string  `json:"otp_uri"` // otpauth:// con la semilla TOTP/HOTP de la cuenta
+
+	Fields       map[string]string    `json:"fields"`
+	CustomFields []CustomFieldRequest `json:"custom_fields"`
+
+	// Generate: sin password_plain, el servidor genera la contraseña con estas opciones y la devuelve.
+	Generate *GenerateRequest `json:"generate"`
 }
 
 type UpdateSecretRequest struct {
@@ -17,4 +28,39 @@ type UpdateSecretRequest struct {
 	Notes         *string `json:"notes"`
 	Icon          *string `json:"icon"`
 	Title         *string `json:"title"`
+	Blob          *string `json:"blob"`
+	OTPURI        *string `json:"otp_uri"` // "" quita la semilla
+
+	// Fields es un cambio parcial: las claves enviadas sustituyen a las guardadas y "" las borra.
+	Fields map[string]string `json:"fields"`
+	// CustomFields es la lista completa y en orden: los IDs que falten se borran y [] quita todos.
+	CustomFields []CustomFieldRequest `json:"custom_fields"`
+}
+
+// CustomFieldRequest: sin id es un campo nuevo; con id, lo que no se envíe conserva lo guardado.
+type CustomFieldRequest struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Kind  string  `json:"kind"` // text (por defecto), hidden, boolean o linked
+	Value *string `json:"value"`
+}
+
+// GenerateRequest: los campos omitidos toman el valor por defecto del generador (contraseña de 20
+// caracteres con las cuatro clases, o frase de 6 palabras separadas por guiones).
+type GenerateRequest struct {
+	Type             string  `json:"type"` // password (por defecto) o passphrase
+	Length           int     `json:"length"`
+	Lowercase        *bool   `json:"lowercase"`
+	Uppercase        *bool   `json:"uppercase"`
+	Digits           *bool   `json:"digits"`
+	Symbols          *bool   `json:"symbols"`
+	ExcludeAmbiguous bool    `json:"exclude_ambiguous"`
+	MinLowercase     int     `json:"min_lowercase"`
+	MinUppercase     int     `json:"min_uppercase"`
+	MinDigits        int     `json:"min_digits"`
+	MinSymbols       int     `json:"min_symbols"`
+	Words            int     `json:"words"`
+	Separator        *string `json:"separator"`
+	Capitalize       bool    `json:"capitalize"`
+	IncludeNumber    bool    `json:"include_number"`
 }
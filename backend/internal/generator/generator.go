@@ -0,0 +1,335 @@
+// Package generator genera contraseñas aleatorias y frases de contraseña estilo diceware con crypto/rand
+// e informa de su entropía. Las contraseñas se eligen de forma uniforme entre todas las que cumplen los
+// mínimos por clase, así que la entropía declarada es exacta.
+package generator
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+//go:embed eff_large_wordlist.txt
+var wordList string
+
+var words = loadWords(wordList)
+
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+	symbols   = "!#$%&*+-=?@^_~.,:;()[]{}<>/|"
+	ambiguous = "Il1O0o|" // se confunden según la tipografía
+)
+
+// Límites de longitud (caracteres) y de número de palabras.
+const (
+	MinLength = 8
+	MaxLength = 128
+	MinWords  = 3
+	MaxWords  = 20
+)
+
+// Tipos de resultado.
+const (
+	KindPassword   = "password"
+	KindPassphrase = "passphrase"
+)
+
+// Options elige el tipo y lleva las opciones de ese tipo; Kind vacío es KindPassword.
+type Options struct {
+	Kind       string
+	Password   PasswordOptions
+	Passphrase PassphraseOptions
+}
+
+var errNoClasses = errors.New("at least one character class must be enabled")
+
+// PasswordOptions: Min* obliga a que salgan al menos tantos caracteres de esa clase (la clase debe estar activa).
+type PasswordOptions struct {
+	Length           int
+	Lowercase        bool
+	Uppercase        bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+	MinLowercase     int
+	MinUppercase     int
+	MinDigits        int
+	MinSymbols       int
+}
+
+// DefaultPassword: 20 caracteres de las cuatro clases, ~128 bits.
+func DefaultPassword() PasswordOptions {
+	return PasswordOptions{Length: 20, Lowercase: true, Uppercase: true, Digits: true, Symbols: true}
+}
+
+// PassphraseOptions: Number añade un dígito al final de una de las palabras, elegida al azar.
+type PassphraseOptions struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+	Number     bool
+}
+
+func DefaultPassphrase() PassphraseOptions {
+	return PassphraseOptions{Words: 6, Separator: "-"}
+}
+
+// Result: Entropy en bits frente a un atacante que conoce las opciones de generación.
+type Result struct {
+	Kind    string  `json:"kind"`
+	Value   string  `json:"value"`
+	Entropy float64 `json:"entropy"`
+}
+
+// Generate despacha a Password o Passphrase según o.Kind.
+func Generate(o Options) (*Result, error) {
+	switch o.Kind {
+	case "", KindPassword:
+		return Password(o.Password)
+	case KindPassphrase:
+		return Passphrase(o.Passphrase)
+	default:
+		return nil, fmt.Errorf("unknown kind %q", o.Kind)
+	}
+}
+
+type charClass struct {
+	chars []rune
+	min   int
+}
+
+// Password genera una contraseña uniforme entre todas las de esa longitud que cumplen los mínimos: primero
+// se sortea cuántos caracteres hay de cada clase (con el peso de cuántas contraseñas tienen ese reparto),
+// luego sus posiciones y por último cada carácter dentro de su clase.
+func Password(o PasswordOptions) (*Result, error) {
+	if o.Length < MinLength || o.Length > MaxLength {
+		return nil, fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+	}
+	classes, err := o.classes()
+	if err != nil {
+		return nil, err
+	}
+	t := newCounter(classes, o.Length)
+	total := t.count[len(classes)][o.Length]
+
+	// Reparto por clases, de la última a la primera.
+	per := make([]int, len(classes))
+	n := o.Length
+	for c := len(classes); c > 0; c-- {
+		r, err := rand.Int(rand.Reader, t.count[c][n])
+		if err != nil {
+			return nil, err
+		}
+		for k := classes[c-1].min; k <= n; k++ {
+			w := t.arrangements(c, n, k)
+			if r.Cmp(w) < 0 {
+				per[c-1] = k
+				break
+			}
+			r.Sub(r, w)
+		}
+		n -= per[c-1]
+	}
+
+	// Posiciones: barajado uniforme de las etiquetas de clase.
+	labels := make([]int, 0, o.Length)
+	for c, k := range per {
+		for i := 0; i < k; i++ {
+			labels = append(labels, c)
+		}
+	}
+	if err := shuffle(labels); err != nil {
+		return nil, err
+	}
+	out := make([]rune, len(labels))
+	for i, c := range labels {
+		j, err := randIndex(len(classes[c].chars))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = classes[c].chars[j]
+	}
+	return &Result{Kind: KindPassword, Value: string(out), Entropy: round(log2(total))}, nil
+}
+
+// Passphrase elige palabras de la lista larga de EFF (7776 palabras, 12,9 bits cada una).
+func Passphrase(o PassphraseOptions) (*Result, error) {
+	if o.Words < MinWords || o.Words > MaxWords {
+		return nil, fmt.Errorf("words must be between %d and %d", MinWords, MaxWords)
+	}
+	if len(o.Separator) > 3 {
+		return nil, errors.New("separator must be at most 3 characters")
+	}
+	picked := make([]string, o.Words)
+	for i := range picked {
+		j, err := randIndex(len(words))
+		if err != nil {
+			return nil, err
+		}
+		picked[i] = words[j]
+		if o.Capitalize {
+			picked[i] = strings.ToUpper(picked[i][:1]) + picked[i][1:]
+		}
+	}
+	bits := float64(o.Words) * math.Log2(float64(len(words)))
+	if o.Number {
+		w, err := randIndex(o.Words)
+		if err != nil {
+			return nil, err
+		}
+		d, err := randIndex(len(digits))
+		if err != nil {
+			return nil, err
+		}
+		picked[w] += digits[d : d+1]
+		bits += math.Log2(float64(o.Words * len(digits)))
+	}
+	return &Result{Kind: KindPassphrase, Value: strings.Join(picked, o.Separator), Entropy: round(bits)}, nil
+}
+
+// classes devuelve las clases activas con su alfabeto ya filtrado.
+func (o PasswordOptions) classes() ([]charClass, error) {
+	all := []struct {
+		on    bool
+		chars string
+		min   int
+		name  string
+	}{
+		{o.Lowercase, lowercase, o.MinLowercase, "lowercase"},
+		{o.Uppercase, uppercase, o.MinUppercase, "uppercase"},
+		{o.Digits, digits, o.MinDigits, "digits"},
+		{o.Symbols, symbols, o.MinSymbols, "symbols"},
+	}
+	var out []charClass
+	required := 0
+	for _, c := range all {
+		if c.min < 0 {
+			return nil, fmt.Errorf("minimum %s must not be negative", c.name)
+		}
+		if !c.on {
+			if c.min > 0 {
+				return nil, fmt.Errorf("minimum %s requires %s to be enabled", c.name, c.name)
+			}
+			continue
+		}
+		chars := c.chars
+		if o.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		out = append(out, charClass{chars: []rune(chars), min: c.min})
+		required += c.min
+	}
+	if len(out) == 0 {
+		return nil, errNoClasses
+	}
+	if required > o.Length {
+		return nil, errors.New("minimum counts exceed the length")
+	}
+	return out, nil
+}
+
+// counter cuenta contraseñas: count[c][n] es cuántas cadenas de longitud n usan solo las c primeras clases
+// cumpliendo sus mínimos. Binomiales y potencias se precalculan porque se usan miles de veces.
+type counter struct {
+	classes []charClass
+	binom   [][]*big.Int // binom[n][k] = C(n,k)
+	pow     [][]*big.Int // pow[c][k] = len(clase c)^k
+	count   [][]*big.Int
+}
+
+func newCounter(classes []charClass, length int) *counter {
+	t := &counter{classes: classes}
+	t.binom = make([][]*big.Int, length+1)
+	for n := range t.binom {
+		t.binom[n] = make([]*big.Int, n+1)
+		t.binom[n][0], t.binom[n][n] = big.NewInt(1), big.NewInt(1)
+		for k := 1; k < n; k++ {
+			t.binom[n][k] = new(big.Int).Add(t.binom[n-1][k-1], t.binom[n-1][k])
+		}
+	}
+	t.pow = make([][]*big.Int, len(classes))
+	for c, cl := range classes {
+		size := big.NewInt(int64(len(cl.chars)))
+		t.pow[c] = make([]*big.Int, length+1)
+		t.pow[c][0] = big.NewInt(1)
+		for k := 1; k <= length; k++ {
+			t.pow[c][k] = new(big.Int).Mul(t.pow[c][k-1], size)
+		}
+	}
+	t.count = make([][]*big.Int, len(classes)+1)
+	t.count[0] = make([]*big.Int, length+1)
+	for n := range t.count[0] {
+		t.count[0][n] = new(big.Int)
+	}
+	t.count[0][0].SetInt64(1)
+	for c := 1; c <= len(classes); c++ {
+		t.count[c] = make([]*big.Int, length+1)
+		for n := 0; n <= length; n++ {
+			sum := new(big.Int)
+			for k := classes[c-1].min; k <= n; k++ {
+				sum.Add(sum, t.arrangements(c, n, k))
+			}
+			t.count[c][n] = sum
+		}
+	}
+	return t
+}
+
+// arrangements = C(n,k) · size^k · count[c-1][n-k]: k posiciones de n para la clase c y el resto
+// rellenas con las clases anteriores.
+func (t *counter) arrangements(c, n, k int) *big.Int {
+	w := new(big.Int).Mul(t.binom[n][k], t.pow[c-1][k])
+	return w.Mul(w, t.count[c-1][n-k])
+}
+
+func shuffle(s []int) error {
+	for i := len(s) - 1; i > 0; i-- {
+		j, err := randIndex(i + 1)
+		if err != nil {
+			return err
+		}
+		s[i], s[j] = s[j], s[i]
+	}
+	return nil
+}
+
+func randIndex(n int) (int, error) {
+	r, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(r.Int64()), nil
+}
+
+// log2 de un entero grande sin pasar por float64 (que se desborda por encima de 2^1024).
+func log2(x *big.Int) float64 {
+	shift := max(x.BitLen()-63, 0)
+	top := new(big.Int).Rsh(x, uint(shift))
+	return math.Log2(float64(top.Uint64())) + float64(shift)
+}
+
+func round(bits float64) float64 {
+	return math.Round(bits*10) / 10
+}
+
+func loadWords(list string) []string {
+	var out []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+// Handlers HTTP de administración: bloqueos por intentos fallidos de login y reset, y verificación
+// de la cadena del registro de auditoría.
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/dto"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+// RegisterAdminRoutes no monta nada si adminToken está vacío.
+func RegisterAdminRoutes(r *gin.Engine, throttle *usecase.Throttle, auditUC *usecase.Audit, adminToken string) {
+	if adminToken == "" {
+		return
+	}
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.AdminRequired(adminToken))
+
+	admin.GET("/lockouts", func(c *gin.Context) {
+		items, err := throttle.Locked()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	// Desbloquea una cuenta (todos sus ámbitos), una IP o ambas.
+	admin.POST("/lockouts/unlock", func(c *gin.Context) {
+		var req dto.AdminUnlockRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Email == "" && req.IP == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email or ip required"})
+			return
+		}
+		if err := throttle.Unlock(req.Email, req.IP); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Recorre la cadena entera: en instalaciones grandes mejor con cmd/auditverify fuera de horas.
+	admin.GET("/audit/verify", func(c *gin.Context) {
+		rep, err := auditUC.Verify(500)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rep)
+	})
+}
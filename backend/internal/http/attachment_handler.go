@@ -0,0 +1,109 @@
+// Handlers HTTP de adjuntos del vault: subida multipart en streaming, descarga, listado y borrado.
+package http
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+// multipartOverhead es el margen sobre el tamaño máximo de fichero para cabeceras y límites del multipart.
+const multipartOverhead = 1 << 20
+
+func RegisterAttachmentRoutes(r *gin.Engine, attachUC *usecase.Attachments, sessionsUC *usecase.Sessions) {
+	v := r.Group("/api/v1/vault")
+	v.Use(middleware.AuthRequired(sessionsUC))
+
+	v.GET("/entries/:id/attachments", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		items, used, err := attachUC.List(userIDFromClaims(c), id)
+		if err != nil {
+			writeAttachmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "used_bytes": used, "quota_bytes": attachUC.Limits().Quota})
+	})
+
+	// Subida: multipart/form-data con el fichero en el campo "file". El cuerpo se lee por partes y se
+	// cifra al vuelo, nunca se guarda entero en memoria ni en un temporal en claro.
+	v.POST("/entries/:id/attachments", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachUC.Limits().MaxFileSize+multipartOverhead)
+		mr, err := c.Request.MultipartReader()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart/form-data body with a file field required"})
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "file field required"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if part.FormName() != "file" {
+				continue
+			}
+			att, err := attachUC.Upload(userIDFromClaims(c), id, part.FileName(), part.Header.Get("Content-Type"), part,
+				c.ClientIP(), c.Request.UserAgent())
+			if err != nil {
+				writeAttachmentError(c, err)
+				return
+			}
+			c.JSON(http.StatusCreated, att)
+			return
+		}
+	})
+
+	// Descarga: siempre como fichero adjunto y sin adivinar el tipo, para que el navegador no lo interprete.
+	v.GET("/entries/:id/attachments/:attachmentId", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		attachmentID, _ := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+		att, rc, err := attachUC.Open(userIDFromClaims(c), id, attachmentID, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			writeAttachmentError(c, err)
+			return
+		}
+		defer rc.Close()
+		noStore(c)
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+		c.Header("Content-Type", att.ContentType)
+		c.Header("Content-Length", strconv.FormatInt(att.Size, 10))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		// Un trozo manipulado a mitad de fichero corta la respuesta: el cliente ve menos bytes que Content-Length.
+		if _, err := io.Copy(c.Writer, rc); err != nil {
+			log.Printf("attachment %d download: %v", att.ID, err)
+		}
+	})
+
+	v.DELETE("/entries/:id/attachments/:attachmentId", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		attachmentID, _ := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+		if err := attachUC.Delete(userIDFromClaims(c), id, attachmentID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			writeAttachmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+}
+
+// writeAttachmentError: 413 si se pasa de tamaño o de cuota; el resto como writeError.
+func writeAttachmentError(c *gin.Context, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.Is(err, usecase.ErrAttachmentTooLarge) || errors.Is(err, usecase.ErrQuotaExceeded) || errors.As(err, &tooBig) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	writeError(c, err)
+}
@@ -0,0 +1,28 @@
+// Handlers HTTP del registro de auditoría: cada usuario ve solo sus propios eventos.
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterAuditRoutes(r *gin.Engine, auditUC *usecase.Audit, sessionsUC *usecase.Sessions) {
+	api := r.Group("/api/v1")
+	api.Use(middleware.AuthRequired(sessionsUC))
+
+	// Del más reciente al más antiguo.
+	api.GET("/audit", func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		items, total, err := auditUC.List(userIDFromClaims(c), limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+	})
+}
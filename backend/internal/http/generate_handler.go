@@ -0,0 +1,64 @@
+// Handler HTTP del generador de contraseñas y frases de contraseña.
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/dto"
+	"password-danie/internal/generator"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterGenerateRoutes(r *gin.Engine, sessionsUC *usecase.Sessions) {
+	api := r.Group("/api/v1")
+	api.Use(middleware.AuthRequired(sessionsUC))
+
+	api.POST("/generate", func(c *gin.Context) {
+		var req dto.GenerateRequest
+		// Sin cuerpo se genera con las opciones por defecto.
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		res, err := generator.Generate(generateOptions(req))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, res)
+	})
+}
+
+// generateOptions parte de los valores por defecto y aplica solo lo que venga en la petición.
+func generateOptions(req dto.GenerateRequest) generator.Options {
+	o := generator.Options{Kind: req.Type, Password: generator.DefaultPassword(), Passphrase: generator.DefaultPassphrase()}
+	p := &o.Password
+	if req.Length != 0 {
+		p.Length = req.Length
+	}
+	for _, f := range []struct {
+		dst *bool
+		src *bool
+	}{{&p.Lowercase, req.Lowercase}, {&p.Uppercase, req.Uppercase}, {&p.Digits, req.Digits}, {&p.Symbols, req.Symbols}} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	p.ExcludeAmbiguous = req.ExcludeAmbiguous
+	p.MinLowercase, p.MinUppercase, p.MinDigits, p.MinSymbols = req.MinLowercase, req.MinUppercase, req.MinDigits, req.MinSymbols
+
+	pp := &o.Passphrase
+	if req.Words != 0 {
+		pp.Words = req.Words
+	}
+	if req.Separator != nil {
+		pp.Separator = *req.Separator
+	}
+	pp.Capitalize, pp.Number = req.Capitalize, req.IncludeNumber
+	return o
+}
@@ -0,0 +1,49 @@
+// Handlers HTTP del historial de versiones de las entradas del vault: listado, revelado y restauración.
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterHistoryRoutes(r *gin.Engine, historyUC *usecase.History, sessionsUC *usecase.Sessions) {
+	v := r.Group("/api/v1/vault")
+	v.Use(middleware.AuthRequired(sessionsUC))
+
+	// Listado sin valores: solo cuándo y qué campos cambiaron.
+	v.GET("/entries/:id/history", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		items, err := historyUC.List(userIDFromClaims(c), id)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	v.GET("/entries/:id/history/:revisionId", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		revisionID, _ := strconv.ParseInt(c.Param("revisionId"), 10, 64)
+		noStore(c)
+		rev, err := historyUC.Reveal(userIDFromClaims(c), id, revisionID, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rev)
+	})
+
+	v.POST("/entries/:id/history/:revisionId/restore", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		revisionID, _ := strconv.ParseInt(c.Param("revisionId"), 10, 64)
+		if err := historyUC.Restore(userIDFromClaims(c), id, revisionID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+}
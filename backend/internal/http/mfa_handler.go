@@ -0,0 +1,86 @@
+// Handlers HTTP del segundo factor: segundo paso del login y alta/baja de TOTP con códigos de recuperación.
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/dto"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterMFARoutes(r *gin.Engine, authUC *usecase.Auth, mfaUC *usecase.MFA, sessionsUC *usecase.Sessions) {
+	api := r.Group("/api/v1")
+
+	// Segundo paso del login: canjea el mfa_token de /auth/login por el par de tokens.
+	api.POST("/auth/login/mfa", func(c *gin.Context) {
+		var req dto.LoginMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		res, err := authUC.LoginMFA(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			if writeLockedError(c, err) {
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, tokenResponse(res))
+	})
+
+	me := api.Group("/users/me/mfa")
+	me.Use(middleware.AuthRequired(sessionsUC))
+
+	me.GET("", func(c *gin.Context) {
+		st, err := mfaUC.Status(userIDFromClaims(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, st)
+	})
+
+	// Alta: devuelve el secreto y la URI otpauth:// (para el QR). No se activa hasta confirmar.
+	me.POST("/totp", func(c *gin.Context) {
+		enr, err := mfaUC.Enroll(userIDFromClaims(c))
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, enr)
+	})
+
+	// Confirmación con un primer código: activa el TOTP y entrega los códigos de recuperación (una sola vez).
+	me.POST("/totp/confirm", func(c *gin.Context) {
+		var req dto.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		codes, err := mfaUC.Confirm(userIDFromClaims(c), req.Code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	})
+
+	me.POST("/totp/disable", func(c *gin.Context) {
+		var req dto.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := mfaUC.Disable(userIDFromClaims(c), req.Code); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"password-danie/internal/domain"
 	"password-danie/internal/usecase"
 )
 
@@ -19,8 +20,11 @@ func RegisterResetRoutes(r *gin.Engine, resetUC *usecase.PasswordReset) {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
-		token, err := resetUC.Request(req.Email)
+		token, err := resetUC.Request(req.Email, c.ClientIP(), c.Request.UserAgent())
 		if err != nil {
+			if writeLockedError(c, err) {
+				return
+			}
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
@@ -31,16 +35,34 @@ func RegisterResetRoutes(r *gin.Engine, resetUC *usecase.PasswordReset) {
 	api.POST("/confirm", func(c *gin.Context) {
 		var req struct {
 			Token       string `json:"token" binding:"required"`
-			NewPassword string `json:"new_password" binding:"required,min=8"`
+			NewPassword string `json:"new_password" binding:"required"`
+			// Solo cuentas zero-knowledge: nuevos parámetros KDF y aceptación del borrado del vault.
+			KDF       *domain.KDFParams `json:"kdf"`
+			WipeVault bool              `json:"wipe_vault"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
-		if err := resetUC.Confirm(req.Token, req.NewPassword); err != nil {
+		var zk *usecase.ZeroKnowledgeReset
+		if req.KDF != nil {
+			zk = &usecase.ZeroKnowledgeReset{KDF: *req.KDF, WipeVault: req.WipeVault}
+		}
+		result, err := resetUC.Confirm(req.Token, req.NewPassword, zk, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			if writePolicyError(c, err) {
+				return
+			}
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"ok": true})
+		res := gin.H{"ok": true}
+		if zk != nil {
+			res["vault_wiped"] = result.VaultWiped
+		}
+		if len(result.Warnings) > 0 {
+			res["warnings"] = result.Warnings
+		}
+		c.JSON(http.StatusOK, res)
 	})
 }
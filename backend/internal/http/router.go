@@ -3,6 +3,7 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,13 +11,16 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"password-danie/internal/domain"
 	"password-danie/internal/dto"
 	"password-danie/internal/middleware"
+	"password-danie/internal/policy"
+	"password-danie/internal/security"
 	"password-danie/internal/usecase"
 )
 
 // RegisterRoutes registra endpoints públicos y protegidos sobre el *gin.Engine* recibido.
-func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault, readyCheck func() error) {
+func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, sessionsUC *usecase.Sessions, vaultUC *usecase.Vault, readyCheck func() error) {
 	// Evita warning de proxies y aplica CORS
 	_ = r.SetTrustedProxies(nil)
 
@@ -47,6 +51,17 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 		c.Status(http.StatusNoContent)
 	})
 
+	// --- JWKS: claves públicas para que otros servicios verifiquen los tokens ---
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		set, err := security.PublicJWKS()
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "keys not loaded"})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, set)
+	})
+
 	// --- Health / Ready ---
 	r.GET("/healthz", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
 	r.GET("/readyz", func(c *gin.Context) {
@@ -66,12 +81,47 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		u, err := authUC.Register(req.Email, req.Password)
+		var (
+			u        *domain.User
+			warnings []policy.Reason
+			err      error
+		)
+		if req.ZeroKnowledge {
+			if req.KDF == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "kdf parameters required in zero-knowledge mode"})
+				return
+			}
+			u, err = authUC.RegisterZeroKnowledge(req.Email, req.Password, *req.KDF)
+		} else {
+			u, warnings, err = authUC.Register(req.Email, req.Password)
+		}
 		if err != nil {
+			if writePolicyError(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, struct {
+			*domain.User
+			Warnings []policy.Reason `json:"warnings,omitempty"`
+		}{u, warnings})
+	})
+
+	// Prelogin: parámetros KDF para que el cliente zero-knowledge derive su clave antes del login (el modo
+	// del vault no se revela aquí; llega con el usuario en la respuesta del login).
+	api.POST("/auth/prelogin", func(c *gin.Context) {
+		var req dto.PreloginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusCreated, u)
+		kdf, err := authUC.Prelogin(req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"kdf": kdf})
 	})
 
 	api.POST("/auth/login", func(c *gin.Context) {
@@ -80,27 +130,92 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		token, u, err := authUC.Login(req.Email, req.Password)
+		res, err := authUC.Login(req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
 		if err != nil {
+			if writeLockedError(c, err) {
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"access_token": token, "user": u})
+		noStore(c)
+		if res.MFAToken != "" {
+			// Falta el segundo factor: se completa en /auth/login/mfa.
+			c.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": res.MFAToken, "mfa_methods": res.MFAMethods,
+				"expires_in": int64(res.ExpiresIn.Seconds())})
+			return
+		}
+		c.JSON(http.StatusOK, tokenResponse(res))
+	})
+
+	// Canjea un refresh token (un solo uso) por un par nuevo.
+	api.POST("/auth/refresh", func(c *gin.Context) {
+		var req dto.RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		res, err := authUC.Refresh(req.RefreshToken)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, tokenResponse(res))
 	})
 
 	// --- Grupo protegido ---
 	authGroup := api.Group("")
-	authGroup.Use(middleware.AuthRequired())
+	authGroup.Use(middleware.AuthRequired(sessionsUC))
+
+	// Logout: revoca la sesión del token (y su refresh token) o todas las del usuario.
+	authGroup.POST("/auth/logout", func(c *gin.Context) {
+		if err := sessionsUC.Logout(userIDFromClaims(c), c.GetString(middleware.CtxSessionID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	authGroup.POST("/auth/logout-all", func(c *gin.Context) {
+		n, err := sessionsUC.LogoutAll(userIDFromClaims(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revoked_sessions": n})
+	})
 
 	// users/me (lee claims del contexto que dejó el middleware)
 	authGroup.GET("/users/me", func(c *gin.Context) {
-		if claims, ok := c.Get("claims"); ok {
+		if claims, ok := c.Get(middleware.CtxClaims); ok {
 			c.JSON(http.StatusOK, gin.H{"claims": claims})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "claims not found"})
 	})
 
+	// Cambio de contraseña maestra (cuentas "server"): cierra el resto de sesiones.
+	authGroup.POST("/users/me/password", func(c *gin.Context) {
+		var req dto.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		warnings, err := authUC.ChangePassword(userIDFromClaims(c), c.GetString(middleware.CtxSessionID), req.CurrentPassword, req.NewPassword)
+		if err != nil {
+			if writePolicyError(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(warnings) > 0 {
+			c.JSON(http.StatusOK, gin.H{"warnings": warnings})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
 	// --- Vault CRUD ---
 	v := authGroup.Group("/vault")
 
@@ -108,9 +223,10 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 		uid := userIDFromClaims(c)
 		q := c.Query("q")
 		domain := c.Query("domain")
+		itemType := c.Query("type")
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-		items, total, err := vaultUC.List(uid, q, domain, limit, offset)
+		items, total, err := vaultUC.List(uid, q, domain, itemType, limit, offset)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -125,11 +241,32 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		id, err := vaultUC.Create(uid, req.Username, req.PasswordPlain, req.URL, req.Notes, req.Icon, req.Title)
+		var (
+			id        int64
+			generated string
+			err       error
+		)
+		if req.Blob != nil {
+			id, err = vaultUC.CreateOpaque(uid, *req.Blob, c.ClientIP(), c.Request.UserAgent())
+		} else {
+			in := usecase.CreateSecretInput{Type: req.Type, Username: req.Username, PasswordPlain: req.PasswordPlain, URL: req.URL, Notes: req.Notes,
+				Icon: req.Icon, Title: req.Title, OTPURI: req.OTPURI, Fields: req.Fields, CustomFields: customFieldInputs(req.CustomFields)}
+			if req.Generate != nil {
+				o := generateOptions(*req.Generate)
+				in.Generate = &o
+			}
+			id, generated, err = vaultUC.Create(uid, in, c.ClientIP(), c.Request.UserAgent())
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		// La contraseña generada solo se devuelve esta vez; después se lee con /entries/:id/password.
+		if generated != "" {
+			noStore(c)
+			c.JSON(http.StatusCreated, gin.H{"id": id, "password": generated})
+			return
+		}
 		c.JSON(http.StatusCreated, gin.H{"id": id})
 	})
 
@@ -148,6 +285,33 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 		c.JSON(http.StatusOK, s)
 	})
 
+	// Revelado de la contraseña (o de los campos secretos en los tipos que no son login): endpoint aparte
+	// de la vista detallada y sin caché.
+	v.GET("/entries/:id/password", func(c *gin.Context) {
+		uid := userIDFromClaims(c)
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		noStore(c)
+		revealed, err := vaultUC.Reveal(uid, id, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, revealed)
+	})
+
+	// Código TOTP/HOTP actual de la semilla guardada en la entrada (la semilla nunca sale del servidor).
+	v.GET("/entries/:id/totp", func(c *gin.Context) {
+		uid := userIDFromClaims(c)
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		noStore(c)
+		code, err := vaultUC.OTP(uid, id, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, code)
+	})
+
 	v.PUT("/entries/:id", func(c *gin.Context) {
 		uid := userIDFromClaims(c)
 		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -156,7 +320,15 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if err := vaultUC.Update(uid, id, req.Username, req.PasswordPlain, req.URL, req.Notes, req.Icon, req.Title); err != nil {
+		var err error
+		if req.Blob != nil {
+			err = vaultUC.UpdateOpaque(uid, id, *req.Blob, c.ClientIP(), c.Request.UserAgent())
+		} else {
+			in := usecase.UpdateSecretInput{Username: req.Username, PasswordPlain: req.PasswordPlain, URL: req.URL, Notes: req.Notes, Icon: req.Icon,
+				Title: req.Title, OTPURI: req.OTPURI, Fields: req.Fields, CustomFields: customFieldInputs(req.CustomFields)}
+			err = vaultUC.Update(uid, id, in, c.ClientIP(), c.Request.UserAgent())
+		}
+		if err != nil {
 			if err.Error() == "not found" {
 				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			} else {
@@ -167,46 +339,80 @@ func RegisterRoutes(r *gin.Engine, authUC *usecase.Auth, vaultUC *usecase.Vault,
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
+	// Borrar manda la entrada a la papelera (ver RegisterTrashRoutes).
 	v.DELETE("/entries/:id", func(c *gin.Context) {
 		uid := userIDFromClaims(c)
 		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
-		if err := vaultUC.Delete(uid, id); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := vaultUC.Delete(uid, id, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 }
 
-// userIDFromClaims obtiene el userID preferentemente del contexto (middleware) y si no, de los claims.
-func userIDFromClaims(c *gin.Context) int64 {
-	// 1) Preferir el valor que dejó el middleware
-	if v, ok := c.Get(middleware.CtxUserID); ok {
-		switch t := v.(type) {
-		case int64:
-			return t
-		case int:
-			return int64(t)
-		case float64:
-			return int64(t)
-		}
+// noStore evita que navegadores o proxies guarden respuestas con secretos en claro.
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+}
+
+// customFieldInputs conserva la diferencia entre lista ausente (nil: no se toca) y vacía (se quitan todos).
+func customFieldInputs(req []dto.CustomFieldRequest) []usecase.CustomFieldInput {
+	if req == nil {
+		return nil
 	}
-	// 2) Fallback: leer de los claims (por compatibilidad)
-	claims, ok := c.Get("claims")
-	if !ok {
-		return 0
+	out := make([]usecase.CustomFieldInput, 0, len(req))
+	for _, f := range req {
+		out = append(out, usecase.CustomFieldInput{ID: f.ID, Name: f.Name, Kind: f.Kind, Value: f.Value})
 	}
-	m, ok := claims.(map[string]any)
-	if !ok {
-		return 0
+	return out
+}
+
+// writePolicyError responde 422 con los motivos estructurados si err viene de la política de contraseñas.
+func writePolicyError(c *gin.Context, err error) bool {
+	var perr *policy.Error
+	if !errors.As(err, &perr) {
+		return false
 	}
-	switch v := m["sub"].(type) {
-	case float64:
-		return int64(v)
-	case int64:
-		return v
-	case int:
-		return int64(v)
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": perr.Error(), "reasons": perr.Reasons, "score": perr.Score})
+	return true
+}
+
+// writeLockedError responde 429 con Retry-After (segundos, redondeando hacia arriba) si err es un bloqueo por intentos.
+func writeLockedError(c *gin.Context, err error) bool {
+	var lerr *usecase.LockedError
+	if !errors.As(err, &lerr) {
+		return false
+	}
+	secs := int64((lerr.RetryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(secs, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": lerr.Error(), "retry_after": secs})
+	return true
+}
+
+// writeError responde 404 si err es "not found" y 400 con el mensaje en cualquier otro caso.
+func writeError(c *gin.Context, err error) {
+	if err.Error() == "not found" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
 	}
-	return 0
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// tokenResponse da forma a la respuesta de login/refresh (expires_in en segundos, como OAuth2).
+func tokenResponse(res *usecase.LoginResult) gin.H {
+	return gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int64(res.ExpiresIn.Seconds()),
+		"user":          res.User,
+	}
+}
+
+// userIDFromClaims devuelve el userID que dejó el middleware (0 si la ruta no está protegida).
+func userIDFromClaims(c *gin.Context) int64 {
+	return c.GetInt64(middleware.CtxUserID)
 }
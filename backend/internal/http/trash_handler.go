@@ -0,0 +1,46 @@
+// Handlers HTTP de la papelera del vault: listado, recuperación y borrado definitivo.
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterTrashRoutes(r *gin.Engine, vaultUC *usecase.Vault, sessionsUC *usecase.Sessions) {
+	v := r.Group("/api/v1/vault")
+	v.Use(middleware.AuthRequired(sessionsUC))
+
+	v.GET("/trash", func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		items, total, err := vaultUC.ListTrash(userIDFromClaims(c), limit, offset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+	})
+
+	v.POST("/trash/:id/restore", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err := vaultUC.Restore(userIDFromClaims(c), id, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// Borrado definitivo: solo de entradas que ya están en la papelera.
+	v.DELETE("/trash/:id", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err := vaultUC.Purge(userIDFromClaims(c), id, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+}
@@ -0,0 +1,127 @@
+// Handlers HTTP de passkeys: ceremonias de alta y de aserción (login sin contraseña o segundo factor)
+// y gestión de las credenciales del usuario.
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"password-danie/internal/dto"
+	"password-danie/internal/middleware"
+	"password-danie/internal/usecase"
+)
+
+func RegisterWebAuthnRoutes(r *gin.Engine, authUC *usecase.Auth, passkeysUC *usecase.Passkeys, sessionsUC *usecase.Sessions) {
+	wa := r.Group("/api/v1/auth/webauthn")
+
+	// Login sin contraseña: opciones para navigator.credentials.get() sin lista de credenciales.
+	wa.POST("/login/begin", func(c *gin.Context) {
+		opts, token, err := passkeysUC.BeginLogin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, gin.H{"session_token": token, "options": opts})
+	})
+
+	wa.POST("/login/finish", func(c *gin.Context) {
+		var req dto.WebAuthnLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		res, err := authUC.LoginPasskey(req.SessionToken, req.Credential, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, tokenResponse(res))
+	})
+
+	// Segundo factor: la aserción se limita a las passkeys del usuario del mfa_token.
+	wa.POST("/mfa/begin", func(c *gin.Context) {
+		var req dto.WebAuthnMFABeginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts, token, err := authUC.BeginPasskeyMFA(req.MFAToken)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, gin.H{"session_token": token, "options": opts})
+	})
+
+	wa.POST("/mfa/finish", func(c *gin.Context) {
+		var req dto.WebAuthnMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		res, err := authUC.LoginPasskeyMFA(req.MFAToken, req.SessionToken, req.Credential, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			if writeLockedError(c, err) {
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, tokenResponse(res))
+	})
+
+	me := wa.Group("")
+	me.Use(middleware.AuthRequired(sessionsUC))
+
+	// Alta: opciones para navigator.credentials.create(), excluyendo las passkeys ya registradas.
+	me.POST("/register/begin", func(c *gin.Context) {
+		opts, token, err := passkeysUC.BeginRegistration(userIDFromClaims(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		noStore(c)
+		c.JSON(http.StatusOK, gin.H{"session_token": token, "options": opts})
+	})
+
+	me.POST("/register/finish", func(c *gin.Context) {
+		var req dto.WebAuthnRegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cred, err := passkeysUC.FinishRegistration(userIDFromClaims(c), req.SessionToken, req.Name, req.Credential)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, cred)
+	})
+
+	me.GET("/credentials", func(c *gin.Context) {
+		creds, err := passkeysUC.List(userIDFromClaims(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": creds})
+	})
+
+	me.DELETE("/credentials/:id", func(c *gin.Context) {
+		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err := passkeysUC.Delete(userIDFromClaims(c), id); err != nil {
+			if err.Error() == "not found" {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
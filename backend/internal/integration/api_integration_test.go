@@ -9,15 +9,22 @@ import (
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
 
+	"password-danie/internal/blobstore"
 	api "password-danie/internal/http"
+	"password-danie/internal/policy"
 	"password-danie/internal/repository"
 	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/security"
 	"password-danie/internal/usecase"
+	"password-danie/pkg/db"
 )
 
 // ---------- helpers ----------
@@ -49,13 +56,29 @@ func doJSON(t *testing.T, ts *httptest.Server, method, path string, token string
 	return rr
 }
 
+// registerAndLogin crea una cuenta normal y devuelve su access token.
+func registerAndLogin(t *testing.T, ts *httptest.Server, email string) string {
+	t.Helper()
+	creds := map[string]any{"email": email, "password": testPassword}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", creds), 201)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", creds)
+	mustStatus(t, rr, 200)
+	var res loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	if res.AccessToken == "" {
+		t.Fatalf("no access token: %s", rr.Body.String())
+	}
+	return res.AccessToken
+}
+
 type regRes struct {
 	ID    int64  `json:"id"`
 	Email string `json:"email"`
 }
 type loginRes struct {
-	AccessToken string `json:"access_token"`
-	User        struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         struct {
 		ID    int64  `json:"id"`
 		Email string `json:"email"`
 	} `json:"user"`
@@ -70,32 +93,129 @@ type listRes struct {
 	Total int `json:"total"`
 }
 
-// ---------- schema (mimic migrations) ----------
-const schema = `
-CREATE TABLE IF NOT EXISTS users(
-  id INTEGER PRIMARY KEY AUTOINCREMENT,
-  email TEXT NOT NULL UNIQUE,
-  password_hash TEXT NOT NULL,
-  reset_token TEXT NULL,
-  reset_expires_at DATETIME NULL,
-  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-CREATE TABLE IF NOT EXISTS secrets(
-  id INTEGER PRIMARY KEY AUTOINCREMENT,
-  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-  username TEXT NOT NULL,
-  password_cipher TEXT NOT NULL,
-  password_iv TEXT NOT NULL,
-  url TEXT NOT NULL DEFAULT '',
-  url_domain TEXT NOT NULL DEFAULT '',
-  notes TEXT NOT NULL DEFAULT '',
-  icon TEXT NOT NULL DEFAULT '',
-  title TEXT NOT NULL DEFAULT '',
-  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-`
+// migrationsDir: los tests montan la base con las mismas migraciones que el servidor.
+const migrationsDir = "../../migrations"
+
+// applyMigrations crea el esquema en sqlDB aplicando las migraciones reales.
+func applyMigrations(t *testing.T, sqlDB *sql.DB) {
+	t.Helper()
+	if err := db.ApplyMigrations(sqlDB, migrationsDir); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+}
+
+// testAttachmentLimits: pequeños para probar tamaño máximo y cuota con ficheros de pocos trozos.
+var testAttachmentLimits = usecase.AttachmentLimits{MaxFileSize: 256 << 10, Quota: 512 << 10}
+
+// testHistoryPolicy: pocas versiones por entrada para probar la retención por número.
+var testHistoryPolicy = usecase.HistoryPolicy{MaxRevisions: 3, MaxAge: 30 * 24 * time.Hour}
+
+// Claves de firma de prueba (PKCS#8 base64): Ed25519 activa y P-256 para probar rotación.
+const (
+	testJWTKey   = "MC4CAQAwBQYDK2VwBCIEIFNn8KL3kH0QPtEIuc+BJHflFFszhJpV/M3zwJQ0pz0o"
+	testJWTKeyES = "MIGHAgEAMBMGByqGSM49AgEGCCqGSM49AwEHBG0wawIBAQQgolc3k5DOj0hNxuWLs1drxbM1syLOdH+IG8oAyCBE+TahRANCAASOeT0mhULSy386sqMWCNQKHKFBU28uYBBcF2uA+QASf4CseVA51w7Hs+pCz64/R6urQGmVNxIt0SEtxuqYE2hu"
+)
+
+// loadEnvKeys carga en el paquete security las claves de las variables de entorno actuales.
+func loadEnvKeys(t *testing.T) {
+	t.Helper()
+	if err := security.Init(security.EnvProvider{}); err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+}
+
+const testAdminToken = "test-admin-token"
+
+// testPreloginKey: clave fija de los parámetros de relleno de prelogin.
+var testPreloginKey = []byte("0123456789abcdef0123456789abcdef")
+
+// testThrottle bloquea un minuto tras 5 fallos para que el bloqueo no caduque a mitad de test.
+var testThrottle = usecase.ThrottlePolicy{FreeAttempts: 5, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+// Contraseñas maestras que cumplen policy.Default().
+const (
+	testPassword    = "Lluvia-de-Marzo-1987!"
+	testNewPassword = "Tr0mb0n-Galaxia-Nube-58"
+)
+
+// newTestHasher usa los parámetros Argon2id mínimos aceptados para que los tests vayan rápidos.
+func newTestHasher(t *testing.T) security.PasswordHasher {
+	t.Helper()
+	h, err := security.NewArgon2idHasher(security.Argon2idParams{Memory: 19 * 1024, Iterations: 2, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	return h
+}
+
+// newTestAPI monta la API completa sobre una SQLite en memoria con las migraciones.
+func newTestAPI(t *testing.T) (*httptest.Server, *sql.DB) {
+	t.Helper()
+	return newTestAPIWithPolicy(t, policy.Default())
+}
+
+// newTestAPIWithPolicy es newTestAPI con otra política; su volcado de filtraciones (si lo hay)
+// también se usa para marcar las entradas del vault.
+func newTestAPIWithPolicy(t *testing.T, pol policy.Policy) (*httptest.Server, *sql.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey)
+	t.Setenv("AES_KEY", "0123456789abcdef0123456789abcdef")
+	loadEnvKeys(t)
+
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite mem: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	applyMigrations(t, sqlDB)
+
+	var (
+		userRepo   repository.UserRepo   = sqlrepo.NewUserSQLite(sqlDB)
+		secretRepo repository.SecretRepo = sqlrepo.NewSecretSQLite(sqlDB)
+		keyRepo    repository.KeyRepo    = sqlrepo.NewKeySQLite(sqlDB)
+	)
+	tokenRepo := sqlrepo.NewRefreshTokenSQLite(sqlDB)
+	sessionsUC := usecase.NewSessions(sqlrepo.NewSessionSQLite(sqlDB), tokenRepo, time.Minute)
+	hasher := newTestHasher(t)
+	mfaUC := usecase.NewMFA(sqlrepo.NewMFASQLite(sqlDB), userRepo, "password-danie", time.Minute)
+	rp, err := webauthn.New(&webauthn.Config{RPID: testRPID, RPDisplayName: "password-danie", RPOrigins: []string{testOrigin}})
+	if err != nil {
+		t.Fatalf("webauthn: %v", err)
+	}
+	passkeysUC := usecase.NewPasskeys(sqlrepo.NewWebAuthnSQLite(sqlDB), userRepo, rp, time.Minute)
+	throttle := usecase.NewThrottle(sqlrepo.NewAttemptSQLite(sqlDB), testThrottle, usecase.DefaultIPThrottle())
+	auditUC := usecase.NewAudit(sqlrepo.NewAuditSQLite(sqlDB))
+	authUC := usecase.NewAuth(userRepo, tokenRepo, sessionsUC, mfaUC, passkeysUC, throttle, auditUC, hasher, pol, testPreloginKey, 15*time.Minute, time.Hour)
+	vaultUC := usecase.NewVault(secretRepo, keyRepo, userRepo, pol.Breaches, auditUC)
+	resetUC := usecase.NewPasswordReset(userRepo, secretRepo, sessionsUC, throttle, auditUC, hasher, pol)
+	// Los blobs van a ATTACHMENTS_DIR si el test lo fija (para inspeccionarlos) o a un temporal.
+	blobDir := os.Getenv("ATTACHMENTS_DIR")
+	if blobDir == "" {
+		blobDir = t.TempDir()
+	}
+	blobs, err := blobstore.NewFS(blobDir)
+	if err != nil {
+		t.Fatalf("blob store: %v", err)
+	}
+	attachUC := usecase.NewAttachments(sqlrepo.NewAttachmentSQLite(sqlDB), vaultUC, blobs, testAttachmentLimits, auditUC)
+	historyUC := usecase.NewHistory(vaultUC, testHistoryPolicy)
+
+	r := gin.New()
+	api.RegisterRoutes(r, authUC, sessionsUC, vaultUC, func() error { return sqlDB.Ping() })
+	api.RegisterResetRoutes(r, resetUC)
+	api.RegisterMFARoutes(r, authUC, mfaUC, sessionsUC)
+	api.RegisterWebAuthnRoutes(r, authUC, passkeysUC, sessionsUC)
+	api.RegisterGenerateRoutes(r, sessionsUC)
+	api.RegisterAuditRoutes(r, auditUC, sessionsUC)
+	api.RegisterAttachmentRoutes(r, attachUC, sessionsUC)
+	api.RegisterHistoryRoutes(r, historyUC, sessionsUC)
+	api.RegisterTrashRoutes(r, vaultUC, sessionsUC)
+	api.RegisterAdminRoutes(r, throttle, auditUC, testAdminToken)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts, sqlDB
+}
 
 // ---------- test ----------
 
@@ -103,8 +223,9 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// Env necesarios para JWT y AES
-	_ = os.Setenv("JWT_SECRET", "test-secret")
+	_ = os.Setenv("JWT_SIGNING_KEYS", testJWTKey)
 	_ = os.Setenv("AES_KEY", "0123456789abcdef0123456789abcdef")
+	loadEnvKeys(t)
 
 	// DB en memoria
 	sqlDB, err := sql.Open("sqlite", ":memory:")
@@ -112,23 +233,27 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 		t.Fatalf("open sqlite mem: %v", err)
 	}
 	defer sqlDB.Close()
-	if _, err := sqlDB.Exec(schema); err != nil {
-		t.Fatalf("apply schema: %v", err)
-	}
+	applyMigrations(t, sqlDB)
 
 	// Repos & Usecases
 	var (
 		userRepo   repository.UserRepo   = sqlrepo.NewUserSQLite(sqlDB)
 		secretRepo repository.SecretRepo = sqlrepo.NewSecretSQLite(sqlDB)
+		keyRepo    repository.KeyRepo    = sqlrepo.NewKeySQLite(sqlDB)
 	)
-	authUC := usecase.NewAuth(userRepo)
-	vaultUC := usecase.NewVault(secretRepo)
-	resetUC := usecase.NewPasswordReset(userRepo)
+	tokenRepo := sqlrepo.NewRefreshTokenSQLite(sqlDB)
+	sessionsUC := usecase.NewSessions(sqlrepo.NewSessionSQLite(sqlDB), tokenRepo, time.Minute)
+	hasher := newTestHasher(t)
+	mfaUC := usecase.NewMFA(sqlrepo.NewMFASQLite(sqlDB), userRepo, "password-danie", time.Minute)
+	authUC := usecase.NewAuth(userRepo, tokenRepo, sessionsUC, mfaUC, nil, nil, nil, hasher, policy.Default(), nil, 15*time.Minute, time.Hour)
+	vaultUC := usecase.NewVault(secretRepo, keyRepo, userRepo, nil, nil)
+	resetUC := usecase.NewPasswordReset(userRepo, secretRepo, sessionsUC, nil, nil, hasher, policy.Default())
 
 	// Router y server
 	r := gin.Default()
-	api.RegisterRoutes(r, authUC, vaultUC, func() error { return sqlDB.Ping() })
+	api.RegisterRoutes(r, authUC, sessionsUC, vaultUC, func() error { return sqlDB.Ping() })
 	api.RegisterResetRoutes(r, resetUC)
+	api.RegisterMFARoutes(r, authUC, mfaUC, sessionsUC)
 	ts := httptest.NewServer(r)
 	defer ts.Close()
 
@@ -139,7 +264,7 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 	mustStatus(t, rr, 200)
 
 	// --- 2) register
-	registerBody := map[string]any{"email": "e2e@test.com", "password": "Secret123!"}
+	registerBody := map[string]any{"email": "e2e@test.com", "password": testPassword}
 	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", registerBody)
 	mustStatus(t, rr, 201)
 	var reg regRes
@@ -193,6 +318,22 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+idStr, token, nil)
 	mustStatus(t, rr, 200)
 
+	// --- 7b) revelar contraseña (sin caché)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+idStr+"/password", token, nil)
+	mustStatus(t, rr, 200)
+	if cc := rr.Header().Get("Cache-Control"); !strings.Contains(cc, "no-store") {
+		t.Fatalf("expected no-store cache header, got %q", cc)
+	}
+	var pres map[string]string
+	_ = json.Unmarshal(rr.Body.Bytes(), &pres)
+	if pres["password"] != "p@ss" {
+		t.Fatalf("bad revealed password: %s", rr.Body.String())
+	}
+	var keys int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM user_keys WHERE user_id = ?`, reg.ID).Scan(&keys); err != nil || keys != 1 {
+		t.Fatalf("expected a wrapped data key for the user, got %d (err=%v)", keys, err)
+	}
+
 	// --- 8) actualizar
 	updateBody := map[string]any{
 		"notes":          "actualizada",
@@ -201,6 +342,17 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 	rr = doJSON(t, ts, http.MethodPut, "/api/v1/vault/entries/"+idStr, token, updateBody)
 	mustStatus(t, rr, 200)
 
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+idStr+"/password", token, nil)
+	mustStatus(t, rr, 200)
+	_ = json.Unmarshal(rr.Body.Bytes(), &pres)
+	if pres["password"] != "new-pass-123" {
+		t.Fatalf("bad revealed password after update: %s", rr.Body.String())
+	}
+	var reveals int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM secret_reveals WHERE secret_id = ?`, cres.ID).Scan(&reveals); err != nil || reveals != 2 {
+		t.Fatalf("expected 2 reveal records, got %d (err=%v)", reveals, err)
+	}
+
 	// --- 9) borrar
 	rr = doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+idStr, token, nil)
 	mustStatus(t, rr, 200)
@@ -218,14 +370,14 @@ func Test_FullAPI_HappyPath(t *testing.T) {
 	// --- 11) reset password: confirm
 	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/confirm", "", map[string]any{
 		"token":        resetToken,
-		"new_password": "NewPassw0rd!",
+		"new_password": testNewPassword,
 	})
 	mustStatus(t, rr, 200)
 
 	// --- 12) login con la nueva contraseña
 	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{
 		"email":    "e2e@test.com",
-		"password": "NewPassw0rd!",
+		"password": testNewPassword,
 	})
 	mustStatus(t, rr, 200)
 }
@@ -0,0 +1,177 @@
+// Test de integración de adjuntos: subida multipart y descarga de varios trozos, cifrado en disco,
+// manipulación detectada, tamaño máximo y cuota por usuario, y borrado en cascada al borrar la entrada del todo.
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func uploadFile(t *testing.T, ts *httptest.Server, token, path, name, contentType string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("comment", "ignored")
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write(content)
+	_ = mw.Close()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	ts.Config.Handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// blobFiles lista los blobs guardados en dir.
+func blobFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var out []string
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			out = append(out, path)
+		}
+		return err
+	})
+	return out
+}
+
+func Test_VaultAttachments(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ATTACHMENTS_DIR", dir)
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "files@test.com")
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "alice", "password_plain": "s3cret!"})
+	mustStatus(t, rr, 201)
+	var entry struct {
+		ID int64 `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &entry)
+	base := "/api/v1/vault/entries/" + strconv.FormatInt(entry.ID, 10) + "/attachments"
+
+	// 200 KiB: cuatro trozos, el último parcial.
+	content := bytes.Repeat([]byte("RECOVERY-CODE-0123456789\n"), 8192)
+	rr = uploadFile(t, ts, token, base, "recovery codes.pdf", "application/pdf", content)
+	mustStatus(t, rr, 201)
+	var att struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &att)
+	if att.Name != "recovery codes.pdf" || att.Size != int64(len(content)) {
+		t.Fatalf("unexpected attachment: %s", rr.Body.String())
+	}
+	files := blobFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("expected one blob, got %v", files)
+	}
+	raw, _ := os.ReadFile(files[0])
+	if bytes.Contains(raw, []byte("RECOVERY-CODE")) {
+		t.Fatalf("blob stored in clear")
+	}
+	var name string
+	_ = sqlDB.QueryRow(`SELECT name FROM attachments WHERE id = ?`, att.ID).Scan(&name)
+	if strings.Contains(name, "recovery") {
+		t.Fatalf("file name stored in clear: %q", name)
+	}
+
+	path := base + "/" + strconv.FormatInt(att.ID, 10)
+	rr = doJSON(t, ts, http.MethodGet, path, token, nil)
+	mustStatus(t, rr, 200)
+	if !bytes.Equal(rr.Body.Bytes(), content) {
+		t.Fatalf("downloaded %d bytes, want %d", rr.Body.Len(), len(content))
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, "recovery codes.pdf") {
+		t.Fatalf("unexpected Content-Disposition %q", cd)
+	}
+	if rr.Header().Get("Content-Type") != "application/pdf" || rr.Header().Get("Cache-Control") == "" {
+		t.Fatalf("unexpected headers: %v", rr.Header())
+	}
+
+	// Un fichero vacío también es un adjunto válido.
+	rr = uploadFile(t, ts, token, base, "empty.key", "", nil)
+	mustStatus(t, rr, 201)
+	var empty struct {
+		ID int64 `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &empty)
+	rr = doJSON(t, ts, http.MethodGet, base+"/"+strconv.FormatInt(empty.ID, 10), token, nil)
+	mustStatus(t, rr, 200)
+	if rr.Body.Len() != 0 || rr.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("unexpected empty download: %d bytes, %v", rr.Body.Len(), rr.Header())
+	}
+
+	// Tamaño máximo (256 KiB) y cuota (512 KiB): lo rechazado no deja blobs.
+	mustStatus(t, uploadFile(t, ts, token, base, "big.bin", "", make([]byte, 300<<10)), 413)
+	mustStatus(t, uploadFile(t, ts, token, base, "second.pdf", "", content), 201)
+	mustStatus(t, uploadFile(t, ts, token, base, "third.pdf", "", content), 413)
+	if n := len(blobFiles(t, dir)); n != 3 {
+		t.Fatalf("expected 3 blobs after rejected uploads, got %d", n)
+	}
+	rr = doJSON(t, ts, http.MethodGet, base, token, nil)
+	mustStatus(t, rr, 200)
+	var list struct {
+		Items []struct {
+			Name string `json:"name"`
+		} `json:"items"`
+		Used  int64 `json:"used_bytes"`
+		Quota int64 `json:"quota_bytes"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Items) != 3 || list.Items[0].Name != "recovery codes.pdf" || list.Used != 2*int64(len(content)) || list.Quota != 512<<10 {
+		t.Fatalf("unexpected list: %s", rr.Body.String())
+	}
+
+	// Otro usuario no ve nada.
+	other := registerAndLogin(t, ts, "other-files@test.com")
+	mustStatus(t, doJSON(t, ts, http.MethodGet, base, other, nil), 404)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, path, other, nil), 404)
+	mustStatus(t, uploadFile(t, ts, other, base, "x.txt", "", []byte("x")), 404)
+
+	// Un byte cambiado en el blob se detecta antes de responder.
+	var blobKey string
+	_ = sqlDB.QueryRow(`SELECT blob_key FROM attachments WHERE id = ?`, att.ID).Scan(&blobKey)
+	blobPath := filepath.Join(dir, blobKey[:2], blobKey)
+	raw, _ = os.ReadFile(blobPath)
+	raw[20] ^= 1
+	if err := os.WriteFile(blobPath, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, path, token, nil), 400)
+
+	// Borrar un adjunto quita su blob; borrar la entrada del todo (desde la papelera) se lleva el resto.
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, path, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, path, token, nil), 404)
+	if _, err := os.Stat(blobPath); !os.IsNotExist(err) {
+		t.Fatalf("blob not removed: %v", err)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+strconv.FormatInt(entry.ID, 10), token, nil), 200)
+	if len(blobFiles(t, dir)) != 2 {
+		t.Fatalf("attachments must survive while the entry is in the trash")
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/trash/"+strconv.FormatInt(entry.ID, 10), token, nil), 200)
+	var rows int
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&rows)
+	if rows != 0 || len(blobFiles(t, dir)) != 0 {
+		t.Fatalf("attachments left after deleting the entry: %d rows, %v", rows, blobFiles(t, dir))
+	}
+}
@@ -0,0 +1,97 @@
+// Test de integración del registro de auditoría: eventos de login, vault y reset visibles solo para su
+// usuario, tablas de solo inserción y detección de manipulación al verificar la cadena.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/usecase"
+)
+
+type auditRes struct {
+	Total int `json:"total"`
+	Items []struct {
+		ID       int64  `json:"id"`
+		Action   string `json:"action"`
+		Result   string `json:"result"`
+		TargetID int64  `json:"target_id"`
+		Actor    string `json:"actor"`
+	} `json:"items"`
+}
+
+func Test_Audit_OwnEventsAndTamperDetection(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	const email = "audited@test.com"
+	token := registerAndLogin(t, ts, email)
+	other := registerAndLogin(t, ts, "bystander@test.com")
+
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": "wrong-password"}), 401)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "u", "password_plain": "p@ss", "title": "t"})
+	mustStatus(t, rr, 201)
+	var created createRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	id := strconv.FormatInt(created.ID, 10)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+id+"/password", token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+id, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": email}), 200)
+
+	// Del más reciente al más antiguo; solo los eventos propios.
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/audit", "", nil), 401)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/audit", token, nil)
+	mustStatus(t, rr, 200)
+	var res auditRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	want := []string{"auth.reset_request:success", "vault.delete:success", "vault.reveal:success", "vault.create:success",
+		"auth.login:failure", "auth.login:success"}
+	if res.Total != len(want) || len(res.Items) != len(want) {
+		t.Fatalf("unexpected events: %s", rr.Body.String())
+	}
+	for i, w := range want {
+		if got := res.Items[i].Action + ":" + res.Items[i].Result; got != w {
+			t.Fatalf("event %d: got %s want %s", i, got, w)
+		}
+	}
+	if res.Items[1].TargetID != created.ID || res.Items[4].Actor != email {
+		t.Fatalf("missing target or actor: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/audit", other, nil)
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	if res.Total != 1 {
+		t.Fatalf("bystander sees foreign events: %s", rr.Body.String())
+	}
+
+	// Verificación por administración y con el caso de uso directo.
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/admin/audit/verify", testAdminToken, nil)
+	mustStatus(t, rr, 200)
+	var rep usecase.AuditReport
+	_ = json.Unmarshal(rr.Body.Bytes(), &rep)
+	if rep.BrokenAt != 0 || rep.Checked != 7 {
+		t.Fatalf("unexpected report: %s", rr.Body.String())
+	}
+
+	// La tabla es de solo inserción.
+	if _, err := sqlDB.Exec(`UPDATE audit_events SET result = 'success' WHERE action = 'auth.login' AND result = 'failure'`); err == nil {
+		t.Fatal("update of audit_events should fail")
+	}
+	if _, err := sqlDB.Exec(`DELETE FROM audit_events`); err == nil {
+		t.Fatal("delete of audit_events should fail")
+	}
+
+	// Aun saltándose los triggers, editar un evento rompe la cadena en ese punto.
+	var tampered int64
+	_ = sqlDB.QueryRow(`SELECT id FROM audit_events WHERE action = 'auth.login' AND result = 'failure'`).Scan(&tampered)
+	if _, err := sqlDB.Exec(`DROP TRIGGER audit_events_no_update`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`UPDATE audit_events SET result = 'success' WHERE id = ?`, tampered); err != nil {
+		t.Fatal(err)
+	}
+	got, err := usecase.NewAudit(sqlrepo.NewAuditSQLite(sqlDB)).Verify(3)
+	if err != nil || got.BrokenAt != tampered || got.Checked != int(tampered-1) {
+		t.Fatalf("tampering not detected at %d: %+v (err=%v)", tampered, got, err)
+	}
+}
@@ -0,0 +1,174 @@
+// Test de integración del volcado local de contraseñas filtradas: búsqueda en ambos formatos,
+// modo aviso/bloqueo en la contraseña maestra y marcado de entradas del vault.
+package integration_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"password-danie/internal/breach"
+	"password-danie/internal/policy"
+)
+
+// Contraseñas "filtradas" del volcado de prueba; testPassword no está, testNewPassword sí.
+var breachedPasswords = map[string]int{
+	testNewPassword:           3,
+	"Hunter2-Contraseña-Rota": 17,
+	"vault-leaked-pass":       42,
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeBreachDatasets escribe el mismo volcado como fichero ordenado y como directorio de shards,
+// con relleno suficiente para que la búsqueda binaria dé varios saltos.
+func writeBreachDatasets(t *testing.T) (sortedPath, shardDir string) {
+	t.Helper()
+	counts := map[string]int{}
+	for pw, n := range breachedPasswords {
+		counts[sha1Hex(pw)] = n
+	}
+	for i := 0; i < 500; i++ {
+		counts[sha1Hex(fmt.Sprintf("filler-%d", i))] = i + 1
+	}
+	hashes := make([]string, 0, len(counts))
+	for h := range counts {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	dir := t.TempDir()
+	sortedPath = filepath.Join(dir, "pwned-passwords-sha1-ordered-by-hash.txt")
+	shardDir = filepath.Join(dir, "shards")
+	if err := os.Mkdir(shardDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	var all strings.Builder
+	shards := map[string]*strings.Builder{}
+	for _, h := range hashes {
+		fmt.Fprintf(&all, "%s:%d\r\n", h, counts[h])
+		if shards[h[:5]] == nil {
+			shards[h[:5]] = &strings.Builder{}
+		}
+		fmt.Fprintf(shards[h[:5]], "%s:%d\r\n", h[5:], counts[h])
+	}
+	if err := os.WriteFile(sortedPath, []byte(all.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for prefix, b := range shards {
+		if err := os.WriteFile(filepath.Join(shardDir, prefix+".txt"), []byte(b.String()), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sortedPath, shardDir
+}
+
+func Test_Breach_DatasetLookup(t *testing.T) {
+	sortedPath, shardDir := writeBreachDatasets(t)
+	for _, path := range []string{sortedPath, shardDir} {
+		ds, err := breach.Open(path)
+		if err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		for pw, want := range breachedPasswords {
+			if n, err := ds.Count(pw); err != nil || n != want {
+				t.Fatalf("%s: count(%q) = %d, %v; want %d", filepath.Base(path), pw, n, err, want)
+			}
+		}
+		for _, pw := range []string{testPassword, "filler-500", ""} {
+			if n, err := ds.Count(pw); err != nil || n != 0 {
+				t.Fatalf("%s: count(%q) = %d, %v; want 0", filepath.Base(path), pw, n, err)
+			}
+		}
+		if n, err := ds.Count("filler-0"); err != nil || n != 1 {
+			t.Fatalf("%s: filler-0 = %d, %v", filepath.Base(path), n, err)
+		}
+	}
+}
+
+func Test_Breach_MasterPasswordAndVault(t *testing.T) {
+	sortedPath, shardDir := writeBreachDatasets(t)
+
+	// Modo bloqueo (volcado ordenado): la contraseña filtrada se rechaza con su motivo.
+	ds, err := breach.Open(sortedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol := policy.Default()
+	pol.Breaches, pol.BlockBreached = ds, true
+	ts, _ := newTestAPIWithPolicy(t, pol)
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{"email": "pwned@test.com", "password": testNewPassword})
+	mustStatus(t, rr, 422)
+	if codes := reasonCodes(t, rr.Body.Bytes()); !codes["breached"] {
+		t.Fatalf("expected breached reason: %s", rr.Body.String())
+	}
+	token := registerAndLogin(t, ts, "pwned@test.com")
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/users/me/password", token, map[string]any{
+		"current_password": testPassword, "new_password": testNewPassword,
+	})
+	mustStatus(t, rr, 422)
+
+	// Modo aviso (shards): se acepta pero la respuesta lo advierte.
+	ds, err = breach.Open(shardDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol.Breaches, pol.BlockBreached = ds, false
+	ts, _ = newTestAPIWithPolicy(t, pol)
+
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{"email": "warn@test.com", "password": testNewPassword})
+	mustStatus(t, rr, 201)
+	var reg struct {
+		Warnings []struct {
+			Code string `json:"code"`
+		} `json:"warnings"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &reg)
+	if len(reg.Warnings) != 1 || reg.Warnings[0].Code != "breached" {
+		t.Fatalf("expected breached warning: %s", rr.Body.String())
+	}
+	token = loginAs(t, ts, "warn@test.com", testNewPassword)
+
+	// Vault: las entradas con contraseña filtrada se marcan, y se desmarcan al cambiarla.
+	breached := func(id int64) bool {
+		rr := doJSON(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/vault/entries/%d", id), token, nil)
+		mustStatus(t, rr, 200)
+		var s struct {
+			Breached bool `json:"breached"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &s)
+		return s.Breached
+	}
+	create := func(password string) int64 {
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "alice", "password_plain": password})
+		mustStatus(t, rr, 201)
+		var res struct {
+			ID int64 `json:"id"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res.ID
+	}
+	leaked, safe := create("vault-leaked-pass"), create("vault-unique-pass-93")
+	if !breached(leaked) || breached(safe) {
+		t.Fatalf("unexpected breached flags: leaked=%v safe=%v", breached(leaked), breached(safe))
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, fmt.Sprintf("/api/v1/vault/entries/%d", leaked), token, map[string]any{"password_plain": "vault-unique-pass-17"}), 200)
+	if breached(leaked) {
+		t.Fatal("entry still flagged after changing its password")
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, fmt.Sprintf("/api/v1/vault/entries/%d", leaked), token, map[string]any{"notes": "sin cambiar la contraseña"}), 200)
+	if breached(leaked) {
+		t.Fatal("flag changed on an update without password")
+	}
+}
@@ -0,0 +1,132 @@
+// Test de integración de los campos personalizados: tipos de campo, valores hidden que solo salen al
+// revelar, cifrado en base de datos y altas, bajas y reordenación a través de PUT.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type customField struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+func Test_VaultCustomFields(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "custom@test.com")
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "alice", "password_plain": "s3cret!", "url": "https://console.aws.amazon.com",
+		"custom_fields": []map[string]any{
+			{"name": "Account number", "value": "123456789012"},
+			{"name": "Security answer", "kind": "hidden", "value": "Fluffy"},
+			{"name": "MFA enforced", "kind": "boolean", "value": "TRUE"},
+			{"name": "Sign in as", "kind": "linked", "value": "username"},
+		},
+	})
+	mustStatus(t, rr, 201)
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	path := "/api/v1/vault/entries/" + strconv.FormatInt(created.ID, 10)
+
+	fields := func() []customField {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodGet, path, token, nil)
+		mustStatus(t, rr, 200)
+		var e struct {
+			CustomFields []customField `json:"custom_fields"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &e)
+		return e.CustomFields
+	}
+	reveal := func() map[string]string {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodGet, path+"/password", token, nil)
+		mustStatus(t, rr, 200)
+		var r struct {
+			CustomFields map[string]string `json:"custom_fields"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &r)
+		return r.CustomFields
+	}
+
+	got := fields()
+	if len(got) != 4 || got[0].Value != "123456789012" || got[1].Kind != "hidden" || got[1].Value != "" ||
+		got[2].Value != "true" || got[3].Value != "username" {
+		t.Fatalf("unexpected custom fields: %+v", got)
+	}
+	text, hidden := got[0], got[1]
+	if text.ID == "" || hidden.ID == "" || text.ID == hidden.ID {
+		t.Fatalf("expected distinct ids: %+v", got)
+	}
+	if r := reveal(); r[hidden.ID] != "Fluffy" || len(r) != 1 {
+		t.Fatalf("unexpected revealed custom fields: %+v", r)
+	}
+
+	var stored string
+	if err := sqlDB.QueryRow(`SELECT custom_fields FROM secrets WHERE id = ?`, created.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == "" || strings.Contains(stored, "Fluffy") || strings.Contains(stored, "123456789012") {
+		t.Fatalf("custom fields not sealed: %q", stored)
+	}
+
+	// Nombres y valores de texto se pueden buscar; los hidden no.
+	for q, want := range map[string]int{"account": 1, "123456789012": 1, "fluffy": 0} {
+		rr := doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?q="+q, token, nil)
+		mustStatus(t, rr, 200)
+		var list struct {
+			Total int `json:"total"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &list)
+		if list.Total != want {
+			t.Fatalf("q=%s: got %d results, want %d", q, list.Total, want)
+		}
+	}
+
+	for name, body := range map[string]map[string]any{
+		"kind":         {"custom_fields": []map[string]any{{"name": "x", "kind": "color", "value": "red"}}},
+		"boolean":      {"custom_fields": []map[string]any{{"name": "x", "kind": "boolean", "value": "maybe"}}},
+		"linked":       {"custom_fields": []map[string]any{{"name": "x", "kind": "linked", "value": "pin"}}},
+		"no name":      {"custom_fields": []map[string]any{{"value": "x"}}},
+		"unknown id":   {"custom_fields": []map[string]any{{"id": "deadbeef", "name": "x"}}},
+		"duplicate id": {"custom_fields": []map[string]any{{"id": text.ID}, {"id": text.ID}}},
+	} {
+		if rr := doJSON(t, ts, http.MethodPut, path, token, body); rr.Code != 400 {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rr.Code, rr.Body.String())
+		}
+	}
+
+	// Reordenar, renombrar sin reenviar valores, quitar los que faltan y añadir uno nuevo.
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"custom_fields": []map[string]any{
+		{"id": hidden.ID},
+		{"id": text.ID, "name": "AWS account"},
+		{"name": "Region", "value": "eu-west-1"},
+	}}), 200)
+	got = fields()
+	if len(got) != 3 || got[0].ID != hidden.ID || got[1].ID != text.ID || got[1].Name != "AWS account" ||
+		got[1].Value != "123456789012" || got[2].Name != "Region" {
+		t.Fatalf("unexpected fields after reorder: %+v", got)
+	}
+	if r := reveal(); r[hidden.ID] != "Fluffy" {
+		t.Fatalf("hidden value lost on reorder: %+v", r)
+	}
+
+	// Sin custom_fields en el PUT la lista no se toca; con [] se vacía.
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"title": "AWS"}), 200)
+	if len(fields()) != 3 {
+		t.Fatalf("custom fields changed by an unrelated update")
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"custom_fields": []any{}}), 200)
+	if got := fields(); len(got) != 0 {
+		t.Fatalf("expected no custom fields: %+v", got)
+	}
+}
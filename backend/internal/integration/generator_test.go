@@ -0,0 +1,128 @@
+// Test de integración del generador: mínimos por clase, exclusión de caracteres ambiguos, entropía
+// declarada, frases diceware y generación de la contraseña al crear una entrada del vault.
+package integration_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"password-danie/internal/generator"
+)
+
+func Test_Generator_PasswordsAndPassphrases(t *testing.T) {
+	// Solo dígitos: 10^8 contraseñas posibles.
+	res, err := generator.Password(generator.PasswordOptions{Length: 8, Digits: true})
+	if err != nil || len(res.Value) != 8 || res.Entropy != 26.6 {
+		t.Fatalf("digits only: %+v (err=%v)", res, err)
+	}
+
+	// Con mínimos muy ajustados a la longitud tienen que salir siempre.
+	opts := generator.PasswordOptions{Length: 10, Lowercase: true, Uppercase: true, Digits: true, Symbols: true,
+		ExcludeAmbiguous: true, MinUppercase: 3, MinDigits: 3, MinSymbols: 3}
+	for i := 0; i < 200; i++ {
+		res, err := generator.Password(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var upper, digit, other int
+		for _, r := range res.Value {
+			switch {
+			case strings.ContainsRune("Il1O0o|", r):
+				t.Fatalf("ambiguous character in %q", res.Value)
+			case unicode.IsUpper(r):
+				upper++
+			case unicode.IsDigit(r):
+				digit++
+			case !unicode.IsLower(r):
+				other++
+			}
+		}
+		if upper < 3 || digit < 3 || other < 3 {
+			t.Fatalf("minimums not met in %q", res.Value)
+		}
+	}
+	// Los mínimos restan entropía frente a elegir libremente de las cuatro clases.
+	free, _ := generator.Password(generator.DefaultPassword())
+	if free.Entropy <= 120 || free.Entropy >= 20*math.Log2(90) {
+		t.Fatalf("unexpected default entropy %.1f", free.Entropy)
+	}
+
+	for _, bad := range []generator.PasswordOptions{
+		{Length: 4, Lowercase: true},
+		{Length: 12},
+		{Length: 12, Lowercase: true, MinDigits: 1},
+		{Length: 8, Lowercase: true, Digits: true, MinLowercase: 5, MinDigits: 5},
+	} {
+		if _, err := generator.Password(bad); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+
+	res, err = generator.Passphrase(generator.PassphraseOptions{Words: 5, Separator: " ", Capitalize: true, Number: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Split(res.Value, " ")
+	if len(words) != 5 || !unicode.IsUpper(rune(words[0][0])) || !strings.ContainsAny(res.Value, "0123456789") {
+		t.Fatalf("unexpected passphrase %q", res.Value)
+	}
+	// 5 palabras de 7776 más qué palabra lleva el dígito y cuál.
+	if want := math.Round((5*math.Log2(7776)+math.Log2(50))*10) / 10; res.Entropy != want {
+		t.Fatalf("passphrase entropy %.1f, want %.1f", res.Entropy, want)
+	}
+}
+
+func Test_Generator_APIAndVaultCreate(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	token := registerAndLogin(t, ts, "generate@test.com")
+
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/generate", "", nil), 401)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/generate", token, nil)
+	mustStatus(t, rr, 200)
+	var res generator.Result
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	if res.Kind != "password" || len(res.Value) != 20 || res.Entropy < 120 {
+		t.Fatalf("unexpected default result: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/generate", token, map[string]any{"type": "passphrase", "words": 4, "separator": "."})
+	mustStatus(t, rr, 200)
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	if res.Kind != "passphrase" || strings.Count(res.Value, ".") != 3 {
+		t.Fatalf("unexpected passphrase: %s", rr.Body.String())
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/generate", token, map[string]any{"length": 8, "min_digits": 9}), 400)
+
+	// Sin password_plain y con generate, la entrada se crea con una contraseña generada.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "alice", "title": "Generada", "generate": map[string]any{"length": 32, "symbols": false},
+	})
+	mustStatus(t, rr, 201)
+	var created struct {
+		ID       int64  `json:"id"`
+		Password string `json:"password"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	if len(created.Password) != 32 || strings.ContainsAny(created.Password, "!#$%&*+-=?@^_~") {
+		t.Fatalf("unexpected generated password: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+strconv.FormatInt(created.ID, 10)+"/password", token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), created.Password) {
+		t.Fatalf("stored password differs: %s", rr.Body.String())
+	}
+	// Las dos cosas a la vez son un error, no se ignora generate.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "carol", "password_plain": "mine", "generate": map[string]any{"length": 32},
+	})
+	mustStatus(t, rr, 400)
+	if !strings.Contains(rr.Body.String(), "mutually exclusive") {
+		t.Fatalf("unexpected error: %s", rr.Body.String())
+	}
+	// Sin ninguna de las dos cosas sigue siendo obligatoria.
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "bob"}), 400)
+}
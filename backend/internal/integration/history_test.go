@@ -0,0 +1,160 @@
+// Test de integración del historial de versiones: cambios de contraseña y de campos secretos guardados
+// cifrados, revelado y restauración, retención por número y por antigüedad y borrado definitivo con la entrada.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type revisionItem struct {
+	ID      int64    `json:"id"`
+	Changed []string `json:"changed"`
+}
+
+func Test_VaultHistory(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "history@test.com")
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "alice", "password_plain": "first-pass!"})
+	mustStatus(t, rr, 201)
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	loginID := created.ID
+	path := "/api/v1/vault/entries/" + strconv.FormatInt(loginID, 10)
+
+	history := func(token string) []revisionItem {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodGet, path+"/history", token, nil)
+		mustStatus(t, rr, 200)
+		var res struct {
+			Items []revisionItem `json:"items"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res.Items
+	}
+	password := func() string {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodGet, path+"/password", token, nil)
+		mustStatus(t, rr, 200)
+		var r struct {
+			Password string `json:"password"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &r)
+		return r.Password
+	}
+	update := func(body map[string]any) {
+		t.Helper()
+		mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, body), 200)
+	}
+
+	// Cambiar las notas no deja versión (solo se guardan los campos secretos) ni la contraseña repetida.
+	update(map[string]any{"password_plain": "second-pass!"})
+	update(map[string]any{"notes": "rotated"})
+	update(map[string]any{"password_plain": "second-pass!"})
+	update(map[string]any{"password_plain": "third-pass!"})
+	items := history(token)
+	if len(items) != 2 || items[0].ID <= items[1].ID || strings.Join(items[0].Changed, ",") != "password" {
+		t.Fatalf("unexpected history: %+v", items)
+	}
+	var payload string
+	_ = sqlDB.QueryRow(`SELECT payload FROM secret_history WHERE id = ?`, items[0].ID).Scan(&payload)
+	if payload == "" || strings.Contains(payload, "second-pass") {
+		t.Fatalf("revision not sealed: %q", payload)
+	}
+
+	rr = doJSON(t, ts, http.MethodGet, path+"/history/"+strconv.FormatInt(items[0].ID, 10), token, nil)
+	mustStatus(t, rr, 200)
+	var rev struct {
+		Password string   `json:"password"`
+		Changed  []string `json:"changed"`
+		Username *string  `json:"username"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &rev)
+	if rev.Password != "second-pass!" || len(rev.Changed) != 1 || rev.Username != nil || rr.Header().Get("Cache-Control") == "" {
+		t.Fatalf("unexpected revision: %s", rr.Body.String())
+	}
+
+	// Restaurar la primera versión; la contraseña que se pisa queda como versión nueva.
+	oldest := items[1].ID
+	mustStatus(t, doJSON(t, ts, http.MethodPost, path+"/history/"+strconv.FormatInt(oldest, 10)+"/restore", token, nil), 200)
+	if got := password(); got != "first-pass!" {
+		t.Fatalf("expected restored password, got %q", got)
+	}
+	items = history(token)
+	if len(items) != 3 {
+		t.Fatalf("expected the overwritten password as a new revision: %+v", items)
+	}
+	rr = doJSON(t, ts, http.MethodGet, path+"/history/"+strconv.FormatInt(items[0].ID, 10), token, nil)
+	mustStatus(t, rr, 200)
+	_ = json.Unmarshal(rr.Body.Bytes(), &rev)
+	if rev.Password != "third-pass!" {
+		t.Fatalf("unexpected revision after restore: %s", rr.Body.String())
+	}
+
+	// Retención: como mucho 3 versiones por entrada y ninguna de más de 30 días.
+	update(map[string]any{"password_plain": "fourth-pass!"})
+	items = history(token)
+	if len(items) != 3 {
+		t.Fatalf("expected 3 revisions after pruning, got %+v", items)
+	}
+	if _, err := sqlDB.Exec(`UPDATE secret_history SET created_at = datetime('now', '-40 days') WHERE id = ?`, items[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := history(token); len(got) != 2 {
+		t.Fatalf("expected the expired revision to be pruned, got %+v", got)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, path+"/history/"+strconv.FormatInt(items[2].ID, 10), token, nil), 404)
+
+	// Otro usuario no ve el historial.
+	other := registerAndLogin(t, ts, "other-history@test.com")
+	mustStatus(t, doJSON(t, ts, http.MethodGet, path+"/history", other, nil), 404)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, path+"/history/"+strconv.FormatInt(items[0].ID, 10)+"/restore", other, nil), 404)
+
+	// En una tarjeta cuentan los campos secretos del tipo, no el titular.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"type": "card", "fields": map[string]string{"cardholder": "Alice", "number": "4111 1111 1111 1111", "cvv": "123"},
+	})
+	mustStatus(t, rr, 201)
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	card := "/api/v1/vault/entries/" + strconv.FormatInt(created.ID, 10)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, card, token, map[string]any{"fields": map[string]string{"cvv": "456"}}), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, card, token, map[string]any{"fields": map[string]string{"cardholder": "Alice B."}}), 200)
+	rr = doJSON(t, ts, http.MethodGet, card+"/history", token, nil)
+	mustStatus(t, rr, 200)
+	var cardHistory struct {
+		Items []revisionItem `json:"items"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &cardHistory)
+	if len(cardHistory.Items) != 1 || strings.Join(cardHistory.Items[0].Changed, ",") != "fields.cvv" {
+		t.Fatalf("unexpected card history: %s", rr.Body.String())
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, card+"/history/"+strconv.FormatInt(cardHistory.Items[0].ID, 10)+"/restore", token, nil), 200)
+	rr = doJSON(t, ts, http.MethodGet, card+"/password", token, nil)
+	mustStatus(t, rr, 200)
+	var revealed struct {
+		Fields map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &revealed)
+	if revealed.Fields["cvv"] != "123" {
+		t.Fatalf("expected restored cvv, got %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodGet, card, token, nil)
+	if !strings.Contains(rr.Body.String(), "Alice B.") {
+		t.Fatalf("restore must only touch the revision's fields: %s", rr.Body.String())
+	}
+
+	// Borrar la entrada del todo borra su historial.
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, path, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/trash/"+strconv.FormatInt(loginID, 10), token, nil), 200)
+	var n int
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM secret_history WHERE secret_id = ?`, loginID).Scan(&n)
+	if n != 0 {
+		t.Fatalf("expected history to be deleted with the entry, %d left", n)
+	}
+}
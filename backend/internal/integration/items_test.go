@@ -0,0 +1,155 @@
+// Test de integración de los tipos de entrada: validación por tipo, campos secretos que solo salen al
+// revelar, campos derivados (últimas cifras, huella SSH), filtro por tipo y cambios parciales de campos.
+package integration_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type itemView struct {
+	ID     int64             `json:"id"`
+	Type   string            `json:"type"`
+	Fields map[string]string `json:"fields"`
+}
+
+func getItem(t *testing.T, id int64, do func(path string) []byte) itemView {
+	t.Helper()
+	var it itemView
+	if err := json.Unmarshal(do("/api/v1/vault/entries/"+strconv.FormatInt(id, 10)), &it); err != nil {
+		t.Fatal(err)
+	}
+	return it
+}
+
+func Test_VaultItems_TypesAndFields(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "items@test.com")
+	get := func(path string) []byte {
+		rr := doJSON(t, ts, http.MethodGet, path, token, nil)
+		mustStatus(t, rr, 200)
+		return rr.Body.Bytes()
+	}
+	create := func(body map[string]any) int64 {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, body)
+		mustStatus(t, rr, 201)
+		var res struct {
+			ID int64 `json:"id"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res.ID
+	}
+
+	// Tarjeta: el número se normaliza y se valida con Luhn; número y CVV solo salen al revelar.
+	card := create(map[string]any{"type": "card", "title": "Visa trabajo", "fields": map[string]string{
+		"cardholder": "Alice Zamora", "number": "4111 1111 1111 1111", "exp_month": "3", "exp_year": "31", "cvv": "123",
+	}})
+	it := getItem(t, card, get)
+	if it.Type != "card" || it.Fields["last4"] != "1111" || it.Fields["exp_month"] != "03" || it.Fields["exp_year"] != "2031" {
+		t.Fatalf("unexpected card view: %+v", it)
+	}
+	if _, ok := it.Fields["number"]; ok {
+		t.Fatalf("card number leaked in the view: %+v", it)
+	}
+	var rev struct {
+		Password string            `json:"password"`
+		Fields   map[string]string `json:"fields"`
+	}
+	_ = json.Unmarshal(get("/api/v1/vault/entries/"+strconv.FormatInt(card, 10)+"/password"), &rev)
+	if rev.Password != "" || rev.Fields["number"] != "4111111111111111" || rev.Fields["cvv"] != "123" {
+		t.Fatalf("unexpected reveal: %+v", rev)
+	}
+	// Los campos van cifrados en payload.
+	var payload string
+	if err := sqlDB.QueryRow(`SELECT payload FROM secrets WHERE id = ?`, card).Scan(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload == "" || strings.Contains(payload, "Zamora") || strings.Contains(payload, "4111") {
+		t.Fatalf("payload not sealed: %q", payload)
+	}
+
+	for name, body := range map[string]map[string]any{
+		"luhn":          {"type": "card", "fields": map[string]string{"number": "4111111111111112"}},
+		"unknown field": {"type": "card", "fields": map[string]string{"number": "4111111111111111", "pin": "1234"}},
+		"unknown type":  {"type": "wifi", "fields": map[string]string{"ssid": "casa"}},
+		"password":      {"type": "api_key", "password_plain": "x", "fields": map[string]string{"key": "k"}},
+		"empty note":    {"type": "note", "title": "vacía"},
+		"bad date":      {"type": "identity", "fields": map[string]string{"full_name": "Alice", "birth_date": "31/12/1990"}},
+		"signed year":   {"type": "card", "fields": map[string]string{"number": "4111111111111111", "exp_year": "-1"}},
+		"plus year":     {"type": "card", "fields": map[string]string{"number": "4111111111111111", "exp_year": "+5"}},
+		"year zero":     {"type": "card", "fields": map[string]string{"number": "4111111111111111", "exp_year": "0000"}},
+		"login fields":  {"username": "a", "password_plain": "b", "fields": map[string]string{"number": "1"}},
+	} {
+		if rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, body); rr.Code != 400 {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rr.Code, rr.Body.String())
+		}
+	}
+
+	// Clave SSH: la pública y la huella se derivan de la privada; una pública ajena se rechaza.
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(priv, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, _ := ssh.NewPublicKey(pub)
+	sshKey := create(map[string]any{"type": "ssh_key", "title": "deploy", "fields": map[string]string{"private_key": string(pem.EncodeToMemory(block))}})
+	it = getItem(t, sshKey, get)
+	if it.Fields["fingerprint"] != ssh.FingerprintSHA256(sshPub) || !strings.HasPrefix(it.Fields["public_key"], "ssh-ed25519 ") {
+		t.Fatalf("unexpected ssh view: %+v", it)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	other, _ := ssh.NewPublicKey(otherPub)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"type": "ssh_key", "fields": map[string]string{
+		"private_key": string(pem.EncodeToMemory(block)), "public_key": string(ssh.MarshalAuthorizedKey(other)),
+	}})
+	mustStatus(t, rr, 400)
+
+	create(map[string]any{"type": "note", "title": "Wifi", "notes": "clave del router"})
+	create(map[string]any{"username": "alice", "password_plain": "s3cret!"})
+
+	// Filtro por tipo y búsqueda por los campos no secretos.
+	var list struct {
+		Items []itemView `json:"items"`
+		Total int        `json:"total"`
+	}
+	_ = json.Unmarshal(get("/api/v1/vault/entries?type=card"), &list)
+	if list.Total != 1 || list.Items[0].ID != card {
+		t.Fatalf("unexpected type filter: %+v", list)
+	}
+	_ = json.Unmarshal(get("/api/v1/vault/entries?type=login"), &list)
+	if list.Total != 1 || list.Items[0].Type != "login" {
+		t.Fatalf("unexpected login filter: %+v", list)
+	}
+	_ = json.Unmarshal(get("/api/v1/vault/entries?q=zamora"), &list)
+	if list.Total != 1 || list.Items[0].ID != card {
+		t.Fatalf("expected search by cardholder: %+v", list)
+	}
+	_ = json.Unmarshal(get("/api/v1/vault/entries?q=4111"), &list)
+	if list.Total != 0 {
+		t.Fatalf("secret fields must not be searchable: %+v", list)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?type=wifi", token, nil), 400)
+
+	// Cambio parcial: "" borra un campo, el resto se conserva y se vuelve a validar.
+	path := "/api/v1/vault/entries/" + strconv.FormatInt(card, 10)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"fields": map[string]string{"cvv": "", "brand": "visa"}}), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"fields": map[string]string{"number": ""}}), 400)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, path, token, map[string]any{"password_plain": "x"}), 400)
+	rev.Fields = nil
+	_ = json.Unmarshal(get(path+"/password"), &rev)
+	if _, ok := rev.Fields["cvv"]; ok || rev.Fields["number"] != "4111111111111111" {
+		t.Fatalf("unexpected fields after update: %+v", rev)
+	}
+	if it = getItem(t, card, get); it.Fields["brand"] != "visa" || it.Fields["cardholder"] != "Alice Zamora" {
+		t.Fatalf("unexpected card after update: %+v", it)
+	}
+}
@@ -0,0 +1,76 @@
+// Test de integración de la firma asimétrica: kid en la cabecera, JWKS público y rotación de claves.
+package integration_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"password-danie/internal/security"
+)
+
+func Test_JWKS_VerifyAndRotate(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKeyES+","+testJWTKey)
+	loadEnvKeys(t)
+
+	token := registerAndLogin(t, ts, "jwks@example.com")
+
+	rr := doJSON(t, ts, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	mustStatus(t, rr, 200)
+	var set security.JWKSet
+	if err := json.Unmarshal(rr.Body.Bytes(), &set); err != nil || len(set.Keys) != 2 {
+		t.Fatalf("jwks: %s (err=%v)", rr.Body.String(), err)
+	}
+	if strings.Contains(rr.Body.String(), `"d"`) {
+		t.Fatalf("jwks must not expose private material: %s", rr.Body.String())
+	}
+
+	// Un servicio externo verifica el token solo con el JWKS, eligiendo la clave por kid.
+	parsed, err := jwt.Parse(token, func(tok *jwt.Token) (any, error) {
+		for _, k := range set.Keys {
+			if k.Kid == tok.Header["kid"] && k.Kty == "EC" {
+				x, _ := base64.RawURLEncoding.DecodeString(k.X)
+				y, _ := base64.RawURLEncoding.DecodeString(k.Y)
+				return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+			}
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil || !parsed.Valid {
+		t.Fatalf("verify with jwks: %v", err)
+	}
+
+	// Rotación: la Ed25519 pasa a firmar, los tokens ES256 siguen valiendo mientras su clave esté publicada.
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey+","+testJWTKeyES)
+	loadEnvKeys(t)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", token, nil), 200)
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "jwks@example.com", "password": testPassword})
+	mustStatus(t, rr, 200)
+	var res loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	header, _, _ := strings.Cut(res.AccessToken, ".")
+	raw, _ := base64.RawURLEncoding.DecodeString(header)
+	if !strings.Contains(string(raw), `"alg":"EdDSA"`) {
+		t.Fatalf("expected EdDSA after rotation, header=%s", raw)
+	}
+
+	// Al retirar la clave ES256, sus tokens dejan de valer.
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey)
+	loadEnvKeys(t)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", token, nil), 401)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", res.AccessToken, nil), 200)
+
+	// Un token HS256 (p. ej. firmado con la clave pública como secreto) se rechaza.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": 1, "email": "x@example.com", "exp": 9999999999})
+	forged.Header["kid"] = set.Keys[1].Kid
+	s, _ := forged.SignedString([]byte(set.Keys[1].X))
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", s, nil), 401)
+}
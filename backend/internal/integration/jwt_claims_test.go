@@ -0,0 +1,89 @@
+// Test de integración de la validación estricta de claims: iss, aud, nbf, iat, exp con margen y sub como string.
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"password-danie/internal/security"
+)
+
+func Test_JWTClaims_StrictValidation(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	security.SetTokenPolicy(security.TokenPolicy{Issuer: "issuer-test", Audience: "aud-test", Leeway: 5 * time.Second})
+	t.Cleanup(func() { security.SetTokenPolicy(security.DefaultTokenPolicy) })
+
+	token := registerAndLogin(t, ts, "claims@example.com")
+	good, err := security.ParseToken(token)
+	if err != nil {
+		t.Fatalf("parse own token: %v", err)
+	}
+	if good.Subject == "" || good.Issuer != "issuer-test" || len(good.Audience) != 1 || good.Audience[0] != "aud-test" {
+		t.Fatalf("unexpected claims: %+v", good)
+	}
+
+	key, err := security.ParseSigningKey(testJWTKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(mutate func(c *security.Claims)) string {
+		now := time.Now()
+		c := &security.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "issuer-test",
+				Subject:   good.Subject,
+				Audience:  jwt.ClaimStrings{"aud-test"},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+				NotBefore: jwt.NewNumericDate(now),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ID:        "jti-test",
+			},
+			Email:     good.Email,
+			SessionID: good.SessionID,
+		}
+		mutate(c)
+		tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+		tok.Header["kid"] = key.ID
+		s, err := tok.SignedString(key.Signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	me := func(tok string) int {
+		return doJSON(t, ts, http.MethodGet, "/api/v1/users/me", tok, nil).Code
+	}
+
+	if code := me(sign(func(*security.Claims) {})); code != 200 {
+		t.Fatalf("well-formed token rejected: %d", code)
+	}
+	// Dentro del margen de reloj: recién caducado o con nbf/iat ligeramente en el futuro.
+	if code := me(sign(func(c *security.Claims) {
+		c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Second))
+		c.NotBefore = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+		c.IssuedAt = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+	})); code != 200 {
+		t.Fatalf("token within leeway rejected: %d", code)
+	}
+
+	cases := map[string]func(c *security.Claims){
+		"wrong issuer":   func(c *security.Claims) { c.Issuer = "someone-else" },
+		"wrong audience": func(c *security.Claims) { c.Audience = jwt.ClaimStrings{"other-service"} },
+		"no audience":    func(c *security.Claims) { c.Audience = nil },
+		"expired":        func(c *security.Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+		"no expiry":      func(c *security.Claims) { c.ExpiresAt = nil },
+		"not yet valid":  func(c *security.Claims) { c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute)) },
+		"issued later":   func(c *security.Claims) { c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Minute)) },
+		"no iat":         func(c *security.Claims) { c.IssuedAt = nil },
+		"no jti":         func(c *security.Claims) { c.ID = "" },
+		"bad subject":    func(c *security.Claims) { c.Subject = "abc" },
+	}
+	for name, mutate := range cases {
+		if code := me(sign(mutate)); code != 401 {
+			t.Errorf("%s: expected 401, got %d", name, code)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Test de integración de los proveedores de claves: fichero con permisos y servicio de tránsito simulado.
+package integration_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"password-danie/internal/security"
+)
+
+func Test_KeyProvider_FileRequiresPrivatePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"current_version": 2, "aes_keys": {"1": "` + rotationKeyV1 + `", "2": "` + rotationKeyV2 + `"}, "jwt_signing_keys": ["` + testJWTKey + `"]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := security.FileProvider{Path: path}
+	if err := security.Init(p); err == nil || !strings.Contains(err.Error(), "too open") {
+		t.Fatalf("expected permissions error, got %v", err)
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := security.Init(p); err != nil {
+		t.Fatalf("init from file: %v", err)
+	}
+	if v, err := security.CurrentKeyVersion(); err != nil || v != 2 {
+		t.Fatalf("expected current version 2, got %d (err=%v)", v, err)
+	}
+	c, iv, version, err := security.Encrypt([]byte("hola"))
+	if err != nil || version != 2 {
+		t.Fatalf("encrypt: version=%d err=%v", version, err)
+	}
+	if plain, err := security.Decrypt(version, c, iv); err != nil || string(plain) != "hola" {
+		t.Fatalf("decrypt: %q err=%v", plain, err)
+	}
+}
+
+func Test_KeyProvider_TransitUnwrapsKeys(t *testing.T) {
+	// Servicio de tránsito de mentira: "desenvuelve" ciphertexts que conoce y exige el token.
+	jwtDER, _ := base64.StdEncoding.DecodeString(testJWTKey)
+	wrapped := map[string][]byte{
+		"vault:v1:aes-key": []byte(rotationKeyV1),
+		"vault:v1:jwt":     jwtDER,
+	}
+	kms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/transit/decrypt/master" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		var in struct {
+			Ciphertext string `json:"ciphertext"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		plain, ok := wrapped[in.Ciphertext]
+		if !ok {
+			http.Error(w, "invalid ciphertext", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plain)},
+		})
+	}))
+	defer kms.Close()
+
+	t.Setenv("KEY_PROVIDER", "transit")
+	t.Setenv("KMS_ADDR", kms.URL)
+	t.Setenv("KMS_TOKEN", "s.token")
+	t.Setenv("KMS_KEY_NAME", "master")
+	t.Setenv("AES_KEY_WRAPPED", "vault:v1:aes-key")
+	t.Setenv("AES_KEY_VERSION", "")
+	t.Setenv("AES_RETIRED_KEYS_WRAPPED", "")
+	t.Setenv("JWT_SIGNING_KEYS_WRAPPED", "vault:v1:jwt")
+
+	p, err := security.ProviderFromEnv()
+	if err != nil {
+		t.Fatalf("provider: %v", err)
+	}
+	if err := security.Init(p); err != nil {
+		t.Fatalf("init from transit: %v", err)
+	}
+	c, iv, version, err := security.Encrypt([]byte("hola"))
+	if err != nil || version != 1 {
+		t.Fatalf("encrypt: version=%d err=%v", version, err)
+	}
+	// Lo cifrado con la clave desenvuelta se descifra con la misma clave cargada por entorno.
+	t.Setenv("AES_KEY", rotationKeyV1)
+	t.Setenv("AES_RETIRED_KEYS", "")
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey)
+	loadEnvKeys(t)
+	if plain, err := security.Decrypt(version, c, iv); err != nil || string(plain) != "hola" {
+		t.Fatalf("decrypt: %q err=%v", plain, err)
+	}
+
+	// Token incorrecto: el servidor no debe arrancar.
+	t.Setenv("KMS_TOKEN", "wrong")
+	p, _ = security.ProviderFromEnv()
+	if err := security.Init(p); err == nil {
+		t.Fatal("expected init to fail with a rejected token")
+	}
+}
+
+func Test_KeyProvider_EnvHasNoDefaults(t *testing.T) {
+	t.Setenv("AES_KEY", "")
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey)
+	if err := security.Init(security.EnvProvider{}); err == nil {
+		t.Fatal("expected missing AES_KEY to fail")
+	}
+	t.Setenv("AES_KEY", rotationKeyV1)
+	t.Setenv("JWT_SIGNING_KEYS", "")
+	if err := security.Init(security.EnvProvider{}); err == nil {
+		t.Fatal("expected missing JWT_SIGNING_KEYS to fail")
+	}
+}
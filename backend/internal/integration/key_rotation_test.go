@@ -0,0 +1,112 @@
+// Test de integración de la rotación de clave maestra: re-envoltura de claves de datos y re-cifrado de filas legadas.
+package integration_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"password-danie/internal/policy"
+	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/security"
+	"password-danie/internal/usecase"
+)
+
+const (
+	rotationKeyV1 = "0123456789abcdef0123456789abcdef"
+	rotationKeyV2 = "fedcba9876543210fedcba9876543210"
+)
+
+func Test_KeyRotation_ReencryptsWithNewVersion(t *testing.T) {
+	t.Setenv("AES_KEY", rotationKeyV1)
+	t.Setenv("AES_KEY_VERSION", "")
+	t.Setenv("AES_RETIRED_KEYS", "")
+	t.Setenv("JWT_SIGNING_KEYS", testJWTKey)
+	loadEnvKeys(t)
+
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite mem: %v", err)
+	}
+	defer sqlDB.Close()
+	applyMigrations(t, sqlDB)
+
+	userRepo := sqlrepo.NewUserSQLite(sqlDB)
+	secretRepo := sqlrepo.NewSecretSQLite(sqlDB)
+	keyRepo := sqlrepo.NewKeySQLite(sqlDB)
+	rotRepo := sqlrepo.NewRotationSQLite(sqlDB)
+
+	u, _, err := usecase.NewAuth(userRepo, nil, nil, nil, nil, nil, nil, newTestHasher(t), policy.Default(), nil, time.Minute, time.Hour).Register("rotate@test.com", testPassword)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	// Una entrada nueva (clave de datos envuelta con v1) y otra legada cifrada directamente con v1.
+	enveloped, _, err := usecase.NewVault(secretRepo, keyRepo, userRepo, nil, nil).Create(u.ID, usecase.CreateSecretInput{Username: "alice", PasswordPlain: "enveloped-pass"}, "", "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	c, iv, version, err := security.Encrypt([]byte("legacy-pass"))
+	if err != nil || version != 1 {
+		t.Fatalf("encrypt legacy: v=%d err=%v", version, err)
+	}
+	res, err := sqlDB.Exec(`INSERT INTO secrets(user_id, username, password_cipher, password_iv, enc_scheme, key_version) VALUES(?, 'bob', ?, ?, 0, 1)`, u.ID, c, iv)
+	if err != nil {
+		t.Fatalf("insert legacy: %v", err)
+	}
+	legacy, _ := res.LastInsertId()
+
+	// Nada que rotar mientras la versión no cambie.
+	rot := usecase.NewKeyRotation(rotRepo, 1, 0)
+	if job, err := rot.Prepare(); err != nil || job != nil {
+		t.Fatalf("expected no rotation job, got %+v (err=%v)", job, err)
+	}
+
+	// Nueva clave maestra v2; v1 queda retirada solo para descifrar.
+	t.Setenv("AES_KEY", rotationKeyV2)
+	t.Setenv("AES_KEY_VERSION", "2")
+	t.Setenv("AES_RETIRED_KEYS", "1:"+rotationKeyV1)
+	loadEnvKeys(t)
+
+	job, err := rot.Prepare()
+	if err != nil || job == nil || job.TargetVersion != 2 {
+		t.Fatalf("expected rotation job to v2, got %+v (err=%v)", job, err)
+	}
+	if err := rot.Run(job); err != nil {
+		t.Fatalf("run rotation: %v", err)
+	}
+	if job.Processed != 2 {
+		t.Fatalf("expected 2 rows rotated, got %d", job.Processed)
+	}
+	var stale int
+	if err := sqlDB.QueryRow(`SELECT (SELECT COUNT(*) FROM user_keys WHERE key_version <> 2) + (SELECT COUNT(*) FROM secrets WHERE enc_scheme = 0 AND key_version <> 2)`).Scan(&stale); err != nil || stale != 0 {
+		t.Fatalf("expected every row on v2, %d stale (err=%v)", stale, err)
+	}
+	if job, err := rot.Prepare(); err != nil || job != nil {
+		t.Fatalf("expected rotation to be finished, got %+v (err=%v)", job, err)
+	}
+
+	// Sin la clave v1 todo sigue siendo legible.
+	t.Setenv("AES_RETIRED_KEYS", "")
+	loadEnvKeys(t)
+	vault := usecase.NewVault(secretRepo, keyRepo, userRepo, nil, nil)
+	for id, want := range map[int64]string{enveloped: "enveloped-pass", legacy: "legacy-pass"} {
+		got, err := vault.Reveal(u.ID, id, "", "")
+		if err != nil || got.Password != want {
+			t.Fatalf("reveal %d: got %+v want %q (err=%v)", id, got, want, err)
+		}
+	}
+
+	// La fila legada pasa al esquema sellado y sigue siendo legible.
+	if n, err := vault.UpgradeLegacy(10); err != nil || n != 1 {
+		t.Fatalf("upgrade legacy: n=%d err=%v", n, err)
+	}
+	if got, err := vault.Reveal(u.ID, legacy, "", ""); err != nil || got.Password != "legacy-pass" {
+		t.Fatalf("reveal after upgrade: got %+v (err=%v)", got, err)
+	}
+	if s, err := vault.Get(u.ID, legacy); err != nil || s.Username != "bob" {
+		t.Fatalf("get after upgrade: %+v (err=%v)", s, err)
+	}
+}
@@ -0,0 +1,133 @@
+// Test de integración del segundo factor: alta TOTP, login en dos pasos, reutilización de códigos,
+// códigos de recuperación y límite de intentos del reto.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"password-danie/internal/otp"
+)
+
+func Test_TOTP_RFC6238Vectors(t *testing.T) {
+	// Apéndice B de la RFC 6238 (SHA-1), truncado a 6 dígitos. Secreto "12345678901234567890".
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		got, err := otp.Code(secret, otp.Step(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Fatalf("T=%d: got %q, %v; want %q", unix, got, err, want)
+		}
+	}
+	if _, ok, _ := otp.Validate(secret, "287082", time.Unix(59+otp.Period, 0), 1); !ok {
+		t.Fatal("previous step should be accepted with skew 1")
+	}
+	if _, ok, _ := otp.Validate(secret, "287082", time.Unix(59+3*otp.Period, 0), 1); ok {
+		t.Fatal("code three steps old should be rejected")
+	}
+}
+
+type mfaLoginRes struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	AccessToken string `json:"access_token"`
+}
+
+func Test_MFA_TOTPLoginAndRecovery(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	const email = "mfa@test.com"
+	token := registerAndLogin(t, ts, email)
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp", token, nil)
+	mustStatus(t, rr, 200)
+	var enr struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &enr)
+	if enr.Secret == "" || enr.URI == "" {
+		t.Fatalf("bad enrollment: %s", rr.Body.String())
+	}
+	base := otp.Step(time.Now()) // pasos relativos a este para no depender de cruzar un límite de 30 s
+	code := func(offset int64) string {
+		c, err := otp.Code(enr.Secret, base+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// Sin confirmar, el login sigue siendo de un paso.
+	if res := mfaLogin(t, ts, email); res.MFARequired {
+		t.Fatal("mfa required before confirming enrollment")
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp/confirm", token, map[string]any{"code": "000000x"}), 400)
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp/confirm", token, map[string]any{"code": code(0)})
+	mustStatus(t, rr, 200)
+	var conf struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &conf)
+	if len(conf.RecoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes: %s", rr.Body.String())
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp", token, nil), 409)
+
+	// Login en dos pasos. El código de la confirmación ya no vale (mismo paso); el siguiente sí, una vez.
+	res := mfaLogin(t, ts, email)
+	if !res.MFARequired || res.MFAToken == "" || res.AccessToken != "" {
+		t.Fatalf("expected mfa challenge: %+v", res)
+	}
+	second := func(mfaToken, code string) *httptest.ResponseRecorder {
+		return doJSON(t, ts, http.MethodPost, "/api/v1/auth/login/mfa", "", map[string]any{"mfa_token": mfaToken, "code": code})
+	}
+	mustStatus(t, second(res.MFAToken, code(0)), 401)
+	mustStatus(t, second(res.MFAToken, code(1)), 200)
+	mustStatus(t, second(res.MFAToken, code(1)), 401) // reto ya canjeado
+	res = mfaLogin(t, ts, email)
+	mustStatus(t, second(res.MFAToken, code(1)), 401) // código ya usado
+
+	// Código de recuperación: vale una vez, en cualquier formato.
+	rr = second(res.MFAToken, " "+conf.RecoveryCodes[0]+" ")
+	mustStatus(t, rr, 200)
+	var tokens loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &tokens)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", tokens.AccessToken, nil), 200)
+	res = mfaLogin(t, ts, email)
+	mustStatus(t, second(res.MFAToken, conf.RecoveryCodes[0]), 401)
+
+	// Tras 5 fallos el reto deja de valer aunque luego llegue un código correcto.
+	for i := 0; i < 4; i++ {
+		mustStatus(t, second(res.MFAToken, "000000"), 401)
+	}
+	mustStatus(t, second(res.MFAToken, conf.RecoveryCodes[1]), 401)
+
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/users/me/mfa", token, nil)
+	mustStatus(t, rr, 200)
+	var st struct {
+		Enabled bool `json:"totp_enabled"`
+		Left    int  `json:"recovery_codes_left"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &st)
+	if !st.Enabled || st.Left != 9 {
+		t.Fatalf("unexpected status: %s", rr.Body.String())
+	}
+
+	// Baja con un código de recuperación: el login vuelve a ser de un paso.
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp/disable", token, map[string]any{"code": "000000"}), 400)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/users/me/mfa/totp/disable", token, map[string]any{"code": conf.RecoveryCodes[2]}), 204)
+	if res := mfaLogin(t, ts, email); res.MFARequired || res.AccessToken == "" {
+		t.Fatalf("expected plain login after disabling: %+v", res)
+	}
+}
+
+func mfaLogin(t *testing.T, ts *httptest.Server, email string) mfaLoginRes {
+	t.Helper()
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": testPassword})
+	mustStatus(t, rr, 200)
+	var res mfaLoginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	return res
+}
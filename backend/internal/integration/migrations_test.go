@@ -0,0 +1,60 @@
+// Test de integración de las migraciones: se aplican todas sobre una base vacía como en el arranque, una
+// segunda pasada no hace nada y un fichero que falla a medias no deja nada aplicado.
+package integration_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"password-danie/pkg/db"
+)
+
+func Test_Migrations(t *testing.T) {
+	sqlDB, err := db.OpenSQLite("file:" + filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer sqlDB.Close()
+
+	files, _ := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	if len(files) == 0 {
+		t.Fatalf("no migrations in %s", migrationsDir)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.ApplyMigrations(sqlDB, migrationsDir); err != nil {
+			t.Fatalf("apply migrations (pass %d): %v", i+1, err)
+		}
+	}
+	var applied int
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	if applied != len(files) {
+		t.Fatalf("expected %d migrations recorded, got %d", len(files), applied)
+	}
+
+	// Un ";" en un comentario no parte la sentencia y un fallo deshace el fichero entero.
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("001_ok.sql", "-- tabla de prueba; con punto y coma\nCREATE TABLE ok_table(note TEXT NOT NULL DEFAULT '-- no es comentario');\n")
+	write("002_broken.sql", "CREATE TABLE half_table(id INTEGER);\nCREATE TABLE broken(;\n")
+	if err := db.ApplyMigrations(sqlDB, dir); err == nil || !strings.Contains(err.Error(), "002_broken.sql") {
+		t.Fatalf("expected 002_broken.sql to fail, got %v", err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO ok_table DEFAULT VALUES`); err != nil {
+		t.Fatalf("001_ok.sql not applied: %v", err)
+	}
+	var note string
+	_ = sqlDB.QueryRow(`SELECT note FROM ok_table`).Scan(&note)
+	var tables, recorded int
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_table'`).Scan(&tables)
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name = '002_broken.sql'`).Scan(&recorded)
+	if note != "-- no es comentario" || tables != 0 || recorded != 0 {
+		t.Fatalf("partial migration left behind: note=%q tables=%d recorded=%d", note, tables, recorded)
+	}
+}
@@ -0,0 +1,100 @@
+// Test de integración del hash de contraseñas: Argon2id en el alta, migración transparente desde bcrypt en el
+// login, el mismo trabajo de verificación con un email desconocido y parámetros fuera de rango en la configuración.
+package integration_test
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"password-danie/internal/config"
+	"password-danie/internal/policy"
+	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/security"
+	"password-danie/internal/usecase"
+)
+
+func Test_PasswordHash_Argon2idAndBcryptUpgrade(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	hashOf := func(email string) string {
+		var h string
+		if err := sqlDB.QueryRow(`SELECT password_hash FROM users WHERE email = ?`, email).Scan(&h); err != nil {
+			t.Fatalf("read hash: %v", err)
+		}
+		return h
+	}
+
+	// Alta nueva: Argon2id en formato PHC. Sin el límite de 72 bytes de bcrypt.
+	long := strings.Repeat("Tr0mb0n-Galaxia-", 5) + "tail"
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{"email": "argon@example.com", "password": long}), 201)
+	if h := hashOf("argon@example.com"); !strings.HasPrefix(h, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Fatalf("expected argon2id hash, got %q", h)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "argon@example.com", "password": long}), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "argon@example.com", "password": strings.Repeat("Tr0mb0n-Galaxia-", 5) + "other"}), 401)
+
+	// Cuenta antigua con bcrypt (y contraseña anterior a la política): el login correcto la re-hashea a Argon2id.
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost)
+	if _, err := sqlDB.Exec(`INSERT INTO users(email, password_hash) VALUES(?, ?)`, "legacy@example.com", string(legacy)); err != nil {
+		t.Fatal(err)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "legacy@example.com", "password": "wrong-pass"}), 401)
+	if h := hashOf("legacy@example.com"); h != string(legacy) {
+		t.Fatal("failed login must not touch the hash")
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "legacy@example.com", "password": "Secret123!"}), 200)
+	if h := hashOf("legacy@example.com"); !strings.HasPrefix(h, "$argon2id$") {
+		t.Fatalf("expected bcrypt hash upgraded, got %q", h)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "legacy@example.com", "password": "Secret123!"}), 200)
+}
+
+// countingHasher cuenta las verificaciones del hasher que envuelve.
+type countingHasher struct {
+	security.PasswordHasher
+	verified int
+}
+
+func (h *countingHasher) Verify(password, encoded string) (bool, error) {
+	h.verified++
+	return h.PasswordHasher.Verify(password, encoded)
+}
+
+func Test_PasswordHash_UnknownEmailVerifiesDummy(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite mem: %v", err)
+	}
+	defer sqlDB.Close()
+	applyMigrations(t, sqlDB)
+
+	hasher := &countingHasher{PasswordHasher: newTestHasher(t)}
+	auth := usecase.NewAuth(sqlrepo.NewUserSQLite(sqlDB), nil, nil, nil, nil, nil, nil, hasher, policy.Default(), nil, time.Minute, time.Hour)
+	for i := 1; i <= 2; i++ {
+		if _, err := auth.Login("nobody@example.com", testPassword, "", ""); err == nil || err.Error() != "invalid credentials" {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+		if hasher.verified != i {
+			t.Fatalf("unknown email must cost one verification per attempt, got %d after %d", hasher.verified, i)
+		}
+	}
+}
+
+func Test_PasswordHash_ConfigOutOfRange(t *testing.T) {
+	// Fuera del rango del tipo no se trunca (256 hilos serían 0): se usa el valor por defecto.
+	t.Setenv("ARGON2_MEMORY_KIB", "4294967296")
+	t.Setenv("ARGON2_ITERATIONS", "-1")
+	t.Setenv("ARGON2_PARALLELISM", "256")
+	cfg := config.Load()
+	if cfg.Argon2Memory != 64*1024 || cfg.Argon2Time != 3 || cfg.Argon2Threads != 2 {
+		t.Fatalf("out-of-range argon2 settings not rejected: m=%d t=%d p=%d", cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads)
+	}
+	t.Setenv("ARGON2_PARALLELISM", "255")
+	if cfg := config.Load(); cfg.Argon2Threads != 255 {
+		t.Fatalf("expected 255 threads, got %d", cfg.Argon2Threads)
+	}
+}
@@ -0,0 +1,88 @@
+// Test de integración de la política de contraseñas: motivos estructurados en alta, reset y cambio de contraseña.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type policyRes struct {
+	Reasons []struct {
+		Code string `json:"code"`
+	} `json:"reasons"`
+	Score int `json:"score"`
+}
+
+func reasonCodes(t *testing.T, body []byte) map[string]bool {
+	t.Helper()
+	var res policyRes
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("decode policy error: %v", err)
+	}
+	out := map[string]bool{}
+	for _, r := range res.Reasons {
+		out[r.Code] = true
+	}
+	return out
+}
+
+func Test_PasswordPolicy_RegisterResetAndChange(t *testing.T) {
+	ts, _ := newTestAPI(t)
+
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{"email": "policy@example.com", "password": "password"})
+	mustStatus(t, rr, 422)
+	if codes := reasonCodes(t, rr.Body.Bytes()); !codes["too_short"] || !codes["common_password"] || !codes["too_weak"] {
+		t.Fatalf("unexpected reasons: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{"email": "john.smith@example.com", "password": "Smith-Galaxia-Nube-58"})
+	mustStatus(t, rr, 422)
+	if codes := reasonCodes(t, rr.Body.Bytes()); !codes["contains_email"] {
+		t.Fatalf("expected contains_email: %s", rr.Body.String())
+	}
+
+	// Cambio de contraseña: verifica la actual, aplica la política y cierra las demás sesiones.
+	token := registerAndLogin(t, ts, "policy@example.com")
+	other := loginAs(t, ts, "policy@example.com", testPassword)
+	change := func(current, next string) int {
+		return doJSON(t, ts, http.MethodPost, "/api/v1/users/me/password", token, map[string]any{
+			"current_password": current, "new_password": next,
+		}).Code
+	}
+	if code := change("wrong-password", testNewPassword); code != 400 {
+		t.Fatalf("wrong current password: got %d", code)
+	}
+	if code := change(testPassword, "qwerty12345"); code != 422 {
+		t.Fatalf("weak new password: got %d", code)
+	}
+	if code := change(testPassword, testNewPassword); code != 204 {
+		t.Fatalf("change password: got %d", code)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", other, nil), 401)
+	loginAs(t, ts, "policy@example.com", testNewPassword)
+
+	// Reset: la nueva contraseña también pasa por la política.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": "policy@example.com"})
+	mustStatus(t, rr, 200)
+	var reset struct {
+		Token string `json:"reset_token"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &reset)
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/confirm", "", map[string]any{"token": reset.Token, "new_password": "Password123!"})
+	mustStatus(t, rr, 422)
+	if codes := reasonCodes(t, rr.Body.Bytes()); !codes["common_password"] {
+		t.Fatalf("expected common_password: %s", rr.Body.String())
+	}
+}
+
+// loginAs hace login con una contraseña concreta y devuelve el access token.
+func loginAs(t *testing.T, ts *httptest.Server, email, password string) string {
+	t.Helper()
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": password})
+	mustStatus(t, rr, 200)
+	var res loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	return res.AccessToken
+}
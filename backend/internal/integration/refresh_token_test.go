@@ -0,0 +1,58 @@
+// Test de integración de los refresh tokens: rotación de un solo uso y revocación de la familia al reutilizar uno.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func Test_RefreshToken_RotationAndReuse(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	creds := map[string]any{"email": "refresh@example.com", "password": testPassword}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", creds), 201)
+
+	login := func() loginRes {
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", creds)
+		mustStatus(t, rr, 200)
+		var res loginRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		if res.RefreshToken == "" {
+			t.Fatalf("no refresh token: %s", rr.Body.String())
+		}
+		return res
+	}
+	refresh := func(token string, want int) loginRes {
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/refresh", "", map[string]any{"refresh_token": token})
+		mustStatus(t, rr, want)
+		var res loginRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res
+	}
+
+	first := login()
+	other := login() // otra sesión (otra familia) del mismo usuario
+
+	// En base de datos solo hay hashes.
+	var n int
+	_ = sqlDB.QueryRow(`SELECT COUNT(*) FROM refresh_tokens WHERE token_hash = ?`, first.RefreshToken).Scan(&n)
+	if n != 0 {
+		t.Fatal("refresh token stored in plain text")
+	}
+
+	second := refresh(first.RefreshToken, 200)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatalf("expected a rotated pair, got %+v", second)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", second.AccessToken, nil), 200)
+	third := refresh(second.RefreshToken, 200)
+
+	// Reutilizar un token ya canjeado revoca toda la familia, incluido el último emitido.
+	refresh(first.RefreshToken, 401)
+	refresh(third.RefreshToken, 401)
+
+	// La otra sesión no se ve afectada.
+	refresh(other.RefreshToken, 200)
+
+	refresh("not-a-token", 401)
+}
@@ -0,0 +1,128 @@
+// Test de integración de campos cifrados: nada legible en la tabla y búsqueda/filtrado por índices ciegos.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"password-danie/internal/security"
+)
+
+func Test_SealedFields_SearchAndUpgrade(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+
+	creds := map[string]any{"email": "sealed@test.com", "password": testPassword}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", creds), 201)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", creds)
+	mustStatus(t, rr, 200)
+	var logres loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &logres)
+	token := logres.AccessToken
+
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "danie", "password_plain": "p@ss", "url": "https://www.GitHub.com/login",
+		"notes": "recovery codes 1234-5678", "title": "GitHub",
+	}), 201)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "ops", "password_plain": "x", "url": "https://gitlab.com", "title": "GitLab",
+	}), 201)
+
+	// En reposo no queda ningún campo sensible legible.
+	var leaked int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM secrets
+		WHERE username LIKE '%danie%' OR url LIKE '%github%' OR notes LIKE '%recovery%' OR title LIKE '%Git%' OR url_domain <> ''`).Scan(&leaked); err != nil || leaked != 0 {
+		t.Fatalf("plaintext found at rest: %d rows (err=%v)", leaked, err)
+	}
+
+	for query, want := range map[string]int{
+		"q=git":              2,
+		"q=github":           1,
+		"q=recovery%201234":  1,
+		"q=gitlab%20danie":   0,
+		"domain=github.com":  1,
+		"domain=GitLab.com":  1,
+		"domain=example.com": 0,
+	} {
+		rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?"+query, token, nil)
+		mustStatus(t, rr, 200)
+		var res struct {
+			Items []struct {
+				Username  string `json:"username"`
+				URLDomain string `json:"url_domain"`
+			} `json:"items"`
+			Total int `json:"total"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		if res.Total != want {
+			t.Fatalf("%s: got %d results, want %d: %s", query, res.Total, want, rr.Body.String())
+		}
+		for _, it := range res.Items {
+			if it.Username == "" || !strings.Contains(it.URLDomain, ".com") {
+				t.Fatalf("%s: item not decrypted: %s", query, rr.Body.String())
+			}
+		}
+	}
+
+	// Una fila antigua con campos en claro se sigue encontrando y el usuario puede editarla.
+	c, iv, version, err := security.Encrypt([]byte("legacy-pass"))
+	if err != nil {
+		t.Fatalf("encrypt legacy: %v", err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO secrets(id, user_id, username, password_cipher, password_iv, enc_scheme, key_version, url, url_domain, title)
+		VALUES(3, (SELECT id FROM users WHERE email = 'sealed@test.com'), 'bob', ?, ?, 0, ?, 'https://example.com', 'example.com', 'Legacy')`, c, iv, version); err != nil {
+		t.Fatalf("insert legacy row: %v", err)
+	}
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?domain=example.com&q=legacy", token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"total":1`) {
+		t.Fatalf("legacy row not found: %s", rr.Body.String())
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, "/api/v1/vault/entries/3", token, map[string]any{"notes": "now sealed"}), 200)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?domain=example.com&q=sealed", token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"total":1`) || !strings.Contains(rr.Body.String(), `"username":"bob"`) {
+		t.Fatalf("edited legacy row not sealed and searchable: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/3/password", token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), "legacy-pass") {
+		t.Fatalf("password lost while sealing: %s", rr.Body.String())
+	}
+}
+
+func Test_BoundCiphertexts_CannotBeMoved(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	alice := registerAndLogin(t, ts, "alice@test.com")
+	mallory := registerAndLogin(t, ts, "mallory@test.com")
+
+	create := func(token, password string) string {
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "u", "password_plain": password})
+		mustStatus(t, rr, 201)
+		var res createRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return strconv.FormatInt(res.ID, 10)
+	}
+	aliceOne := create(alice, "alice-secret")
+	aliceTwo := create(alice, "alice-other")
+	malloryOne := create(mallory, "mallory-secret")
+
+	// Quien pueda escribir en la base de datos copia el cifrado de una fila a otra.
+	copyPassword := func(from, to string) {
+		if _, err := sqlDB.Exec(`UPDATE secrets SET (password_cipher, password_iv) = (SELECT password_cipher, password_iv FROM secrets WHERE id = ?) WHERE id = ?`, from, to); err != nil {
+			t.Fatalf("copy ciphertext: %v", err)
+		}
+	}
+	copyPassword(aliceOne, malloryOne)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+malloryOne+"/password", mallory, nil), 400)
+	copyPassword(aliceOne, aliceTwo)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+aliceTwo+"/password", alice, nil), 400)
+
+	// Tampoco vale mover un campo a otra columna de la misma fila.
+	if _, err := sqlDB.Exec(`UPDATE secrets SET title = username WHERE id = ?`, aliceOne); err != nil {
+		t.Fatalf("swap columns: %v", err)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+aliceOne, alice, nil), 400)
+}
@@ -0,0 +1,70 @@
+// Test de integración de sesiones: jti/sid en los tokens, logout, logout en todos lados y revocación tras un reset.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func Test_Sessions_LogoutAndRevocation(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	creds := map[string]any{"email": "sessions@example.com", "password": testPassword}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", creds), 201)
+	login := func() loginRes {
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", creds)
+		mustStatus(t, rr, 200)
+		var res loginRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res
+	}
+	me := func(token string) int {
+		return doJSON(t, ts, http.MethodGet, "/api/v1/users/me", token, nil).Code
+	}
+	refresh := func(token string) int {
+		return doJSON(t, ts, http.MethodPost, "/api/v1/auth/refresh", "", map[string]any{"refresh_token": token}).Code
+	}
+
+	a, b := login(), login()
+	rr := doJSON(t, ts, http.MethodGet, "/api/v1/users/me", a.AccessToken, nil)
+	mustStatus(t, rr, 200)
+	var body struct {
+		Claims map[string]any `json:"claims"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if body.Claims["jti"] == nil || body.Claims["sid"] == nil {
+		t.Fatalf("expected jti and sid claims, got %v", body.Claims)
+	}
+
+	// Logout: muere el access token y el refresh token de esa sesión; la otra sigue.
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/logout", a.AccessToken, nil), 204)
+	if me(a.AccessToken) != 401 || refresh(a.RefreshToken) != 401 {
+		t.Fatal("logged out session still usable")
+	}
+	if me(b.AccessToken) != 200 {
+		t.Fatal("other session must survive a single logout")
+	}
+
+	// Logout en todos lados.
+	c := login()
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/logout-all", b.AccessToken, nil)
+	mustStatus(t, rr, 200)
+	if me(b.AccessToken) != 401 || me(c.AccessToken) != 401 || refresh(c.RefreshToken) != 401 {
+		t.Fatal("logout-all left a session alive")
+	}
+
+	// Un reset de contraseña completado revoca todas las sesiones.
+	d := login()
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": "sessions@example.com"})
+	mustStatus(t, rr, 200)
+	var reset struct {
+		Token string `json:"reset_token"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &reset)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/confirm", "", map[string]any{
+		"token": reset.Token, "new_password": testNewPassword,
+	}), 200)
+	if me(d.AccessToken) != 401 || refresh(d.RefreshToken) != 401 {
+		t.Fatal("password reset must revoke existing sessions")
+	}
+}
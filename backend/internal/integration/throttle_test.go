@@ -0,0 +1,124 @@
+// Test de integración de la protección contra fuerza bruta: bloqueo de cuenta en login y reset con 429 y
+// Retry-After, desbloqueo de administración y espera exponencial por IP con el almacén en memoria.
+package integration_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"password-danie/internal/repository/memory"
+	"password-danie/internal/usecase"
+)
+
+func Test_Throttle_LoginLockoutAndAdminUnlock(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	const email = "locked@test.com"
+	registerAndLogin(t, ts, email)
+	login := func(password string) int {
+		return doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": password}).Code
+	}
+
+	// 5 fallos gratis; el sexto bloquea la cuenta y a partir de ahí ni la contraseña buena entra.
+	for i := 0; i < 6; i++ {
+		if code := login("wrong-password"); code != 401 {
+			t.Fatalf("attempt %d: got %d", i+1, code)
+		}
+	}
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": testPassword})
+	mustStatus(t, rr, 429)
+	if secs, _ := strconv.Atoi(rr.Header().Get("Retry-After")); secs < 1 || secs > 60 {
+		t.Fatalf("bad Retry-After %q", rr.Header().Get("Retry-After"))
+	}
+	// El bloqueo es por cuenta: otra cuenta desde la misma IP entra.
+	registerAndLogin(t, ts, "other@test.com")
+
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/admin/lockouts", "", nil), 401)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/admin/lockouts", "wrong", nil), 401)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/admin/lockouts", testAdminToken, nil)
+	mustStatus(t, rr, 200)
+	var list struct {
+		Items []struct {
+			Key      string `json:"key"`
+			Failures int    `json:"failures"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Items[0].Key != "login:account:"+email || list.Items[0].Failures != 6 {
+		t.Fatalf("unexpected lockouts: %s", rr.Body.String())
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/admin/lockouts/unlock", testAdminToken, map[string]any{}), 400)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/admin/lockouts/unlock", testAdminToken, map[string]any{"email": email}), 204)
+	if code := login(testPassword); code != 200 {
+		t.Fatalf("login after unlock: got %d", code)
+	}
+
+	// El reset cuenta cada solicitud y tiene su propio contador.
+	for i := 0; i < 6; i++ {
+		mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": email}), 200)
+	}
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": email})
+	mustStatus(t, rr, 429)
+	if rr.Header().Get("Retry-After") == "" {
+		t.Fatal("missing Retry-After")
+	}
+	if code := login(testPassword); code != 200 {
+		t.Fatalf("reset throttling should not block login: got %d", code)
+	}
+}
+
+func Test_Throttle_MemoryStoreIPBackoff(t *testing.T) {
+	ip := usecase.ThrottlePolicy{FreeAttempts: 2, BaseDelay: time.Minute, MaxDelay: 3 * time.Minute, Window: time.Hour}
+	th := usecase.NewThrottle(memory.NewAttemptMemory(), usecase.DefaultAccountThrottle(), ip)
+	const addr = "203.0.113.7"
+
+	retryAfter := func(email string) time.Duration {
+		t.Helper()
+		var lerr *usecase.LockedError
+		if err := th.Check(usecase.ThrottleLogin, email, addr); !errors.As(err, &lerr) {
+			t.Fatalf("expected lockout for %s, got %v", email, err)
+		}
+		return lerr.RetryAfter
+	}
+
+	// Cuentas distintas desde la misma IP: al tercer fallo se bloquea la IP, no las cuentas.
+	for _, email := range []string{"a@test.com", "b@test.com", "c@test.com"} {
+		if err := th.Fail(usecase.ThrottleLogin, email, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := retryAfter("d@test.com"); d <= 0 || d > time.Minute {
+		t.Fatalf("first lockout: %s", d)
+	}
+	if err := th.Check(usecase.ThrottleLogin, "d@test.com", "198.51.100.1"); err != nil {
+		t.Fatalf("other ip should not be locked: %v", err)
+	}
+	if err := th.Check(usecase.ThrottleReset, "d@test.com", addr); err != nil {
+		t.Fatalf("reset scope should not be locked: %v", err)
+	}
+
+	// Cada fallo dobla la espera hasta el máximo.
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		_ = th.Fail(usecase.ThrottleLogin, "e@test.com", addr)
+		if d := retryAfter("e@test.com"); d <= want-time.Second || d > want {
+			t.Fatalf("got %s, want ~%s", d, want)
+		}
+	}
+
+	// Un login correcto no limpia la IP; el desbloqueo de administración sí.
+	th.Succeed(usecase.ThrottleLogin, "a@test.com")
+	retryAfter("a@test.com")
+	locked, _ := th.Locked()
+	if len(locked) != 1 {
+		t.Fatalf("expected the ip lockout, got %+v", locked)
+	}
+	if err := th.Unlock("", addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Check(usecase.ThrottleLogin, "a@test.com", addr); err != nil {
+		t.Fatalf("still locked after unlock: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Test de integración de la papelera: borrar oculta la entrada sin perderla, se recupera tal cual, el
+// borrado definitivo solo va desde la papelera y la purga se lleva lo que pasa del periodo de retención.
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/usecase"
+)
+
+func Test_VaultTrash(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "trash@test.com")
+
+	create := func(username string) (int64, string) {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": username, "password_plain": "s3cret!"})
+		mustStatus(t, rr, 201)
+		var res struct {
+			ID int64 `json:"id"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res.ID, strconv.FormatInt(res.ID, 10)
+	}
+	type listRes struct {
+		Items []struct {
+			ID        int64   `json:"id"`
+			Username  string  `json:"username"`
+			DeletedAt *string `json:"deleted_at"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+	list := func(path, token string) listRes {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodGet, path, token, nil)
+		mustStatus(t, rr, 200)
+		var res listRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		return res
+	}
+
+	aID, a := create("alice")
+	_, b := create("bob")
+	mustStatus(t, doJSON(t, ts, http.MethodPut, "/api/v1/vault/entries/"+a, token, map[string]any{"password_plain": "n3w-secret!"}), 200)
+
+	// En la papelera la entrada no se ve ni se puede tocar desde el vault.
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+a, token, nil), 200)
+	if res := list("/api/v1/vault/entries?q=alice", token); res.Total != 0 {
+		t.Fatalf("trashed entry still searchable: %+v", res)
+	}
+	if res := list("/api/v1/vault/entries", token); res.Total != 1 || res.Items[0].Username != "bob" {
+		t.Fatalf("unexpected vault after delete: %+v", res)
+	}
+	for _, p := range []string{"", "/password", "/history"} {
+		mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+a+p, token, nil), 404)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, "/api/v1/vault/entries/"+a, token, map[string]any{"notes": "x"}), 404)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+a, token, nil), 404)
+
+	trash := list("/api/v1/vault/trash", token)
+	if trash.Total != 1 || trash.Items[0].ID != aID || trash.Items[0].Username != "alice" || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+	other := registerAndLogin(t, ts, "other-trash@test.com")
+	if res := list("/api/v1/vault/trash", other); res.Total != 0 {
+		t.Fatalf("trash leaked to another user: %+v", res)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/trash/"+a+"/restore", other, nil), 404)
+
+	// Recuperada vuelve con su contraseña y su historial.
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/trash/"+a+"/restore", token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/vault/trash/"+a+"/restore", token, nil), 404)
+	rr := doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+a+"/password", token, nil)
+	mustStatus(t, rr, 200)
+	var revealed struct {
+		Password string `json:"password"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &revealed)
+	if revealed.Password != "n3w-secret!" || len(list("/api/v1/vault/entries/"+a+"/history", token).Items) != 1 {
+		t.Fatalf("entry not restored intact: %s", rr.Body.String())
+	}
+	if res := list("/api/v1/vault/trash", token); res.Total != 0 {
+		t.Fatalf("restored entry still in trash: %+v", res)
+	}
+
+	// El borrado definitivo solo se admite desde la papelera.
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/trash/"+b, token, nil), 404)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+b, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/trash/"+b, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/trash/"+b, token, nil), 404)
+
+	// Purga: solo lo que lleva en la papelera más que la retención, con su historial.
+	_, c := create("carol")
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+a, token, nil), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/vault/entries/"+c, token, nil), 200)
+	if _, err := sqlDB.Exec(`UPDATE secrets SET deleted_at = datetime('now', '-40 days') WHERE id = ?`, aID); err != nil {
+		t.Fatal(err)
+	}
+	vault := usecase.NewVault(sqlrepo.NewSecretSQLite(sqlDB), sqlrepo.NewKeySQLite(sqlDB), sqlrepo.NewUserSQLite(sqlDB), nil, nil)
+	n, err := vault.PurgeExpired(30*24*time.Hour, 1)
+	if err != nil || n != 1 {
+		t.Fatalf("purge: n=%d err=%v", n, err)
+	}
+	if res := list("/api/v1/vault/trash", token); res.Total != 1 || res.Items[0].Username != "carol" {
+		t.Fatalf("unexpected trash after purge: %+v", res)
+	}
+	var rows int
+	_ = sqlDB.QueryRow(`SELECT (SELECT COUNT(*) FROM secrets WHERE id = ?) + (SELECT COUNT(*) FROM secret_history WHERE secret_id = ?)`, aID, aID).Scan(&rows)
+	if rows != 0 {
+		t.Fatalf("purged entry left %d rows", rows)
+	}
+}
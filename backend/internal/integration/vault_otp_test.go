@@ -0,0 +1,139 @@
+// Test de integración de las semillas OTP del vault: vectores de las RFC 4226/6238 con SHA256 y SHA512,
+// códigos de Steam, importación desde otpauth:// y códigos servidos por la API (HOTP gasta el contador).
+package integration_test
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"password-danie/internal/otp"
+	sqlrepo "password-danie/internal/repository/sqlite"
+)
+
+func Test_OTPKeys_AlgorithmsAndSteam(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	// Apéndice B de la RFC 6238 con 8 dígitos; cada algoritmo usa una semilla de su tamaño.
+	for alg, c := range map[string]struct {
+		seed string
+		want map[int64]string
+	}{
+		"SHA1":   {"12345678901234567890", map[int64]string{59: "94287082", 1111111109: "07081804"}},
+		"SHA256": {"12345678901234567890123456789012", map[int64]string{59: "46119246", 1111111109: "68084774"}},
+		"SHA512": {"1234567890123456789012345678901234567890123456789012345678901234", map[int64]string{59: "90693936", 1111111109: "25091201"}},
+	} {
+		k, err := otp.ParseURI("otpauth://totp/ACME:alice@example.com?secret=" + enc.EncodeToString([]byte(c.seed)) + "&algorithm=" + strings.ToLower(alg) + "&digits=8")
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		for unix, want := range c.want {
+			got, remaining, err := k.Generate(time.Unix(unix, 0))
+			if err != nil || got != want {
+				t.Fatalf("%s T=%d: got %q, %v; want %q", alg, unix, got, err, want)
+			}
+			if want := 30 - int(unix%30); remaining != want {
+				t.Fatalf("%s T=%d: remaining %d, want %d", alg, unix, remaining, want)
+			}
+		}
+	}
+
+	// Steam: 5 caracteres de su alfabeto, venga como tipo propio o como encoder=steam.
+	for _, uri := range []string{
+		"otpauth://steam/Steam:bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"otpauth://totp/Steam:bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&encoder=steam&digits=6",
+	} {
+		k, err := otp.ParseURI(uri)
+		if err != nil || k.Type != otp.TypeSteam {
+			t.Fatalf("%s: %+v (err=%v)", uri, k, err)
+		}
+		code, _, _ := k.Generate(time.Unix(1234567890, 0))
+		if len(code) != 5 || strings.Trim(code, "23456789BCDFGHJKMNPQRTVWXY") != "" {
+			t.Fatalf("bad steam code %q", code)
+		}
+	}
+
+	for _, bad := range []string{
+		"https://example.com",
+		"otpauth://totp/x?secret=not-base32!",
+		"otpauth://totp/x?secret=GEZDGNBV&algorithm=MD5",
+		"otpauth://totp/x?secret=GEZDGNBV&digits=4",
+		"otpauth://foo/x?secret=GEZDGNBV",
+	} {
+		if _, err := otp.ParseURI(bad); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func Test_VaultOTP_ImportAndCodes(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+	token := registerAndLogin(t, ts, "otp@test.com")
+
+	// HOTP de la RFC 4226: cada petición da el código del contador y lo avanza.
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{
+		"username": "svc", "password_plain": "p@ss",
+		"otp_uri": "otpauth://hotp/ACME:svc?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0",
+	})
+	mustStatus(t, rr, 201)
+	var created createRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	base := "/api/v1/vault/entries/" + strconv.FormatInt(created.ID, 10)
+	for _, want := range []string{"755224", "287082", "359152"} {
+		rr = doJSON(t, ts, http.MethodGet, base+"/totp", token, nil)
+		mustStatus(t, rr, 200)
+		if !strings.Contains(rr.Body.String(), `"code":"`+want+`"`) {
+			t.Fatalf("want %s: %s", want, rr.Body.String())
+		}
+	}
+
+	// La semilla va sellada en la base de datos y no sale en la vista de la entrada.
+	var stored string
+	_ = sqlDB.QueryRow(`SELECT otp FROM secrets WHERE id = ?`, created.ID).Scan(&stored)
+	if stored == "" || strings.Contains(stored, "GEZDGNBV") {
+		t.Fatalf("otp seed not sealed: %q", stored)
+	}
+	rr = doJSON(t, ts, http.MethodGet, base, token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"has_otp":true`) || strings.Contains(rr.Body.String(), "GEZDGNBV") {
+		t.Fatalf("unexpected entry: %s", rr.Body.String())
+	}
+
+	// El contador se guarda comparando con la semilla leída: con otra semilla no se escribe nada y una
+	// edición de otros campos no lo hace retroceder.
+	var userID int64
+	_ = sqlDB.QueryRow(`SELECT user_id FROM secrets WHERE id = ?`, created.ID).Scan(&userID)
+	if ok, err := sqlrepo.NewSecretSQLite(sqlDB).SwapOTP(userID, created.ID, stored+"x", "stale"); ok || err != nil {
+		t.Fatalf("otp swapped over a different seed: ok=%v err=%v", ok, err)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodPut, base, token, map[string]any{"notes": "hotp"}), 200)
+	rr = doJSON(t, ts, http.MethodGet, base+"/totp", token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"code":"969429"`) {
+		t.Fatalf("want 969429 after editing the entry: %s", rr.Body.String())
+	}
+
+	// TOTP con SHA256 y 8 dígitos.
+	uri := "otpauth://totp/ACME:svc?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA&algorithm=SHA256&digits=8&period=60"
+	mustStatus(t, doJSON(t, ts, http.MethodPut, base, token, map[string]any{"otp_uri": uri}), 200)
+	rr = doJSON(t, ts, http.MethodGet, base+"/totp", token, nil)
+	mustStatus(t, rr, 200)
+	var code struct {
+		Code      string `json:"code"`
+		Type      string `json:"type"`
+		Period    int    `json:"period"`
+		Remaining int    `json:"remaining"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &code)
+	if len(code.Code) != 8 || code.Type != "totp" || code.Period != 60 || code.Remaining < 1 || code.Remaining > 60 {
+		t.Fatalf("unexpected totp: %s", rr.Body.String())
+	}
+
+	mustStatus(t, doJSON(t, ts, http.MethodPut, base, token, map[string]any{"otp_uri": "otpauth://totp/x?secret=@@"}), 400)
+	mustStatus(t, doJSON(t, ts, http.MethodPut, base, token, map[string]any{"otp_uri": ""}), 200)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, base+"/totp", token, nil), 400)
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/999/totp", token, nil), 404)
+}
@@ -0,0 +1,222 @@
+// Test de integración de passkeys con un autenticador software (ECDSA P-256, atestación "none"):
+// alta, segundo factor tras la contraseña, login sin contraseña y rechazo de un contador que retrocede.
+package integration_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:5173"
+)
+
+var b64 = base64.RawURLEncoding
+
+// softAuthenticator hace de llave de seguridad: una sola credencial descubrible con verificación de usuario.
+type softAuthenticator struct {
+	t      *testing.T
+	key    *ecdsa.PrivateKey
+	credID []byte
+	handle []byte
+	count  uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &softAuthenticator{t: t, key: key, credID: id}
+}
+
+// ceremony es la respuesta de los endpoints */begin.
+type ceremony struct {
+	SessionToken string `json:"session_token"`
+	Options      struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+			AllowCredentials []struct {
+				ID string `json:"id"`
+			} `json:"allowCredentials"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+func (a *softAuthenticator) clientData(typ, challenge string) []byte {
+	cd, _ := json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": testOrigin})
+	return cd
+}
+
+// authData: hash del RP ID | flags | contador | (datos de la credencial si attested).
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpHash := sha256.Sum256([]byte(testRPID))
+	out := append(rpHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.count)
+	return append(out, attested...)
+}
+
+// create responde a navigator.credentials.create().
+func (a *softAuthenticator) create(c ceremony) json.RawMessage {
+	var err error
+	if a.handle, err = b64.DecodeString(c.Options.PublicKey.User.ID); err != nil {
+		a.t.Fatalf("user handle: %v", err)
+	}
+	coseKey, err := cbor.Marshal(map[int]any{
+		1: 2, 3: -7, -1: 1, // EC2, ES256, P-256
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	attested := make([]byte, 16) // AAGUID a cero
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credID)))
+	attested = append(append(attested, a.credID...), coseKey...)
+	att, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(0x45, attested), // UP | UV | AT
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return a.credential(map[string]string{
+		"clientDataJSON":    b64.EncodeToString(a.clientData("webauthn.create", c.Options.PublicKey.Challenge)),
+		"attestationObject": b64.EncodeToString(att),
+	})
+}
+
+// get responde a navigator.credentials.get(); cada aserción incrementa el contador.
+func (a *softAuthenticator) get(c ceremony) json.RawMessage {
+	a.count++
+	cd := a.clientData("webauthn.get", c.Options.PublicKey.Challenge)
+	ad := a.authData(0x05, nil) // UP | UV
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return a.credential(map[string]string{
+		"clientDataJSON":    b64.EncodeToString(cd),
+		"authenticatorData": b64.EncodeToString(ad),
+		"signature":         b64.EncodeToString(sig),
+		"userHandle":        b64.EncodeToString(a.handle),
+	})
+}
+
+func (a *softAuthenticator) credential(response map[string]string) json.RawMessage {
+	raw, _ := json.Marshal(map[string]any{
+		"id":       b64.EncodeToString(a.credID),
+		"rawId":    b64.EncodeToString(a.credID),
+		"type":     "public-key",
+		"response": response,
+	})
+	return raw
+}
+
+func begin(t *testing.T, ts *httptest.Server, path, token string, payload any) ceremony {
+	t.Helper()
+	rr := doJSON(t, ts, http.MethodPost, path, token, payload)
+	mustStatus(t, rr, 200)
+	var c ceremony
+	_ = json.Unmarshal(rr.Body.Bytes(), &c)
+	if c.SessionToken == "" || c.Options.PublicKey.Challenge == "" {
+		t.Fatalf("bad ceremony: %s", rr.Body.String())
+	}
+	return c
+}
+
+func Test_WebAuthn_PasskeyLogin(t *testing.T) {
+	ts, _ := newTestAPI(t)
+	const email = "passkey@test.com"
+	token := registerAndLogin(t, ts, email)
+	authn := newSoftAuthenticator(t)
+
+	// Alta de la passkey.
+	reg := begin(t, ts, "/api/v1/auth/webauthn/register/begin", token, nil)
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/webauthn/register/finish", token,
+		map[string]any{"session_token": reg.SessionToken, "name": "portátil", "credential": authn.create(reg)})
+	mustStatus(t, rr, 201)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/auth/webauthn/credentials", token, nil)
+	mustStatus(t, rr, 200)
+	var list struct {
+		Items []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Items[0].Name != "portátil" {
+		t.Fatalf("unexpected credentials: %s", rr.Body.String())
+	}
+	// La sesión de la ceremonia es de un solo uso.
+	mustStatus(t, doJSON(t, ts, http.MethodPost, "/api/v1/auth/webauthn/register/finish", token,
+		map[string]any{"session_token": reg.SessionToken, "credential": authn.create(reg)}), 400)
+
+	// Con passkey, el login con contraseña pide segundo factor.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": email, "password": testPassword})
+	mustStatus(t, rr, 200)
+	var res struct {
+		mfaLoginRes
+		Methods []string `json:"mfa_methods"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &res)
+	if !res.MFARequired || len(res.Methods) != 1 || res.Methods[0] != "webauthn" {
+		t.Fatalf("expected webauthn challenge: %s", rr.Body.String())
+	}
+	mfa := begin(t, ts, "/api/v1/auth/webauthn/mfa/begin", "", map[string]any{"mfa_token": res.MFAToken})
+	if len(mfa.Options.PublicKey.AllowCredentials) != 1 {
+		t.Fatalf("expected allowCredentials with the registered passkey")
+	}
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/webauthn/mfa/finish", "",
+		map[string]any{"mfa_token": res.MFAToken, "session_token": mfa.SessionToken, "credential": authn.get(mfa)})
+	mustStatus(t, rr, 200)
+
+	// Login sin contraseña con la passkey descubrible.
+	login := begin(t, ts, "/api/v1/auth/webauthn/login/begin", "", nil)
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/webauthn/login/finish", "",
+		map[string]any{"session_token": login.SessionToken, "credential": authn.get(login)})
+	mustStatus(t, rr, 200)
+	var tokens loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &tokens)
+	if tokens.User.Email != email {
+		t.Fatalf("logged in as %q", tokens.User.Email)
+	}
+	mustStatus(t, doJSON(t, ts, http.MethodGet, "/api/v1/users/me", tokens.AccessToken, nil), 200)
+
+	// Un contador que retrocede delata un autenticador clonado.
+	authn.count = 0
+	login = begin(t, ts, "/api/v1/auth/webauthn/login/begin", "", nil)
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/webauthn/login/finish", "",
+		map[string]any{"session_token": login.SessionToken, "credential": authn.get(login)})
+	mustStatus(t, rr, 401)
+	if !strings.Contains(rr.Body.String(), "cloned") {
+		t.Fatalf("expected clone rejection: %s", rr.Body.String())
+	}
+
+	// Sin passkeys el login vuelve a ser de un paso.
+	mustStatus(t, doJSON(t, ts, http.MethodDelete, "/api/v1/auth/webauthn/credentials/"+strconv.FormatInt(list.Items[0].ID, 10), token, nil), 204)
+	if res := mfaLogin(t, ts, email); res.MFARequired || res.AccessToken == "" {
+		t.Fatalf("expected plain login after removing the passkey: %+v", res)
+	}
+}
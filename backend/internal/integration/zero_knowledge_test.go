@@ -0,0 +1,125 @@
+// Test de integración del modo zero-knowledge: prelogin, blobs opacos y reset que vacía el vault.
+package integration_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	sqlrepo "password-danie/internal/repository/sqlite"
+	"password-danie/internal/usecase"
+)
+
+func Test_ZeroKnowledgeVault(t *testing.T) {
+	ts, sqlDB := newTestAPI(t)
+
+	// Prelogin responde igual a cualquier email: solo parámetros KDF, estables para cada email. Un email sin
+	// cuenta y una cuenta "server" no se distinguen de una zero-knowledge.
+	type preloginRes struct {
+		KDF struct {
+			Algorithm  string `json:"algorithm"`
+			Iterations int    `json:"iterations"`
+			Salt       string `json:"salt"`
+		} `json:"kdf"`
+	}
+	prelogin := func(email string) (string, preloginRes) {
+		t.Helper()
+		rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/prelogin", "", map[string]any{"email": email})
+		mustStatus(t, rr, 200)
+		var fields map[string]json.RawMessage
+		_ = json.Unmarshal(rr.Body.Bytes(), &fields)
+		var res preloginRes
+		_ = json.Unmarshal(rr.Body.Bytes(), &res)
+		salt, err := base64.StdEncoding.DecodeString(res.KDF.Salt)
+		if len(fields) != 1 || fields["kdf"] == nil || res.KDF.Algorithm == "" || res.KDF.Iterations == 0 || err != nil || len(salt) < 16 {
+			t.Fatalf("unexpected prelogin for %s: %s", email, rr.Body.String())
+		}
+		return rr.Body.String(), res
+	}
+	registerAndLogin(t, ts, "server-prelogin@test.com")
+	unknown, _ := prelogin("zk@test.com")
+	server, _ := prelogin("server-prelogin@test.com")
+	if again, _ := prelogin("zk@test.com"); again != unknown || unknown == server {
+		t.Fatalf("decoy kdf must be stable per email and differ between emails: %s / %s", unknown, server)
+	}
+	if again, _ := prelogin("server-prelogin@test.com"); again != server {
+		t.Fatalf("server account prelogin not stable: %s / %s", server, again)
+	}
+	// La clave de los parámetros de relleno se guarda una vez y no depende de la clave maestra.
+	keys := sqlrepo.NewKeySQLite(sqlDB)
+	k1, err := usecase.PreloginKey(keys)
+	if err != nil || len(k1) != 32 {
+		t.Fatalf("prelogin key: %x (err=%v)", k1, err)
+	}
+	if k2, err := usecase.PreloginKey(keys); err != nil || string(k2) != string(k1) {
+		t.Fatalf("prelogin key changed: %x -> %x (err=%v)", k1, k2, err)
+	}
+
+	// Registro: el cliente manda su hash de autenticación derivado y los parámetros KDF.
+	kdf := map[string]any{
+		"algorithm":  "pbkdf2-sha256",
+		"iterations": 600000,
+		"salt":       base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")),
+	}
+	authKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	rr := doJSON(t, ts, http.MethodPost, "/api/v1/auth/register", "", map[string]any{
+		"email": "zk@test.com", "password": authKey, "zero_knowledge": true, "kdf": kdf,
+	})
+	mustStatus(t, rr, 201)
+
+	// Con la cuenta creada salen sus parámetros reales; el modo llega con el usuario en el login.
+	if body, pre := prelogin("zk@test.com"); pre.KDF.Algorithm != "pbkdf2-sha256" || pre.KDF.Iterations != 600000 || pre.KDF.Salt != kdf["salt"] {
+		t.Fatalf("bad prelogin: %s", body)
+	}
+
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "zk@test.com", "password": authKey})
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"vault_mode":"zero_knowledge"`) {
+		t.Fatalf("login must report the vault mode: %s", rr.Body.String())
+	}
+	var logres loginRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &logres)
+	token := logres.AccessToken
+
+	// El servidor rechaza texto en claro y guarda el blob sin tocarlo.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"username": "u", "password_plain": "p"})
+	mustStatus(t, rr, 400)
+	blob := base64.StdEncoding.EncodeToString([]byte("client-side ciphertext"))
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/vault/entries", token, map[string]any{"blob": blob})
+	mustStatus(t, rr, 201)
+	var cres createRes
+	_ = json.Unmarshal(rr.Body.Bytes(), &cres)
+	idStr := strconv.FormatInt(cres.ID, 10)
+
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+idStr, token, nil)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), blob) {
+		t.Fatalf("blob not returned as stored: %s", rr.Body.String())
+	}
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries/"+idStr+"/password", token, nil)
+	mustStatus(t, rr, 400)
+	rr = doJSON(t, ts, http.MethodGet, "/api/v1/vault/entries?q=git", token, nil)
+	mustStatus(t, rr, 400)
+
+	// Reset: sin la contraseña maestra anterior el vault es ilegible, así que se exige confirmar el borrado.
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/request", "", map[string]any{"email": "zk@test.com"})
+	mustStatus(t, rr, 200)
+	var rres map[string]string
+	_ = json.Unmarshal(rr.Body.Bytes(), &rres)
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+	confirm := map[string]any{"token": rres["reset_token"], "new_password": newKey, "kdf": kdf}
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/confirm", "", confirm)
+	mustStatus(t, rr, 400)
+	confirm["wipe_vault"] = true
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/reset/confirm", "", confirm)
+	mustStatus(t, rr, 200)
+	if !strings.Contains(rr.Body.String(), `"vault_wiped":1`) {
+		t.Fatalf("expected one wiped entry: %s", rr.Body.String())
+	}
+
+	rr = doJSON(t, ts, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"email": "zk@test.com", "password": newKey})
+	mustStatus(t, rr, 200)
+}
@@ -1,4 +1,4 @@
-// Middleware Gin de autenticación JWT: exige Bearer token y expone claims y userID en contexto.
+// Middleware Gin de autenticación JWT: exige Bearer token de una sesión no revocada y expone claims, userID y sesión en contexto.
 package middleware
 
 import (
@@ -11,8 +11,14 @@ import (
 
 const CtxClaims = "claims"
 const CtxUserID = "userID"
+const CtxSessionID = "sessionID"
 
-func AuthRequired() gin.HandlerFunc {
+// SessionChecker decide si la sesión (claim sid) de un token sigue viva.
+type SessionChecker interface {
+	Check(sessionID string) error
+}
+
+func AuthRequired(sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if !strings.HasPrefix(h, "Bearer ") {
@@ -25,24 +31,18 @@ func AuthRequired() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		c.Set(CtxClaims, claims)
-
-		var uid int64
-		if v, ok := claims["sub"]; ok {
-			switch t := v.(type) {
-			case float64:
-				uid = int64(t)
-			case int64:
-				uid = t
-			case int:
-				uid = int64(t)
-			}
+		uid, err := claims.UserID()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
 		}
-		if uid == 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid subject"})
+		if err := sessions.Check(claims.SessionID); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
+		c.Set(CtxClaims, claims)
 		c.Set(CtxUserID, uid)
+		c.Set(CtxSessionID, claims.SessionID)
 		c.Next()
 	}
 }
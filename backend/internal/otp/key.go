@@ -0,0 +1,182 @@
+// Claves OTP de cuentas de terceros (las que se guardan en el vault): TOTP y HOTP con SHA1, SHA256 o
+// SHA512, dígitos y periodo configurables y códigos de Steam, importables desde una URI otpauth://.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipos de clave.
+const (
+	TypeTOTP  = "totp"
+	TypeHOTP  = "hotp"
+	TypeSteam = "steam" // TOTP de 30 s con SHA1 y 5 caracteres de un alfabeto propio
+)
+
+const (
+	steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+	steamDigits   = 5
+	maxPeriod     = 300
+)
+
+type Key struct {
+	Type      string
+	Issuer    string
+	Account   string
+	Secret    string // base32 normalizado (mayúsculas, sin relleno ni espacios)
+	Algorithm string // SHA1, SHA256 o SHA512
+	Digits    int
+	Period    int    // segundos; solo totp y steam
+	Counter   uint64 // próximo contador; solo hotp
+}
+
+// ParseURI lee una otpauth://TIPO/ETIQUETA?secret=...; Steam se reconoce por el tipo steam o por encoder=steam
+// (lo que exportan las apps más habituales).
+func ParseURI(raw string) (*Key, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "otpauth" {
+		return nil, errors.New("otp: not an otpauth:// uri")
+	}
+	q := u.Query()
+	k := &Key{Type: strings.ToLower(u.Host), Algorithm: "SHA1", Digits: Digits, Period: Period}
+	if q.Get("encoder") == "steam" {
+		k.Type = TypeSteam
+	}
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		k.Issuer, k.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		k.Account = strings.TrimSpace(label)
+	}
+	if v := q.Get("issuer"); v != "" {
+		k.Issuer = v
+	}
+	k.Secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(q.Get("secret"), " ", ""), "="))
+	if v := q.Get("algorithm"); v != "" {
+		k.Algorithm = strings.ToUpper(v)
+	}
+	if v := q.Get("digits"); v != "" {
+		if k.Digits, err = strconv.Atoi(v); err != nil {
+			return nil, errors.New("otp: bad digits")
+		}
+	}
+	if v := q.Get("period"); v != "" {
+		if k.Period, err = strconv.Atoi(v); err != nil {
+			return nil, errors.New("otp: bad period")
+		}
+	}
+	if v := q.Get("counter"); v != "" {
+		if k.Counter, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, errors.New("otp: bad counter")
+		}
+	}
+	if k.Type == TypeSteam {
+		k.Algorithm, k.Digits, k.Period = "SHA1", steamDigits, Period
+	}
+	return k, k.validate()
+}
+
+func (k *Key) validate() error {
+	switch k.Type {
+	case TypeTOTP, TypeHOTP, TypeSteam:
+	default:
+		return fmt.Errorf("otp: unsupported type %q", k.Type)
+	}
+	if newHash(k.Algorithm) == nil {
+		return fmt.Errorf("otp: unsupported algorithm %q", k.Algorithm)
+	}
+	if k.Type != TypeSteam && (k.Digits < 6 || k.Digits > 8) {
+		return errors.New("otp: digits must be between 6 and 8")
+	}
+	if k.Type != TypeHOTP && (k.Period < 1 || k.Period > maxPeriod) {
+		return fmt.Errorf("otp: period must be between 1 and %d seconds", maxPeriod)
+	}
+	raw, err := b32.DecodeString(k.Secret)
+	if err != nil || len(raw) == 0 {
+		return errors.New("otp: bad secret")
+	}
+	return nil
+}
+
+// URI vuelve a escribir la clave como otpauth:// con todos los parámetros explícitos.
+func (k *Key) URI() string {
+	v := url.Values{}
+	v.Set("secret", k.Secret)
+	if k.Issuer != "" {
+		v.Set("issuer", k.Issuer)
+	}
+	v.Set("algorithm", k.Algorithm)
+	v.Set("digits", strconv.Itoa(k.Digits))
+	if k.Type == TypeHOTP {
+		v.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		v.Set("period", strconv.Itoa(k.Period))
+	}
+	label := url.PathEscape(k.Account)
+	if k.Issuer != "" {
+		label = url.PathEscape(k.Issuer) + ":" + label
+	}
+	return "otpauth://" + k.Type + "/" + label + "?" + v.Encode()
+}
+
+// Generate devuelve el código para t (totp y steam) o para el contador actual (hotp; el llamador debe
+// avanzar Counter y guardarlo). remaining son los segundos de validez que le quedan (0 en hotp).
+func (k *Key) Generate(t time.Time) (code string, remaining int, err error) {
+	key, err := b32.DecodeString(k.Secret)
+	if err != nil {
+		return "", 0, fmt.Errorf("otp: bad secret: %w", err)
+	}
+	mov := k.Counter
+	if k.Type != TypeHOTP {
+		mov = uint64(t.Unix() / int64(k.Period))
+		remaining = k.Period - int(t.Unix()%int64(k.Period))
+	}
+	bin := truncate(newHash(k.Algorithm), key, mov)
+	if k.Type == TypeSteam {
+		out := make([]byte, steamDigits)
+		for i := range out {
+			out[i] = steamAlphabet[bin%uint32(len(steamAlphabet))]
+			bin /= uint32(len(steamAlphabet))
+		}
+		return string(out), remaining, nil
+	}
+	mod := uint64(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, uint64(bin)%mod), remaining, nil
+}
+
+func newHash(alg string) func() hash.Hash {
+	switch alg {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// truncate es el HMAC del contador con truncado dinámico (RFC 4226 §5.3), sin reducir a dígitos.
+func truncate(h func() hash.Hash, key []byte, counter uint64) uint32 {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(h, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	return binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+}
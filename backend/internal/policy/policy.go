@@ -0,0 +1,172 @@
+// Package policy valida contraseñas maestras: longitud, clases de caracteres, lista local de contraseñas
+// comunes, puntuación de fortaleza (estilo zxcvbn), que no contengan el email y que no aparezcan en
+// filtraciones conocidas. Devuelve motivos estructurados.
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"password-danie/internal/breach"
+)
+
+// Códigos de motivo (estables: la UI los traduce).
+const (
+	ReasonTooShort       = "too_short"
+	ReasonTooLong        = "too_long"
+	ReasonMissingClasses = "missing_classes"
+	ReasonCommon         = "common_password"
+	ReasonContainsEmail  = "contains_email"
+	ReasonTooWeak        = "too_weak"
+	ReasonBreached       = "breached"
+)
+
+type Policy struct {
+	MinLength  int // en caracteres, no bytes
+	MaxLength  int
+	MinClasses int // de 4: minúsculas, mayúsculas, dígitos, símbolos
+	MinScore   int // 0..4, ver Estimate
+
+	// Breaches es el volcado local de filtraciones (nil = no se comprueba). Con BlockBreached una
+	// contraseña filtrada se rechaza; sin él se acepta con un aviso.
+	Breaches      breach.Dataset
+	BlockBreached bool
+}
+
+func Default() Policy {
+	return Policy{MinLength: 12, MaxLength: 1024, MinClasses: 2, MinScore: 3}
+}
+
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error agrupa todos los motivos por los que se rechaza una contraseña.
+type Error struct {
+	Reasons []Reason `json:"reasons"`
+	Score   int      `json:"score"`
+}
+
+func (e *Error) Error() string {
+	codes := make([]string, len(e.Reasons))
+	for i, r := range e.Reasons {
+		codes[i] = r.Code
+	}
+	return "password does not meet policy: " + strings.Join(codes, ", ")
+}
+
+// Check devuelve los avisos que no impiden usar la contraseña y, si no cumple, un *Error con todos
+// los motivos a la vez (no solo el primero). Otros errores vienen de leer el volcado de filtraciones.
+func (p Policy) Check(password, email string) ([]Reason, error) {
+	var reasons, warnings []Reason
+	add := func(code, format string, args ...any) {
+		reasons = append(reasons, Reason{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		add(ReasonTooShort, "must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		add(ReasonTooLong, "must be at most %d characters", p.MaxLength)
+	}
+	if c := countClasses(password); c < p.MinClasses {
+		add(ReasonMissingClasses, "must mix at least %d of: lowercase, uppercase, digits, symbols", p.MinClasses)
+	}
+	if isCommon(password) {
+		add(ReasonCommon, "is one of the most common passwords")
+	}
+	if containsEmail(password, email) {
+		add(ReasonContainsEmail, "must not contain your email or its name part")
+	}
+	s := Estimate(password, emailTokens(email)...)
+	if s.Score < p.MinScore {
+		add(ReasonTooWeak, "is too easy to guess (strength %d of 4, need %d)", s.Score, p.MinScore)
+	}
+	if p.Breaches != nil {
+		n, err := p.Breaches.Count(password)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			r := Reason{Code: ReasonBreached, Message: fmt.Sprintf("has appeared %d times in known data breaches", n)}
+			if p.BlockBreached {
+				reasons = append(reasons, r)
+			} else {
+				warnings = append(warnings, r)
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		return warnings, nil
+	}
+	return nil, &Error{Reasons: reasons, Score: s.Score}
+}
+
+func countClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// isCommon: coincidencia exacta, con leet deshecho o quitando dígitos/símbolos finales ("Password123!").
+func isCommon(password string) bool {
+	p := strings.ToLower(password)
+	if common[p] || common[unleet(p)] {
+		return true
+	}
+	base := strings.TrimRightFunc(p, func(r rune) bool { return !unicode.IsLetter(r) })
+	return utf8.RuneCountInString(base) >= 4 && (common[base] || common[unleet(base)])
+}
+
+func containsEmail(password, email string) bool {
+	p := strings.ToLower(password)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	if strings.Contains(p, email) {
+		return true
+	}
+	for _, t := range emailTokens(email) {
+		if strings.Contains(p, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// emailTokens: la parte local entera y sus trozos (separados por . _ - +) de al menos 4 letras.
+func emailTokens(email string) []string {
+	local, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	var out []string
+	if utf8.RuneCountInString(local) >= 3 {
+		out = append(out, local)
+	}
+	for _, part := range strings.FieldsFunc(local, func(r rune) bool { return r == '.' || r == '_' || r == '-' || r == '+' }) {
+		if part != local && utf8.RuneCountInString(part) >= 4 {
+			out = append(out, part)
+		}
+	}
+	return out
+}
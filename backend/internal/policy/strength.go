@@ -0,0 +1,192 @@
+// Estimación de fortaleza al estilo zxcvbn: se trocea la contraseña en patrones adivinables (palabras
+// comunes, repeticiones, secuencias y filas de teclado) y se suma la entropía de cada trozo.
+package policy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonList string
+
+var (
+	common     = loadCommon(commonList)
+	commonBits = math.Log2(float64(len(common)))
+	maxWordLen = 20
+)
+
+// Strength: Entropy en bits; Score de 0 (trivial) a 4 (muy fuerte).
+type Strength struct {
+	Score   int     `json:"score"`
+	Entropy float64 `json:"entropy"`
+}
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "azertyuiop", "qsdfghjklm"}
+
+var leet = map[rune]rune{'0': 'o', '1': 'i', '3': 'e', '4': 'a', '@': 'a', '$': 's', '5': 's', '7': 't', '!': 'i'}
+
+func loadCommon(list string) map[string]bool {
+	m := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			m[line] = true
+		}
+	}
+	return m
+}
+
+func unleet(s string) string {
+	return strings.Map(func(r rune) rune {
+		if l, ok := leet[r]; ok {
+			return l
+		}
+		return r
+	}, s)
+}
+
+// Estimate calcula la fortaleza; userWords (p. ej. trozos del email) cuentan como casi gratis de adivinar.
+func Estimate(password string, userWords ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{}
+	}
+	charBits := math.Log2(float64(poolSize(runes)))
+	user := map[string]bool{}
+	for _, w := range userWords {
+		user[strings.ToLower(w)] = true
+	}
+
+	var bits float64
+	for i := 0; i < len(runes); {
+		if n, b := matchWord(runes[i:], user); n > 0 {
+			bits += b
+			i += n
+			continue
+		}
+		if n := repeatRun(runes[i:]); n >= 3 {
+			bits += charBits + math.Log2(float64(n))
+			i += n
+			continue
+		}
+		if n := sequenceRun(runes[i:]); n >= 3 {
+			bits += charBits + math.Log2(float64(n))
+			i += n
+			continue
+		}
+		bits += charBits
+		i++
+	}
+	return Strength{Score: scoreFor(bits), Entropy: math.Round(bits*10) / 10}
+}
+
+func scoreFor(bits float64) int {
+	switch {
+	case bits < 20:
+		return 0
+	case bits < 30:
+		return 1
+	case bits < 45:
+		return 2
+	case bits < 60:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func poolSize(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	pool := 0
+	for _, c := range []struct {
+		ok bool
+		n  int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.ok {
+			pool += c.n
+		}
+	}
+	return pool
+}
+
+// matchWord busca la palabra común (o del usuario) más larga al inicio; devuelve su longitud y su coste en bits.
+func matchWord(runes []rune, user map[string]bool) (int, float64) {
+	max := len(runes)
+	if max > maxWordLen {
+		max = maxWordLen
+	}
+	for n := max; n >= 3; n-- {
+		raw := string(runes[:n])
+		word := strings.ToLower(raw)
+		extra := 0.0
+		if word != raw {
+			extra++ // variantes de mayúsculas
+		}
+		switch {
+		case user[word]:
+			return n, 1 + extra
+		case n >= 4 && common[word]:
+			return n, commonBits + extra
+		case n >= 4 && common[unleet(word)]:
+			return n, commonBits + extra + 1
+		}
+	}
+	return 0, 0
+}
+
+// repeatRun: cuántos caracteres iguales seguidos hay al inicio.
+func repeatRun(runes []rune) int {
+	n := 1
+	for n < len(runes) && runes[n] == runes[0] {
+		n++
+	}
+	return n
+}
+
+// sequenceRun: cuántos caracteres al inicio avanzan un paso en el mismo sentido (abc, 987, qwer, lkj).
+func sequenceRun(runes []rune) int {
+	if len(runes) < 2 {
+		return len(runes)
+	}
+	dir := seqStep(runes[0], runes[1])
+	if dir == 0 {
+		return 1
+	}
+	n := 2
+	for n < len(runes) && seqStep(runes[n-1], runes[n]) == dir {
+		n++
+	}
+	return n
+}
+
+// seqStep devuelve +1/-1 si b sigue (o precede) a a en el alfabeto, los dígitos o una fila del teclado.
+func seqStep(a, b rune) int {
+	a, b = unicode.ToLower(a), unicode.ToLower(b)
+	if d := b - a; (d == 1 || d == -1) && (unicode.IsLetter(a) && unicode.IsLetter(b) || unicode.IsDigit(a) && unicode.IsDigit(b)) {
+		return int(d)
+	}
+	for _, row := range keyboardRows {
+		ia, ib := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if ia >= 0 && ib >= 0 && (ib-ia == 1 || ia-ib == 1) {
+			return ib - ia
+		}
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+// Package repository declara puertos (interfaces) para los adjuntos de las entradas del vault.
+package repository
+
+import "password-danie/internal/domain"
+
+type AttachmentRepo interface {
+	// CreateWithinQuota guarda el adjunto solo si con él el total del usuario no pasa de quota; ok=false si la pasa.
+	CreateWithinQuota(a *domain.Attachment, quota int64) (id int64, ok bool, err error)
+	GetByID(userID, secretID, id int64) (*domain.Attachment, error)
+	ListBySecret(userID, secretID int64) ([]domain.Attachment, error)
+	// UsedBytes es la suma de tamaños en claro de los adjuntos del usuario.
+	UsedBytes(userID int64) (int64, error)
+	Delete(userID, id int64) error
+	DeleteBySecret(userID, secretID int64) error
+}
@@ -0,0 +1,19 @@
+// Package repository declara puertos (interfaces) para los contadores de fallos de login y reset.
+package repository
+
+import (
+	"time"
+
+	"password-danie/internal/domain"
+)
+
+// AttemptRepo tiene dos implementaciones: SQLite (compartida entre reinicios) y en memoria (un solo proceso).
+type AttemptRepo interface {
+	// Get devuelve nil si la clave no tiene fallos.
+	Get(key string) (*domain.Attempts, error)
+	// Fail suma un fallo (empezando de nuevo si el anterior es más viejo que window) y devuelve el total.
+	Fail(key string, now time.Time, window time.Duration) (int, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+	ListLocked(now time.Time) ([]domain.Attempts, error)
+}
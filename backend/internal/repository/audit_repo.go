@@ -0,0 +1,12 @@
+// Package repository declara puertos (interfaces) para el registro de auditoría.
+package repository
+
+import "password-danie/internal/domain"
+
+type AuditRepo interface {
+	// Append encadena el evento tras el último (Seal) y lo guarda; las escrituras se serializan.
+	Append(e *domain.AuditEvent) error
+	ListByUser(userID int64, limit, offset int) ([]domain.AuditEvent, int, error)
+	// Chain devuelve hasta limit eventos con id > afterID en orden, para verificar la cadena por lotes.
+	Chain(afterID int64, limit int) ([]domain.AuditEvent, error)
+}
@@ -0,0 +1,14 @@
+// Package repository declara puertos (interfaces) para las claves de datos envueltas de cada usuario y las
+// claves propias del servidor.
+package repository
+
+import "password-danie/internal/domain"
+
+type KeyRepo interface {
+	GetUserKey(userID int64) (*domain.UserKey, error)
+	// CreateUserKey no sobrescribe una clave ya existente (la primera que se guarda gana).
+	CreateUserKey(k *domain.UserKey) error
+	// Claves del servidor por nombre (nil si no existe); CreateServerKey tampoco sobrescribe.
+	GetServerKey(name string) ([]byte, error)
+	CreateServerKey(name string, key []byte) error
+}
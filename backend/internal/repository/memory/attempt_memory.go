@@ -0,0 +1,94 @@
+// Package memory contiene adaptadores en memoria de los puertos de repository. Sirven para un solo
+// proceso: el estado se pierde al reiniciar y no se comparte entre réplicas.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+// maxAttemptKeys limita el mapa: por encima se purgan las claves caducadas (p. ej. barridos de IPs).
+const maxAttemptKeys = 10000
+
+type AttemptMemory struct {
+	mu     sync.Mutex
+	window time.Duration // última ventana usada en Fail, para purgar
+	byKey  map[string]*domain.Attempts
+}
+
+func NewAttemptMemory() repository.AttemptRepo {
+	return &AttemptMemory{byKey: map[string]*domain.Attempts{}}
+}
+
+func (r *AttemptMemory) Get(key string) (*domain.Attempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.byKey[key]
+	if !ok {
+		return nil, nil
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (r *AttemptMemory) Fail(key string, now time.Time, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.window = window
+	a, ok := r.byKey[key]
+	if !ok {
+		if len(r.byKey) >= maxAttemptKeys {
+			r.purge(now)
+		}
+		a = &domain.Attempts{Key: key}
+		r.byKey[key] = a
+	}
+	if a.LastFailure.Before(now.Add(-window)) {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = now
+	return a.Failures, nil
+}
+
+func (r *AttemptMemory) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.byKey[key]; ok {
+		a.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *AttemptMemory) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byKey, key)
+	return nil
+}
+
+func (r *AttemptMemory) ListLocked(now time.Time) ([]domain.Attempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Attempts
+	for _, a := range r.byKey {
+		if a.Locked(now) {
+			out = append(out, *a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LockedUntil.After(*out[j].LockedUntil) })
+	return out, nil
+}
+
+// purge quita las claves sin bloqueo vigente cuyo último fallo ya salió de la ventana. Con r.mu tomado.
+func (r *AttemptMemory) purge(now time.Time) {
+	for k, a := range r.byKey {
+		if !a.Locked(now) && a.LastFailure.Before(now.Add(-r.window)) {
+			delete(r.byKey, k)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package repository declara puertos (interfaces) para el segundo factor: TOTP, códigos de recuperación y retos de login.
+package repository
+
+import "password-danie/internal/domain"
+
+type MFARepo interface {
+	GetTOTP(userID int64) (*domain.TOTP, error)
+	// SaveTOTP guarda un alta pendiente; no pisa un TOTP ya activado (devuelve false).
+	SaveTOTP(t *domain.TOTP) (bool, error)
+	// EnableTOTP activa el alta pendiente con el paso del primer código y guarda los códigos de recuperación.
+	EnableTOTP(userID, step int64, recoveryHashes []string) (bool, error)
+	DeleteTOTP(userID int64) error
+	// UseStep acepta un paso solo si es posterior al último usado (false = código repetido).
+	UseStep(userID, step int64) (bool, error)
+
+	UseRecoveryCode(userID int64, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int64) (int, error)
+
+	CreateChallenge(c *domain.MFAChallenge) error
+	GetChallenge(tokenHash string) (*domain.MFAChallenge, error)
+	// FailChallenge suma un intento fallido y lo invalida al llegar a maxAttempts.
+	FailChallenge(id int64, maxAttempts int) error
+	// ConsumeChallenge lo marca como usado; false si ya lo estaba.
+	ConsumeChallenge(id int64) (bool, error)
+}
@@ -0,0 +1,15 @@
+// Package repository declara puertos (interfaces) para los refresh tokens y sus familias de rotación.
+package repository
+
+import "password-danie/internal/domain"
+
+type RefreshTokenRepo interface {
+	Create(t *domain.RefreshToken) error
+	GetByHash(tokenHash string) (*domain.RefreshToken, error)
+	// Rotate marca old como usado y guarda next en la misma transacción. Devuelve false si old
+	// ya estaba usado o revocado (otra petición llegó antes).
+	Rotate(oldID int64, next *domain.RefreshToken) (bool, error)
+	RevokeFamily(familyID string) error
+	// RevokeUser revoca los tokens del usuario salvo los de la familia exceptFamily ("" = todos).
+	RevokeUser(userID int64, exceptFamily string) error
+}
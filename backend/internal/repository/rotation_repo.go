@@ -0,0 +1,21 @@
+// Package repository declara puertos (interfaces) para la rotación de la clave maestra por lotes.
+package repository
+
+import "password-danie/internal/domain"
+
+type RotationRepo interface {
+	// estado del trabajo
+	Latest() (*domain.KeyRotation, error)
+	Start(targetVersion int) (*domain.KeyRotation, error)
+	SaveProgress(j *domain.KeyRotation) error
+
+	// lotes a procesar: filas con versión distinta de la objetivo y id > afterID
+	UserKeysBatch(afterUserID int64, targetVersion, limit int) ([]domain.UserKey, error)
+	LegacySecretsBatch(afterID int64, targetVersion, limit int) ([]domain.Secret, error)
+	TOTPBatch(afterUserID int64, targetVersion, limit int) ([]domain.TOTP, error)
+
+	// escrituras optimistas: solo aplican si la fila no cambió desde que se leyó
+	RewrapUserKey(k *domain.UserKey, prevWrapped string) (bool, error)
+	ReencryptSecret(s *domain.Secret, prevCipher string) (bool, error)
+	ReencryptTOTP(t *domain.TOTP, prevCipher string) (bool, error)
+}
@@ -1,19 +1,62 @@
 // Package repository declara puertos (interfaces) para secretos del vault y filtros de listado.
 package repository
 
-import "password-danie/internal/domain"
+import (
+	"time"
 
+	"password-danie/internal/domain"
+)
+
+// ListFilter combina filtros en claro (filas antiguas sin campos cifrados) con sus
+// equivalentes en índices ciegos (filas SchemeSealed/SchemeBound).
 type ListFilter struct {
-	Q      string
-	Domain string 
-	Limit  int
-	Offset int
+	Q           string
+	Domain      string
+	Type        string   // tipo de entrada ("" = todos)
+	QueryTokens []string // índices ciegos de cada token de Q
+	DomainIndex string   // índice ciego de Domain
+	Limit       int
+	Offset      int
 }
 
 type SecretRepo interface {
 	Create(s *domain.Secret) (int64, error)
+	// CreateSealed reserva el id y llama a build dentro de la misma transacción, para cifrados
+	// que dependen del id de la fila. build no debe acceder a la base de datos.
+	CreateSealed(userID int64, build func(id int64) (*domain.Secret, error)) (int64, error)
+	// GetByID y List no devuelven las entradas que están en la papelera
 	GetByID(userID, id int64) (*domain.Secret, error)
 	List(userID int64, f ListFilter) ([]domain.Secret, int, error)
 	Update(s *domain.Secret) error
+	// SwapOTP cambia solo la semilla OTP y solo si sigue siendo prevOTP (cifrada, tal como se leyó);
+	// false si entretanto cambió o la entrada ya no está en el vault
+	SwapOTP(userID, id int64, prevOTP, otp string) (bool, error)
+	// Delete borra la entrada de forma definitiva, esté o no en la papelera
 	Delete(userID, id int64) error
+	DeleteAll(userID int64) (int64, error)
+
+	// papelera: Trash marca la entrada como borrada y Untrash la recupera; false si no estaba en el
+	// vault o en la papelera, respectivamente
+	Trash(userID, id int64) (bool, error)
+	Untrash(userID, id int64) (bool, error)
+	GetTrashed(userID, id int64) (*domain.Secret, error)
+	ListTrash(userID int64, limit, offset int) ([]domain.Secret, int, error)
+	// ListExpiredTrash devuelve entradas de cualquier usuario que están en la papelera desde antes de before
+	ListExpiredTrash(before time.Time, limit int) ([]domain.Secret, error)
+
+	// migración de esquema de cifrado: lotes por esquema y escritura solo si la fila sigue en prevScheme
+	ListByScheme(schemes []int, afterID int64, limit int) ([]domain.Secret, error)
+	UpgradeScheme(s *domain.Secret, prevScheme int) (bool, error)
+
+	// auditoría de revelados
+	LogReveal(userID, secretID int64, ip, userAgent string) error
+
+	// historial de versiones: UpdateWithRevision guarda la entrada y la versión anterior en la misma
+	// transacción (rev nil = sin versión)
+	UpdateWithRevision(s *domain.Secret, rev *domain.SecretRevision) error
+	ListRevisions(userID, secretID int64) ([]domain.SecretRevision, error)
+	GetRevision(userID, secretID, id int64) (*domain.SecretRevision, error)
+	// PruneRevisions deja las keep versiones más recientes de la entrada (0 = todas) y borra las
+	// anteriores a before (cero = ninguna).
+	PruneRevisions(userID, secretID int64, keep int, before time.Time) error
 }
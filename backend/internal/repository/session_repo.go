@@ -0,0 +1,12 @@
+// Package repository declara puertos (interfaces) para las sesiones de login y su revocación.
+package repository
+
+import "password-danie/internal/domain"
+
+type SessionRepo interface {
+	Create(s *domain.Session) error
+	GetByID(id string) (*domain.Session, error)
+	Revoke(id string) error
+	// RevokeUser revoca las sesiones activas del usuario salvo exceptID ("" = todas) y devuelve sus ids.
+	RevokeUser(userID int64, exceptID string) ([]string, error)
+}
@@ -0,0 +1,84 @@
+// Adaptador SQLite de AttachmentRepo: metadatos de los adjuntos (el contenido está en el almacén de blobs).
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type AttachmentSQLite struct{ db *sql.DB }
+
+func NewAttachmentSQLite(db *sql.DB) repository.AttachmentRepo { return &AttachmentSQLite{db: db} }
+
+const attachmentColumns = `id, user_id, secret_id, blob_key, name, content_type, size, key_cipher, key_iv, created_at`
+
+func scanAttachment(row rowScanner) (*domain.Attachment, error) {
+	var a domain.Attachment
+	if err := row.Scan(&a.ID, &a.UserID, &a.SecretID, &a.BlobKey, &a.Name, &a.ContentType, &a.Size, &a.KeyCipher, &a.KeyIV, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateWithinQuota comprueba la cuota y guarda en la misma sentencia: dos subidas a la vez no pueden
+// pasarse de la cuota entre las dos.
+func (r *AttachmentSQLite) CreateWithinQuota(a *domain.Attachment, quota int64) (int64, bool, error) {
+	res, err := r.db.Exec(`INSERT INTO attachments(user_id, secret_id, blob_key, name, content_type, size, key_cipher, key_iv)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE (SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = ?) + ? <= ?`,
+		a.UserID, a.SecretID, a.BlobKey, a.Name, a.ContentType, a.Size, a.KeyCipher, a.KeyIV,
+		a.UserID, a.Size, quota)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, false, nil
+	}
+	id, err := res.LastInsertId()
+	return id, err == nil, err
+}
+
+func (r *AttachmentSQLite) GetByID(userID, secretID, id int64) (*domain.Attachment, error) {
+	a, err := scanAttachment(r.db.QueryRow(`SELECT `+attachmentColumns+` FROM attachments WHERE id = ? AND user_id = ? AND secret_id = ?`,
+		id, userID, secretID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return a, err
+}
+
+func (r *AttachmentSQLite) ListBySecret(userID, secretID int64) ([]domain.Attachment, error) {
+	rows, err := r.db.Query(`SELECT `+attachmentColumns+` FROM attachments WHERE user_id = ? AND secret_id = ? ORDER BY id`, userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func (r *AttachmentSQLite) UsedBytes(userID int64) (int64, error) {
+	var n int64
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = ?`, userID).Scan(&n)
+	return n, err
+}
+
+func (r *AttachmentSQLite) Delete(userID, id int64) error {
+	_, err := r.db.Exec(`DELETE FROM attachments WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+func (r *AttachmentSQLite) DeleteBySecret(userID, secretID int64) error {
+	_, err := r.db.Exec(`DELETE FROM attachments WHERE user_id = ? AND secret_id = ?`, userID, secretID)
+	return err
+}
@@ -0,0 +1,76 @@
+// Adaptador SQLite de AttemptRepo: contadores de fallos con bloqueo temporal.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+// Las fechas se guardan en UTC para que las comparaciones de texto de SQLite sigan el orden temporal.
+type AttemptSQLite struct{ db *sql.DB }
+
+func NewAttemptSQLite(db *sql.DB) repository.AttemptRepo { return &AttemptSQLite{db: db} }
+
+func (r *AttemptSQLite) Get(key string) (*domain.Attempts, error) {
+	a, err := scanAttempts(r.db.QueryRow(`SELECT key, failures, last_failure, locked_until FROM login_attempts WHERE key = ?`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return a, err
+}
+
+func (r *AttemptSQLite) Fail(key string, now time.Time, window time.Duration) (int, error) {
+	var n int
+	err := r.db.QueryRow(`INSERT INTO login_attempts(key, failures, last_failure) VALUES(?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET
+		    failures = CASE WHEN login_attempts.last_failure < ? THEN 1 ELSE login_attempts.failures + 1 END,
+		    last_failure = excluded.last_failure
+		RETURNING failures`, key, now.UTC(), now.Add(-window).UTC()).Scan(&n)
+	return n, err
+}
+
+func (r *AttemptSQLite) Lock(key string, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE login_attempts SET locked_until = ? WHERE key = ?`, until.UTC(), key)
+	return err
+}
+
+func (r *AttemptSQLite) Reset(key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_attempts WHERE key = ?`, key)
+	return err
+}
+
+func (r *AttemptSQLite) ListLocked(now time.Time) ([]domain.Attempts, error) {
+	rows, err := r.db.Query(`SELECT key, failures, last_failure, locked_until FROM login_attempts
+		WHERE locked_until > ? ORDER BY locked_until DESC`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.Attempts
+	for rows.Next() {
+		a, err := scanAttempts(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func scanAttempts(row interface{ Scan(...any) error }) (*domain.Attempts, error) {
+	var (
+		a      domain.Attempts
+		locked sql.NullTime
+	)
+	if err := row.Scan(&a.Key, &a.Failures, &a.LastFailure, &locked); err != nil {
+		return nil, err
+	}
+	if locked.Valid {
+		a.LockedUntil = &locked.Time
+	}
+	return &a, nil
+}
@@ -0,0 +1,95 @@
+// Adaptador SQLite de AuditRepo: eventos de auditoría encadenados por hashes, solo de inserción.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type AuditSQLite struct {
+	db *sql.DB
+	mu sync.Mutex // leer el último hash e insertar debe ser atómico dentro del proceso
+}
+
+func NewAuditSQLite(db *sql.DB) repository.AuditRepo { return &AuditSQLite{db: db} }
+
+func (r *AuditSQLite) Append(e *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prev := domain.AuditGenesis
+	err = tx.QueryRow(`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	e.Seal(prev)
+	// prev_hash es UNIQUE: si otro proceso se adelantó, el insert falla en vez de bifurcar la cadena.
+	res, err := tx.Exec(`INSERT INTO audit_events(user_id, actor, ip, user_agent, action, target_type, target_id, result, detail,
+		created_at, prev_hash, hash) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.UserID, e.Actor, e.IP, e.UserAgent, e.Action, e.TargetType, e.TargetID, e.Result, e.Detail,
+		e.CreatedAt.Format(domain.AuditTime), e.PrevHash, e.Hash)
+	if err != nil {
+		return err
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const auditColumns = `id, user_id, actor, ip, user_agent, action, target_type, target_id, result, detail, created_at, prev_hash, hash`
+
+func (r *AuditSQLite) ListByUser(userID int64, limit, offset int) ([]domain.AuditEvent, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_events WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(`SELECT `+auditColumns+` FROM audit_events WHERE user_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`,
+		userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := scanAuditEvents(rows)
+	return items, total, err
+}
+
+func (r *AuditSQLite) Chain(afterID int64, limit int) ([]domain.AuditEvent, error) {
+	rows, err := r.db.Query(`SELECT `+auditColumns+` FROM audit_events WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func scanAuditEvents(rows *sql.Rows) ([]domain.AuditEvent, error) {
+	defer rows.Close()
+	var out []domain.AuditEvent
+	for rows.Next() {
+		var (
+			e       domain.AuditEvent
+			created string
+		)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Actor, &e.IP, &e.UserAgent, &e.Action, &e.TargetType, &e.TargetID,
+			&e.Result, &e.Detail, &created, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(domain.AuditTime, created)
+		if err != nil {
+			return nil, err
+		}
+		e.CreatedAt = t
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,48 @@
+// Adaptador SQLite de KeyRepo: claves de datos por usuario envueltas con la clave maestra y claves del servidor.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type KeySQLite struct{ db *sql.DB }
+
+func NewKeySQLite(db *sql.DB) repository.KeyRepo { return &KeySQLite{db: db} }
+
+func (r *KeySQLite) GetUserKey(userID int64) (*domain.UserKey, error) {
+	row := r.db.QueryRow(`SELECT user_id, wrapped_key, wrapped_iv, key_version, created_at FROM user_keys WHERE user_id = ?`, userID)
+	var k domain.UserKey
+	if err := row.Scan(&k.UserID, &k.WrappedKey, &k.WrappedIV, &k.KeyVersion, &k.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *KeySQLite) CreateUserKey(k *domain.UserKey) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO user_keys(user_id, wrapped_key, wrapped_iv, key_version) VALUES(?, ?, ?, ?)`,
+		k.UserID, k.WrappedKey, k.WrappedIV, k.KeyVersion)
+	return err
+}
+
+func (r *KeySQLite) GetServerKey(name string) ([]byte, error) {
+	var key []byte
+	if err := r.db.QueryRow(`SELECT value FROM server_keys WHERE name = ?`, name).Scan(&key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *KeySQLite) CreateServerKey(name string, key []byte) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO server_keys(name, value) VALUES(?, ?)`, name, key)
+	return err
+}
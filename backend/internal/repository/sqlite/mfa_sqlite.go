@@ -0,0 +1,168 @@
+// Adaptador SQLite de MFARepo: TOTP con protección de reutilización, códigos de recuperación y retos de login.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type MFASQLite struct{ db *sql.DB }
+
+func NewMFASQLite(db *sql.DB) repository.MFARepo { return &MFASQLite{db: db} }
+
+func (r *MFASQLite) GetTOTP(userID int64) (*domain.TOTP, error) {
+	var (
+		t       domain.TOTP
+		enabled sql.NullTime
+	)
+	err := r.db.QueryRow(`SELECT user_id, secret_cipher, secret_iv, key_version, last_step, enabled_at, created_at
+		FROM user_totp WHERE user_id = ?`, userID).
+		Scan(&t.UserID, &t.SecretCipher, &t.SecretIV, &t.KeyVersion, &t.LastStep, &enabled, &t.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if enabled.Valid {
+		t.EnabledAt = &enabled.Time
+	}
+	return &t, nil
+}
+
+func (r *MFASQLite) SaveTOTP(t *domain.TOTP) (bool, error) {
+	res, err := r.db.Exec(`INSERT INTO user_totp(user_id, secret_cipher, secret_iv, key_version) VALUES(?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET secret_cipher = excluded.secret_cipher, secret_iv = excluded.secret_iv,
+		    key_version = excluded.key_version, last_step = 0, created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled_at IS NULL`,
+		t.UserID, t.SecretCipher, t.SecretIV, t.KeyVersion)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *MFASQLite) EnableTOTP(userID, step int64, recoveryHashes []string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_totp SET enabled_at = ?, last_step = ? WHERE user_id = ? AND enabled_at IS NULL`,
+		time.Now(), step, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *MFASQLite) DeleteTOTP(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MFASQLite) UseStep(userID, step int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *MFASQLite) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *MFASQLite) CountRecoveryCodes(userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+func (r *MFASQLite) CreateChallenge(c *domain.MFAChallenge) error {
+	res, err := r.db.Exec(`INSERT INTO mfa_challenges(user_id, token_hash, expires_at) VALUES(?, ?, ?)`,
+		c.UserID, c.TokenHash, c.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	c.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *MFASQLite) GetChallenge(tokenHash string) (*domain.MFAChallenge, error) {
+	var (
+		c    domain.MFAChallenge
+		used sql.NullTime
+	)
+	err := r.db.QueryRow(`SELECT id, user_id, token_hash, expires_at, attempts, used_at FROM mfa_challenges WHERE token_hash = ?`, tokenHash).
+		Scan(&c.ID, &c.UserID, &c.TokenHash, &c.ExpiresAt, &c.Attempts, &used)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if used.Valid {
+		c.UsedAt = &used.Time
+	}
+	return &c, nil
+}
+
+func (r *MFASQLite) FailChallenge(id int64, maxAttempts int) error {
+	_, err := r.db.Exec(`UPDATE mfa_challenges
+		SET attempts = attempts + 1, used_at = CASE WHEN attempts + 1 >= ? THEN ? ELSE used_at END
+		WHERE id = ? AND used_at IS NULL`, maxAttempts, time.Now(), id)
+	return err
+}
+
+func (r *MFASQLite) ConsumeChallenge(id int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE mfa_challenges SET used_at = ? WHERE id = ? AND used_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// replaceRecoveryCodes sustituye los códigos de recuperación del usuario dentro de la transacción.
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, hashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tx.Exec(`INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES(?, ?)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
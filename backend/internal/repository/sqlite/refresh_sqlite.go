@@ -0,0 +1,86 @@
+// Adaptador SQLite de RefreshTokenRepo: tokens hasheados con rotación de un solo uso.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type RefreshTokenSQLite struct{ db *sql.DB }
+
+func NewRefreshTokenSQLite(db *sql.DB) repository.RefreshTokenRepo {
+	return &RefreshTokenSQLite{db: db}
+}
+
+func (r *RefreshTokenSQLite) Create(t *domain.RefreshToken) error {
+	res, err := r.db.Exec(`INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES(?, ?, ?, ?)`,
+		t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	t.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *RefreshTokenSQLite) GetByHash(tokenHash string) (*domain.RefreshToken, error) {
+	row := r.db.QueryRow(`SELECT id, user_id, family_id, token_hash, expires_at, created_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = ?`, tokenHash)
+	var (
+		t             domain.RefreshToken
+		used, revoked sql.NullTime
+	)
+	if err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &used, &revoked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if used.Valid {
+		t.UsedAt = &used.Time
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return &t, nil
+}
+
+func (r *RefreshTokenSQLite) Rotate(oldID int64, next *domain.RefreshToken) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`,
+		time.Now(), oldID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	res, err = tx.Exec(`INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES(?, ?, ?, ?)`,
+		next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	if next.ID, err = res.LastInsertId(); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *RefreshTokenSQLite) RevokeFamily(familyID string) error {
+	_, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`, time.Now(), familyID)
+	return err
+}
+
+func (r *RefreshTokenSQLite) RevokeUser(userID int64, exceptFamily string) error {
+	_, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND family_id <> ? AND revoked_at IS NULL`,
+		time.Now(), userID, exceptFamily)
+	return err
+}
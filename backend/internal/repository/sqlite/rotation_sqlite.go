@@ -0,0 +1,137 @@
+// Adaptador SQLite de RotationRepo: estado de la rotación y lotes de re-cifrado con escrituras optimistas.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type RotationSQLite struct{ db *sql.DB }
+
+func NewRotationSQLite(db *sql.DB) repository.RotationRepo { return &RotationSQLite{db: db} }
+
+func (r *RotationSQLite) Latest() (*domain.KeyRotation, error) {
+	row := r.db.QueryRow(`SELECT id, target_version, phase, last_id, processed, status, error, started_at, updated_at, finished_at
+	                      FROM key_rotations ORDER BY id DESC LIMIT 1`)
+	var j domain.KeyRotation
+	var finished sql.NullTime
+	if err := row.Scan(&j.ID, &j.TargetVersion, &j.Phase, &j.LastID, &j.Processed, &j.Status, &j.Error, &j.StartedAt, &j.UpdatedAt, &finished); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if finished.Valid {
+		j.FinishedAt = finished.Time
+	}
+	return &j, nil
+}
+
+func (r *RotationSQLite) Start(targetVersion int) (*domain.KeyRotation, error) {
+	if _, err := r.db.Exec(`INSERT INTO key_rotations(target_version, phase, status) VALUES(?, ?, ?)`,
+		targetVersion, domain.RotationPhaseUserKeys, domain.RotationRunning); err != nil {
+		return nil, err
+	}
+	return r.Latest()
+}
+
+func (r *RotationSQLite) SaveProgress(j *domain.KeyRotation) error {
+	var finished any
+	if !j.FinishedAt.IsZero() {
+		finished = j.FinishedAt
+	}
+	_, err := r.db.Exec(`UPDATE key_rotations
+	                     SET target_version=?, phase=?, last_id=?, processed=?, status=?, error=?, finished_at=?, updated_at=CURRENT_TIMESTAMP
+	                     WHERE id=?`,
+		j.TargetVersion, j.Phase, j.LastID, j.Processed, j.Status, j.Error, finished, j.ID)
+	return err
+}
+
+func (r *RotationSQLite) UserKeysBatch(afterUserID int64, targetVersion, limit int) ([]domain.UserKey, error) {
+	rows, err := r.db.Query(`SELECT user_id, wrapped_key, wrapped_iv, key_version, created_at FROM user_keys
+	                         WHERE user_id > ? AND key_version <> ? ORDER BY user_id LIMIT ?`, afterUserID, targetVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.UserKey
+	for rows.Next() {
+		var k domain.UserKey
+		if err := rows.Scan(&k.UserID, &k.WrappedKey, &k.WrappedIV, &k.KeyVersion, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (r *RotationSQLite) LegacySecretsBatch(afterID int64, targetVersion, limit int) ([]domain.Secret, error) {
+	rows, err := r.db.Query(`SELECT `+secretColumns+` FROM secrets
+	                         WHERE id > ? AND enc_scheme = ? AND key_version <> ? ORDER BY id LIMIT ?`,
+		afterID, domain.SchemeMasterKey, targetVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.Secret
+	for rows.Next() {
+		s, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func (r *RotationSQLite) TOTPBatch(afterUserID int64, targetVersion, limit int) ([]domain.TOTP, error) {
+	rows, err := r.db.Query(`SELECT user_id, secret_cipher, secret_iv, key_version FROM user_totp
+	                         WHERE user_id > ? AND key_version <> ? ORDER BY user_id LIMIT ?`, afterUserID, targetVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.TOTP
+	for rows.Next() {
+		var t domain.TOTP
+		if err := rows.Scan(&t.UserID, &t.SecretCipher, &t.SecretIV, &t.KeyVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *RotationSQLite) RewrapUserKey(k *domain.UserKey, prevWrapped string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_keys SET wrapped_key=?, wrapped_iv=?, key_version=? WHERE user_id=? AND wrapped_key=?`,
+		k.WrappedKey, k.WrappedIV, k.KeyVersion, k.UserID, prevWrapped)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *RotationSQLite) ReencryptSecret(s *domain.Secret, prevCipher string) (bool, error) {
+	// No toca updated_at: la rotación no es un cambio visible para el usuario.
+	res, err := r.db.Exec(`UPDATE secrets SET password_cipher=?, password_iv=?, key_version=? WHERE id=? AND password_cipher=?`,
+		s.PasswordCipher, s.PasswordIV, s.KeyVersion, s.ID, prevCipher)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *RotationSQLite) ReencryptTOTP(t *domain.TOTP, prevCipher string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_totp SET secret_cipher=?, secret_iv=?, key_version=? WHERE user_id=? AND secret_cipher=?`,
+		t.SecretCipher, t.SecretIV, t.KeyVersion, t.UserID, prevCipher)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -1,10 +1,12 @@
-// Adaptador SQLite de SecretRepo: CRUD y listado con búsqueda/filtro de dominio.
+// Adaptador SQLite de SecretRepo: CRUD y listado con búsqueda/filtro de dominio (en claro o por índices ciegos).
 package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"password-danie/internal/domain"
 	"password-danie/internal/repository"
@@ -14,42 +16,131 @@ type SecretSQLite struct{ db *sql.DB }
 
 func NewSecretSQLite(db *sql.DB) repository.SecretRepo { return &SecretSQLite{db: db} }
 
+const secretColumns = `id, user_id, username, password_cipher, password_iv, enc_scheme, key_version, blob, url, url_domain, domain_bidx, notes, icon, title, breached, otp, item_type, payload, custom_fields, deleted_at, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSecret(row rowScanner) (*domain.Secret, error) {
+	var (
+		s       domain.Secret
+		deleted sql.NullTime
+	)
+	if err := row.Scan(&s.ID, &s.UserID, &s.Username, &s.PasswordCipher, &s.PasswordIV, &s.EncScheme, &s.KeyVersion, &s.Blob, &s.URL, &s.URLDomain, &s.DomainIndex, &s.Notes, &s.Icon, &s.Title, &s.Breached, &s.OTP, &s.Type, &s.Payload, &s.CustomPayload, &deleted, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if deleted.Valid {
+		s.DeletedAt = &deleted.Time
+	}
+	return &s, nil
+}
+
+// itemType: las entradas zero-knowledge y las creadas sin tipo son logins.
+func itemType(s *domain.Secret) string {
+	if s.Type == "" {
+		return domain.ItemLogin
+	}
+	return s.Type
+}
+
 func (r *SecretSQLite) Create(s *domain.Secret) (int64, error) {
-	res, err := r.db.Exec(`INSERT INTO secrets(user_id, username, password_cipher, password_iv, url, url_domain, notes, icon, title)
-                           VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		s.UserID, s.Username, s.PasswordCipher, s.PasswordIV, s.URL, s.URLDomain, s.Notes, s.Icon, s.Title)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO secrets(user_id, username, password_cipher, password_iv, enc_scheme, key_version, blob, url, url_domain, domain_bidx, notes, icon, title, breached, otp, item_type, payload, custom_fields)
+                         VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.UserID, s.Username, s.PasswordCipher, s.PasswordIV, s.EncScheme, s.KeyVersion, s.Blob, s.URL, s.URLDomain, s.DomainIndex, s.Notes, s.Icon, s.Title, s.Breached, s.OTP, itemType(s), s.Payload, s.CustomPayload)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := replaceSearchTokens(tx, id, s.SearchTokens); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *SecretSQLite) CreateSealed(userID int64, build func(id int64) (*domain.Secret, error)) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO secrets(user_id, username, password_cipher, password_iv) VALUES(?, '', '', '')`, userID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	s, err := build(id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE secrets
+	                      SET username=?, password_cipher=?, password_iv=?, enc_scheme=?, key_version=?, blob=?, url=?, url_domain=?, domain_bidx=?, notes=?, icon=?, title=?, breached=?, otp=?, item_type=?, payload=?, custom_fields=?
+	                      WHERE id=?`,
+		s.Username, s.PasswordCipher, s.PasswordIV, s.EncScheme, s.KeyVersion, s.Blob, s.URL, s.URLDomain, s.DomainIndex, s.Notes, s.Icon, s.Title, s.Breached, s.OTP, itemType(s), s.Payload, s.CustomPayload, id); err != nil {
+		return 0, err
+	}
+	if err := replaceSearchTokens(tx, id, s.SearchTokens); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
 func (r *SecretSQLite) GetByID(userID, id int64) (*domain.Secret, error) {
-	row := r.db.QueryRow(`SELECT id, user_id, username, password_cipher, password_iv, url, url_domain, notes, icon, title, created_at, updated_at
-	                      FROM secrets WHERE id = ? AND user_id = ?`, id, userID)
-	var s domain.Secret
-	if err := row.Scan(&s.ID, &s.UserID, &s.Username, &s.PasswordCipher, &s.PasswordIV, &s.URL, &s.URLDomain, &s.Notes, &s.Icon, &s.Title, &s.CreatedAt, &s.UpdatedAt); err != nil {
+	row := r.db.QueryRow(`SELECT `+secretColumns+` FROM secrets WHERE id = ? AND user_id = ? AND deleted_at IS NULL`, id, userID)
+	s, err := scanSecret(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &s, nil
+	return s, nil
 }
 
 func (r *SecretSQLite) List(userID int64, f repository.ListFilter) ([]domain.Secret, int, error) {
-	where := []string{"user_id = ?"}
+	where := []string{"user_id = ?", "deleted_at IS NULL"}
 	args := []any{userID}
 
+	// Las filas antiguas (campos en claro) se buscan con LIKE; las selladas, por tokens ciegos:
+	// deben aparecer todos los tokens de la consulta.
+	plainRows := fmt.Sprintf("enc_scheme IN (%d, %d)", domain.SchemeMasterKey, domain.SchemeDataKey)
+	sealedRows := fmt.Sprintf("enc_scheme IN (%d, %d)", domain.SchemeSealed, domain.SchemeBound)
+
 	q := strings.TrimSpace(f.Q)
 	if q != "" {
-		where = append(where, "(username LIKE ? OR url LIKE ? OR notes LIKE ? OR title LIKE ?)")
+		cond := "(" + plainRows + " AND (username LIKE ? OR url LIKE ? OR notes LIKE ? OR title LIKE ?))"
 		like := "%" + q + "%"
 		args = append(args, like, like, like, like)
+		if len(f.QueryTokens) > 0 {
+			cond += " OR (" + sealedRows + " AND id IN (SELECT secret_id FROM secret_search WHERE token IN (" +
+				placeholders(len(f.QueryTokens)) + ") GROUP BY secret_id HAVING COUNT(DISTINCT token) = ?))"
+			for _, t := range f.QueryTokens {
+				args = append(args, t)
+			}
+			args = append(args, len(f.QueryTokens))
+		}
+		where = append(where, "("+cond+")")
 	}
 	if d := strings.TrimSpace(f.Domain); d != "" {
-		where = append(where, "url_domain = ?")
-		args = append(args, d)
+		where = append(where, "(("+plainRows+" AND url_domain = ?) OR ("+sealedRows+" AND domain_bidx = ?))")
+		args = append(args, d, f.DomainIndex)
+	}
+	if f.Type != "" {
+		where = append(where, "item_type = ?")
+		args = append(args, f.Type)
 	}
 	if f.Limit <= 0 {
 		f.Limit = 20
@@ -64,8 +155,7 @@ func (r *SecretSQLite) List(userID int64, f repository.ListFilter) ([]domain.Sec
 		return nil, 0, err
 	}
 
-	rows, err := r.db.Query(fmt.Sprintf(`SELECT id, user_id, username, password_cipher, password_iv, url, url_domain, notes, icon, title, created_at, updated_at
-	                                    FROM secrets %s ORDER BY id DESC LIMIT ? OFFSET ?`, w),
+	rows, err := r.db.Query(fmt.Sprintf(`SELECT %s FROM secrets %s ORDER BY id DESC LIMIT ? OFFSET ?`, secretColumns, w),
 		append(args, f.Limit, f.Offset)...)
 	if err != nil {
 		return nil, 0, err
@@ -74,24 +164,299 @@ func (r *SecretSQLite) List(userID int64, f repository.ListFilter) ([]domain.Sec
 
 	var out []domain.Secret
 	for rows.Next() {
-		var s domain.Secret
-		if err := rows.Scan(&s.ID, &s.UserID, &s.Username, &s.PasswordCipher, &s.PasswordIV, &s.URL, &s.URLDomain, &s.Notes, &s.Icon, &s.Title, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		s, err := scanSecret(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		out = append(out, s)
+		out = append(out, *s)
 	}
 	return out, total, rows.Err()
 }
 
 func (r *SecretSQLite) Update(s *domain.Secret) error {
-	_, err := r.db.Exec(`UPDATE secrets
-	                     SET username=?, password_cipher=?, password_iv=?, url=?, url_domain=?, notes=?, icon=?, title=?, updated_at=CURRENT_TIMESTAMP
-	                     WHERE id=? AND user_id=?`,
-		s.Username, s.PasswordCipher, s.PasswordIV, s.URL, s.URLDomain, s.Notes, s.Icon, s.Title, s.ID, s.UserID)
-	return err
+	return r.UpdateWithRevision(s, nil)
+}
+
+func (r *SecretSQLite) UpdateWithRevision(s *domain.Secret, rev *domain.SecretRevision) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if rev != nil {
+		if _, err := tx.Exec(`INSERT INTO secret_history(user_id, secret_id, payload) VALUES(?, ?, ?)`,
+			rev.UserID, rev.SecretID, rev.Payload); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE secrets
+	                      SET username=?, password_cipher=?, password_iv=?, enc_scheme=?, key_version=?, blob=?, url=?, url_domain=?, domain_bidx=?, notes=?, icon=?, title=?, breached=?, otp=?, payload=?, custom_fields=?, updated_at=CURRENT_TIMESTAMP
+	                      WHERE id=? AND user_id=?`,
+		s.Username, s.PasswordCipher, s.PasswordIV, s.EncScheme, s.KeyVersion, s.Blob, s.URL, s.URLDomain, s.DomainIndex, s.Notes, s.Icon, s.Title, s.Breached, s.OTP, s.Payload, s.CustomPayload, s.ID, s.UserID); err != nil {
+		return err
+	}
+	if err := replaceSearchTokens(tx, s.ID, s.SearchTokens); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SecretSQLite) SwapOTP(userID, id int64, prevOTP, otp string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE secrets SET otp=?, updated_at=CURRENT_TIMESTAMP
+	                       WHERE id=? AND user_id=? AND otp=? AND deleted_at IS NULL`, otp, id, userID, prevOTP)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
 func (r *SecretSQLite) Delete(userID, id int64) error {
-	_, err := r.db.Exec(`DELETE FROM secrets WHERE id=? AND user_id=?`, id, userID)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM secrets WHERE id=? AND user_id=?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := tx.Exec(`DELETE FROM secret_search WHERE secret_id=?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM secret_history WHERE secret_id=?`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteAll borra todas las entradas de un usuario y devuelve cuántas había.
+func (r *SecretSQLite) DeleteAll(userID int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM secret_search WHERE secret_id IN (SELECT id FROM secrets WHERE user_id=?)`, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM secret_history WHERE user_id=?`, userID); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM secrets WHERE user_id=?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// --- papelera ---
+
+func (r *SecretSQLite) Trash(userID, id int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE secrets SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ? AND deleted_at IS NULL`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *SecretSQLite) Untrash(userID, id int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE secrets SET deleted_at = NULL WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *SecretSQLite) GetTrashed(userID, id int64) (*domain.Secret, error) {
+	s, err := scanSecret(r.db.QueryRow(`SELECT `+secretColumns+` FROM secrets WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ListTrash devuelve la papelera del usuario, lo último borrado primero.
+func (r *SecretSQLite) ListTrash(userID int64, limit, offset int) ([]domain.Secret, int, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM secrets WHERE user_id = ? AND deleted_at IS NOT NULL`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	out, err := r.querySecrets(`SELECT `+secretColumns+` FROM secrets WHERE user_id = ? AND deleted_at IS NOT NULL
+	                            ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?`, userID, limit, offset)
+	return out, total, err
+}
+
+func (r *SecretSQLite) ListExpiredTrash(before time.Time, limit int) ([]domain.Secret, error) {
+	return r.querySecrets(`SELECT `+secretColumns+` FROM secrets WHERE deleted_at IS NOT NULL AND deleted_at < ?
+	                       ORDER BY deleted_at, id LIMIT ?`, before.UTC(), limit)
+}
+
+func (r *SecretSQLite) querySecrets(query string, args ...any) ([]domain.Secret, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.Secret
+	for rows.Next() {
+		s, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// --- migración de esquema de cifrado ---
+
+func (r *SecretSQLite) ListByScheme(schemes []int, afterID int64, limit int) ([]domain.Secret, error) {
+	if len(schemes) == 0 {
+		return nil, nil
+	}
+	args := []any{afterID}
+	for _, s := range schemes {
+		args = append(args, s)
+	}
+	args = append(args, limit)
+	rows, err := r.db.Query(`SELECT `+secretColumns+` FROM secrets
+	                         WHERE id > ? AND enc_scheme IN (`+placeholders(len(schemes))+`) ORDER BY id LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.Secret
+	for rows.Next() {
+		s, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// UpgradeScheme reescribe una fila ya migrada sin tocar updated_at. Si entretanto el usuario la
+// editó (y por tanto ya no está en prevScheme) no hace nada y devuelve false.
+func (r *SecretSQLite) UpgradeScheme(s *domain.Secret, prevScheme int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE secrets
+	                     SET username=?, password_cipher=?, password_iv=?, enc_scheme=?, key_version=?, url=?, url_domain=?, domain_bidx=?, notes=?, icon=?, title=?, otp=?, payload=?, custom_fields=?
+	                     WHERE id=? AND user_id=? AND enc_scheme=?`,
+		s.Username, s.PasswordCipher, s.PasswordIV, s.EncScheme, s.KeyVersion, s.URL, s.URLDomain, s.DomainIndex, s.Notes, s.Icon, s.Title, s.OTP, s.Payload, s.CustomPayload, s.ID, s.UserID, prevScheme)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := replaceSearchTokens(tx, s.ID, s.SearchTokens); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// --- auditoría de revelados ---
+
+func (r *SecretSQLite) LogReveal(userID, secretID int64, ip, userAgent string) error {
+	_, err := r.db.Exec(`INSERT INTO secret_reveals(user_id, secret_id, ip, user_agent) VALUES(?, ?, ?, ?)`,
+		userID, secretID, ip, userAgent)
 	return err
 }
+
+// --- historial de versiones ---
+
+const revisionColumns = `id, user_id, secret_id, payload, created_at`
+
+func scanRevision(row rowScanner) (*domain.SecretRevision, error) {
+	var rev domain.SecretRevision
+	if err := row.Scan(&rev.ID, &rev.UserID, &rev.SecretID, &rev.Payload, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// ListRevisions devuelve las versiones de la entrada de la más reciente a la más antigua.
+func (r *SecretSQLite) ListRevisions(userID, secretID int64) ([]domain.SecretRevision, error) {
+	rows, err := r.db.Query(`SELECT `+revisionColumns+` FROM secret_history WHERE user_id = ? AND secret_id = ? ORDER BY id DESC`,
+		userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.SecretRevision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rev)
+	}
+	return out, rows.Err()
+}
+
+func (r *SecretSQLite) GetRevision(userID, secretID, id int64) (*domain.SecretRevision, error) {
+	rev, err := scanRevision(r.db.QueryRow(`SELECT `+revisionColumns+` FROM secret_history WHERE id = ? AND user_id = ? AND secret_id = ?`,
+		id, userID, secretID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return rev, err
+}
+
+func (r *SecretSQLite) PruneRevisions(userID, secretID int64, keep int, before time.Time) error {
+	if keep > 0 {
+		if _, err := r.db.Exec(`DELETE FROM secret_history WHERE user_id = ? AND secret_id = ? AND id NOT IN
+			(SELECT id FROM secret_history WHERE user_id = ? AND secret_id = ? ORDER BY id DESC LIMIT ?)`,
+			userID, secretID, userID, secretID, keep); err != nil {
+			return err
+		}
+	}
+	if !before.IsZero() {
+		if _, err := r.db.Exec(`DELETE FROM secret_history WHERE user_id = ? AND secret_id = ? AND created_at < ?`,
+			userID, secretID, before.UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceSearchTokens sustituye los tokens ciegos de una entrada dentro de la transacción.
+func replaceSearchTokens(tx *sql.Tx, secretID int64, tokens []string) error {
+	if _, err := tx.Exec(`DELETE FROM secret_search WHERE secret_id=?`, secretID); err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO secret_search(secret_id, token) VALUES(?, ?)`, secretID, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
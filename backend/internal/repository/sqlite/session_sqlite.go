@@ -0,0 +1,62 @@
+// Adaptador SQLite de SessionRepo: sesiones de login con revocación en servidor.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type SessionSQLite struct{ db *sql.DB }
+
+func NewSessionSQLite(db *sql.DB) repository.SessionRepo { return &SessionSQLite{db: db} }
+
+func (r *SessionSQLite) Create(s *domain.Session) error {
+	_, err := r.db.Exec(`INSERT INTO sessions(id, user_id) VALUES(?, ?)`, s.ID, s.UserID)
+	return err
+}
+
+func (r *SessionSQLite) GetByID(id string) (*domain.Session, error) {
+	var (
+		s       domain.Session
+		revoked sql.NullTime
+	)
+	err := r.db.QueryRow(`SELECT id, user_id, created_at, revoked_at FROM sessions WHERE id = ?`, id).
+		Scan(&s.ID, &s.UserID, &s.CreatedAt, &revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if revoked.Valid {
+		s.RevokedAt = &revoked.Time
+	}
+	return &s, nil
+}
+
+func (r *SessionSQLite) Revoke(id string) error {
+	_, err := r.db.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now(), id)
+	return err
+}
+
+func (r *SessionSQLite) RevokeUser(userID int64, exceptID string) ([]string, error) {
+	rows, err := r.db.Query(`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL RETURNING id`,
+		time.Now(), userID, exceptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -1,4 +1,4 @@
-// Adaptador SQLite de UserRepo: operaciones básicas + soporte de password reset (con valores, no punteros).
+// Adaptador SQLite de UserRepo: operaciones básicas + soporte de password reset (con valores, no punteros) y modo zero-knowledge.
 package sqlite
 
 import (
@@ -14,21 +14,16 @@ type UserSQLite struct{ db *sql.DB }
 
 func NewUserSQLite(db *sql.DB) repository.UserRepo { return &UserSQLite{db: db} }
 
-func (r *UserSQLite) Create(email, passwordHash string) (int64, error) {
-	res, err := r.db.Exec(`INSERT INTO users(email, password_hash) VALUES(?, ?)`, email, passwordHash)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
-}
+const userColumns = `id, email, password_hash, reset_token, reset_expires_at, vault_mode,
+	kdf_algorithm, kdf_iterations, kdf_memory, kdf_parallelism, kdf_salt, created_at, updated_at`
 
-func (r *UserSQLite) GetByID(id int64) (*domain.User, error) {
-	row := r.db.QueryRow(`SELECT id, email, password_hash, reset_token, reset_expires_at, created_at, updated_at FROM users WHERE id = ?`, id)
+func scanUser(row rowScanner) (*domain.User, error) {
 	var u domain.User
 	var resetToken sql.NullString
 	var resetExpires sql.NullTime
 
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &resetToken, &resetExpires, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &resetToken, &resetExpires, &u.VaultMode,
+		&u.KDF.Algorithm, &u.KDF.Iterations, &u.KDF.Memory, &u.KDF.Parallelism, &u.KDF.Salt, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -47,29 +42,40 @@ func (r *UserSQLite) GetByID(id int64) (*domain.User, error) {
 	return &u, nil
 }
 
+func (r *UserSQLite) Create(email, passwordHash string) (int64, error) {
+	res, err := r.db.Exec(`INSERT INTO users(email, password_hash) VALUES(?, ?)`, email, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *UserSQLite) GetByID(id int64) (*domain.User, error) {
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
 func (r *UserSQLite) GetByEmail(email string) (*domain.User, error) {
-	row := r.db.QueryRow(`SELECT id, email, password_hash, reset_token, reset_expires_at, created_at, updated_at FROM users WHERE email = ?`, email)
-	var u domain.User
-	var resetToken sql.NullString
-	var resetExpires sql.NullTime
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+}
 
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &resetToken, &resetExpires, &u.CreatedAt, &u.UpdatedAt); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	if resetToken.Valid {
-		u.ResetToken = resetToken.String
-	} else {
-		u.ResetToken = ""
-	}
-	if resetExpires.Valid {
-		u.ResetExpiresAt = resetExpires.Time
-	} else {
-		u.ResetExpiresAt = time.Time{}
+// --- zero-knowledge ---
+
+func (r *UserSQLite) CreateZeroKnowledge(email, passwordHash string, kdf domain.KDFParams) (int64, error) {
+	res, err := r.db.Exec(`INSERT INTO users(email, password_hash, vault_mode, kdf_algorithm, kdf_iterations, kdf_memory, kdf_parallelism, kdf_salt)
+	                       VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		email, passwordHash, domain.VaultModeZeroKnowledge, kdf.Algorithm, kdf.Iterations, kdf.Memory, kdf.Parallelism, kdf.Salt)
+	if err != nil {
+		return 0, err
 	}
-	return &u, nil
+	return res.LastInsertId()
+}
+
+func (r *UserSQLite) UpdateKDF(userID int64, kdf domain.KDFParams) error {
+	_, err := r.db.Exec(`UPDATE users
+		SET kdf_algorithm = ?, kdf_iterations = ?, kdf_memory = ?, kdf_parallelism = ?, kdf_salt = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		kdf.Algorithm, kdf.Iterations, kdf.Memory, kdf.Parallelism, kdf.Salt, userID)
+	return err
 }
 
 // --- password reset ---
@@ -81,28 +87,7 @@ func (r *UserSQLite) UpdateReset(userID int64, token *string, expiresAt *time.Ti
 }
 
 func (r *UserSQLite) GetByResetToken(token string) (*domain.User, error) {
-	row := r.db.QueryRow(`SELECT id, email, password_hash, reset_token, reset_expires_at, created_at, updated_at FROM users WHERE reset_token = ?`, token)
-	var u domain.User
-	var resetToken sql.NullString
-	var resetExpires sql.NullTime
-
-	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &resetToken, &resetExpires, &u.CreatedAt, &u.UpdatedAt); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	if resetToken.Valid {
-		u.ResetToken = resetToken.String
-	} else {
-		u.ResetToken = ""
-	}
-	if resetExpires.Valid {
-		u.ResetExpiresAt = resetExpires.Time
-	} else {
-		u.ResetExpiresAt = time.Time{}
-	}
-	return &u, nil
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE reset_token = ?`, token))
 }
 
 func (r *UserSQLite) UpdatePassword(userID int64, passwordHash string) error {
@@ -112,3 +97,9 @@ func (r *UserSQLite) UpdatePassword(userID int64, passwordHash string) error {
 		passwordHash, userID)
 	return err
 }
+
+func (r *UserSQLite) RehashPassword(userID int64, oldHash, newHash string) error {
+	_, err := r.db.Exec(`UPDATE users SET password_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND password_hash = ?`,
+		newHash, userID, oldHash)
+	return err
+}
@@ -0,0 +1,135 @@
+// Adaptador SQLite de WebAuthnRepo: user handles, credenciales con contador de firmas y sesiones de ceremonia.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+type WebAuthnSQLite struct{ db *sql.DB }
+
+func NewWebAuthnSQLite(db *sql.DB) repository.WebAuthnRepo { return &WebAuthnSQLite{db: db} }
+
+func (r *WebAuthnSQLite) Handle(userID int64) (string, error) {
+	var h string
+	err := r.db.QueryRow(`SELECT handle FROM webauthn_users WHERE user_id = ?`, userID).Scan(&h)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return h, err
+}
+
+func (r *WebAuthnSQLite) CreateHandle(userID int64, handle string) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO webauthn_users(user_id, handle) VALUES(?, ?)`, userID, handle)
+	return err
+}
+
+func (r *WebAuthnSQLite) UserByHandle(handle string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(`SELECT user_id FROM webauthn_users WHERE handle = ?`, handle).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+func (r *WebAuthnSQLite) ListCredentials(userID int64) ([]domain.WebAuthnCredential, error) {
+	rows, err := r.db.Query(`SELECT id, user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count,
+		backup_eligible, backup_state, name, created_at, last_used_at
+		FROM webauthn_credentials WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.WebAuthnCredential
+	for rows.Next() {
+		var (
+			c          domain.WebAuthnCredential
+			credID     string
+			transports string
+			lastUsed   sql.NullTime
+		)
+		if err := rows.Scan(&c.ID, &c.UserID, &credID, &c.PublicKey, &c.AttestationType, &transports, &c.AAGUID, &c.SignCount,
+			&c.BackupEligible, &c.BackupState, &c.Name, &c.CreatedAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		if c.CredentialID, err = base64.RawURLEncoding.DecodeString(credID); err != nil {
+			return nil, err
+		}
+		if transports != "" {
+			c.Transports = strings.Split(transports, ",")
+		}
+		if lastUsed.Valid {
+			c.LastUsedAt = &lastUsed.Time
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebAuthnSQLite) CountCredentials(userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = ?`, userID).Scan(&n)
+	return n, err
+}
+
+func (r *WebAuthnSQLite) CreateCredential(c *domain.WebAuthnCredential) error {
+	res, err := r.db.Exec(`INSERT INTO webauthn_credentials(user_id, credential_id, public_key, attestation_type, transports, aaguid,
+		sign_count, backup_eligible, backup_state, name) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.UserID, base64.RawURLEncoding.EncodeToString(c.CredentialID), c.PublicKey, c.AttestationType, strings.Join(c.Transports, ","),
+		c.AAGUID, c.SignCount, c.BackupEligible, c.BackupState, c.Name)
+	if err != nil {
+		return err
+	}
+	c.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *WebAuthnSQLite) UpdateSignCount(id int64, prev, next uint32, backupState bool) (bool, error) {
+	res, err := r.db.Exec(`UPDATE webauthn_credentials SET sign_count = ?, backup_state = ?, last_used_at = ? WHERE id = ? AND sign_count = ?`,
+		next, backupState, time.Now(), id, prev)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *WebAuthnSQLite) DeleteCredential(userID, id int64) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *WebAuthnSQLite) CreateSession(s *domain.WebAuthnSession) error {
+	res, err := r.db.Exec(`INSERT INTO webauthn_sessions(token_hash, user_id, purpose, data, expires_at) VALUES(?, ?, ?, ?, ?)`,
+		s.TokenHash, s.UserID, s.Purpose, s.Data, s.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *WebAuthnSQLite) ConsumeSession(tokenHash string) (*domain.WebAuthnSession, error) {
+	var s domain.WebAuthnSession
+	err := r.db.QueryRow(`UPDATE webauthn_sessions SET used_at = ? WHERE token_hash = ? AND used_at IS NULL
+		RETURNING id, token_hash, user_id, purpose, data, expires_at`, time.Now(), tokenHash).
+		Scan(&s.ID, &s.TokenHash, &s.UserID, &s.Purpose, &s.Data, &s.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
@@ -13,8 +13,15 @@ type UserRepo interface {
 	GetByID(id int64) (*domain.User, error)
 	GetByEmail(email string) (*domain.User, error)
 
+	// zero-knowledge
+	CreateZeroKnowledge(email, passwordHash string, kdf domain.KDFParams) (int64, error)
+	UpdateKDF(userID int64, kdf domain.KDFParams) error
+
 	// password reset
 	UpdateReset(userID int64, token *string, expiresAt *time.Time) error
 	GetByResetToken(token string) (*domain.User, error)
 	UpdatePassword(userID int64, passwordHash string) error
+
+	// RehashPassword cambia el hash solo si sigue siendo oldHash (no pisa un cambio de contraseña concurrente).
+	RehashPassword(userID int64, oldHash, newHash string) error
 }
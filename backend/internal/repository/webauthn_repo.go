@@ -0,0 +1,22 @@
+// Package repository declara puertos (interfaces) para las passkeys (WebAuthn) y sus ceremonias.
+package repository
+
+import "password-danie/internal/domain"
+
+type WebAuthnRepo interface {
+	// Handle devuelve el user handle del usuario ("" si aún no tiene); CreateHandle lo fija si no existía.
+	Handle(userID int64) (string, error)
+	CreateHandle(userID int64, handle string) error
+	UserByHandle(handle string) (int64, error)
+
+	ListCredentials(userID int64) ([]domain.WebAuthnCredential, error)
+	CountCredentials(userID int64) (int, error)
+	CreateCredential(c *domain.WebAuthnCredential) error
+	// UpdateSignCount guarda el nuevo contador solo si sigue valiendo prev (false = otra firma se adelantó).
+	UpdateSignCount(id int64, prev, next uint32, backupState bool) (bool, error)
+	DeleteCredential(userID, id int64) (bool, error)
+
+	CreateSession(s *domain.WebAuthnSession) error
+	// ConsumeSession marca la sesión como usada y la devuelve; nil si no existe o ya se usó.
+	ConsumeSession(tokenHash string) (*domain.WebAuthnSession, error)
+}
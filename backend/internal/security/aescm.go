@@ -1,66 +1,108 @@
+// Cifrado AES-256-GCM: claves maestras versionadas (keyring) y claves de datos por usuario envueltas con ellas.
 package security
 
 import (
-    "crypto/aes"
-    "crypto/cipher"
-    "crypto/rand"
-    "encoding/base64"
-    "errors"
-    "io"
-    "os"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
 )
 
-func getAESKey() ([]byte, error) {
-    k := os.Getenv("AES_KEY") 
-    if k == "" {
-        k = "0123456789abcdef0123456789abcdef"
-    }
-    if len(k) == 32 {
-        return []byte(k), nil
-    }
-    b, err := base64.StdEncoding.DecodeString(k)
-    if err != nil {
-        return nil, err
-    }
-    if len(b) != 32 {
-        return nil, errors.New("AES_KEY debe ser 32 bytes")
-    }
-    return b, nil
-}
+// DataKeySize es el tamaño de las claves de datos (AES-256).
+const DataKeySize = 32
 
-func Encrypt(plain []byte) (cipherText, iv string, err error) {
-    key, err := getAESKey()
-    if err != nil { return "", "", err }
+// Encrypt cifra con la clave maestra actual y devuelve su versión para guardarla junto al cifrado.
+func Encrypt(plain []byte) (cipherText, iv string, version int, err error) {
+	kr, err := masterKeyring()
+	if err != nil {
+		return "", "", 0, err
+	}
+	version, key := kr.Current()
+	cipherText, iv, err = EncryptWithKey(key, plain, nil)
+	return cipherText, iv, version, err
+}
 
-    block, err := aes.NewCipher(key)
-    if err != nil { return "", "", err }
+// Decrypt descifra con la clave maestra de la versión indicada (actual o retirada).
+func Decrypt(version int, cipherText, iv string) ([]byte, error) {
+	kr, err := masterKeyring()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kr.Key(version)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptWithKey(key, cipherText, iv, nil)
+}
 
-    aesgcm, err := cipher.NewGCM(block)
-    if err != nil { return "", "", err }
+// EncryptWithKey cifra con una clave concreta (p. ej. la clave de datos de un usuario). aad (datos
+// asociados, opcional) no se cifra pero queda autenticado: para descifrar hay que aportar el mismo.
+func EncryptWithKey(key, plain, aad []byte) (cipherText, iv string, err error) {
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return "", "", err
+	}
 
-    nonce := make([]byte, aesgcm.NonceSize())
-    if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-        return "", "", err
-    }
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", "", err
+	}
 
-    encrypted := aesgcm.Seal(nil, nonce, plain, nil)
-    return base64.StdEncoding.EncodeToString(encrypted), base64.StdEncoding.EncodeToString(nonce), nil
+	encrypted := aesgcm.Seal(nil, nonce, plain, aad)
+	return base64.StdEncoding.EncodeToString(encrypted), base64.StdEncoding.EncodeToString(nonce), nil
 }
 
-func Decrypt(cipherText, iv string) ([]byte, error) {
-    key, err := getAESKey()
-    if err != nil { return nil, err }
+// DecryptWithKey descifra con una clave concreta y los mismos datos asociados usados al cifrar.
+func DecryptWithKey(key []byte, cipherText, iv string, aad []byte) ([]byte, error) {
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
 
-    block, err := aes.NewCipher(key)
-    if err != nil { return nil, err }
+	ct, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, err
+	}
 
-    aesgcm, err := cipher.NewGCM(block)
-    if err != nil { return nil, err }
+	return aesgcm.Open(nil, nonce, ct, aad)
+}
 
-    ct, err := base64.StdEncoding.DecodeString(cipherText)
-    if err != nil { return nil, err }
-    nonce, err := base64.StdEncoding.DecodeString(iv)
-    if err != nil { return nil, err }
+// NewDataKey genera una clave de datos aleatoria.
+func NewDataKey() ([]byte, error) {
+	k := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// WrapKey envuelve una clave de datos con la clave maestra actual para poder persistirla.
+func WrapKey(dataKey []byte) (wrapped, iv string, version int, err error) {
+	return Encrypt(dataKey)
+}
+
+// UnwrapKey recupera una clave de datos envuelta con WrapKey bajo la versión indicada.
+func UnwrapKey(version int, wrapped, iv string) ([]byte, error) {
+	k, err := Decrypt(version, wrapped, iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != DataKeySize {
+		return nil, errors.New("invalid data key size")
+	}
+	return k, nil
+}
 
-    return aesgcm.Open(nil, nonce, ct, nil)
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
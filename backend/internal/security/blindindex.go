@@ -0,0 +1,68 @@
+// Cifrado de campos sueltos e índices ciegos (HMAC) para buscar sobre datos cifrados sin descifrarlos.
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// SealString cifra un campo y devuelve nonce||cifrado en un único base64, apto para una columna TEXT.
+// aad funciona como en EncryptWithKey.
+func SealString(key []byte, plain string, aad []byte) (string, error) {
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesgcm.NonceSize(), aesgcm.NonceSize()+len(plain)+aesgcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aesgcm.Seal(nonce, nonce, []byte(plain), aad)), nil
+}
+
+// OpenString descifra un campo sellado con SealString.
+func OpenString(key []byte, sealed string, aad []byte) (string, error) {
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aesgcm.NonceSize() {
+		return "", errors.New("sealed value too short")
+	}
+	plain, err := aesgcm.Open(nil, raw[:aesgcm.NonceSize()], raw[aesgcm.NonceSize():], aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// DeriveKey deriva una subclave independiente (HKDF-SHA256) para un propósito concreto,
+// de modo que la clave de índices ciegos no sirva para descifrar y viceversa.
+func DeriveKey(key []byte, purpose string) ([]byte, error) {
+	out := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(purpose)), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BlindIndex calcula un índice ciego determinista: el mismo valor da el mismo índice con la misma
+// clave, sin revelar el valor. kind separa dominios (p. ej. "domain" y "token") para que no colisionen.
+func BlindIndex(key []byte, kind, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
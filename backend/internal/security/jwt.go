@@ -1,47 +1,156 @@
-// Utilidades JWT (HS256): generar tokens de acceso y validar/extraer claims.
+// Utilidades JWT (EdDSA / ES256 con kid): generar tokens de acceso y validar sus claims de forma estricta.
 package security
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func jwtKey() []byte {
-	k := os.Getenv("JWT_SECRET")
-	if k == "" {
-		k = "dev-secret-change-me"
+// TokenPolicy fija emisor, audiencia y margen de reloj de los access tokens.
+type TokenPolicy struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+var DefaultTokenPolicy = TokenPolicy{Issuer: "password-danie", Audience: "password-danie-api", Leeway: 30 * time.Second}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultTokenPolicy
+)
+
+// SetTokenPolicy cambia la política; los campos vacíos conservan el valor por defecto.
+func SetTokenPolicy(p TokenPolicy) {
+	if p.Issuer == "" {
+		p.Issuer = DefaultTokenPolicy.Issuer
+	}
+	if p.Audience == "" {
+		p.Audience = DefaultTokenPolicy.Audience
+	}
+	if p.Leeway < 0 {
+		p.Leeway = 0
+	}
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+func currentPolicy() TokenPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// Claims de un access token. sub es el id del usuario como string para que no pase por float64 en JSON.
+type Claims struct {
+	jwt.RegisteredClaims
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+}
+
+// Validate exige los claims que siempre emitimos (jwt los llama tras validar exp/nbf/iat/iss/aud).
+func (c *Claims) Validate() error {
+	switch {
+	case c.Subject == "":
+		return errors.New("token has no subject")
+	case c.ID == "":
+		return errors.New("token has no jti")
+	case c.SessionID == "":
+		return errors.New("token has no session")
+	case c.IssuedAt == nil || c.NotBefore == nil:
+		return errors.New("token has no iat/nbf")
 	}
-	return []byte(k)
+	_, err := c.UserID()
+	return err
 }
 
-func GenerateAccessToken(sub int64, email string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":   sub,
-		"email": email,
-		"exp":   time.Now().Add(ttl).Unix(),
+// UserID convierte sub al id numérico del usuario.
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid subject")
+	}
+	return id, nil
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgES256 {
+		return jwt.SigningMethodES256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// GenerateAccessToken firma un access token con jti propio y el sid de la sesión a la que pertenece.
+func GenerateAccessToken(sub int64, email, sessionID string, ttl time.Duration) (string, error) {
+	set, err := signingKeys()
+	if err != nil {
+		return "", err
+	}
+	p := currentPolicy()
+	now := time.Now()
+	key := set.Active()
+	token := jwt.NewWithClaims(signingMethod(key.Alg), &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.Issuer,
+			Subject:   strconv.FormatInt(sub, 10),
+			Audience:  jwt.ClaimStrings{p.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        newTokenID(),
+		},
+		Email:     email,
+		SessionID: sessionID,
 	})
-	return token.SignedString(jwtKey())
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Signer)
 }
 
-func ParseToken(tokenStr string) (jwt.MapClaims, error) {
-	t, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ParseToken elige la clave de verificación por el kid de la cabecera (el alg debe ser el de esa clave)
+// y valida exp, nbf, iat, iss y aud con el margen de la política.
+func ParseToken(tokenStr string) (*Claims, error) {
+	set, err := signingKeys()
+	if err != nil {
+		return nil, err
+	}
+	p := currentPolicy()
+	claims := &Claims{}
+	t, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := set.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Alg {
 			return nil, errors.New("unexpected signing method")
 		}
-		return jwtKey(), nil
-	})
+		return key.Signer.Public(), nil
+	},
+		jwt.WithValidMethods([]string{AlgEdDSA, AlgES256}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.Audience),
+		jwt.WithLeeway(p.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil || !t.Valid {
 		if err == nil {
 			err = errors.New("invalid token")
 		}
 		return nil, err
 	}
-	claims, ok := t.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid claims")
-	}
 	return claims, nil
 }
@@ -0,0 +1,94 @@
+// Proveedores de claves maestras: de dónde salen la clave AES (keyring) y las claves de firma JWT.
+// El servidor carga las claves una vez con Init y se niega a arrancar si el proveedor no da claves válidas.
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyProvider obtiene el material de claves desde una fuente concreta.
+type KeyProvider interface {
+	// Name identifica el proveedor en logs y errores.
+	Name() string
+	// MasterKeys devuelve el keyring AES (clave actual + retiradas).
+	MasterKeys() (*Keyring, error)
+	// SigningKeys devuelve las claves privadas de firma JWT (la primera es la activa).
+	SigningKeys() (*SigningKeySet, error)
+}
+
+var (
+	keysMu  sync.RWMutex
+	keyring *Keyring
+	jwtKeys *SigningKeySet
+
+	errKeysNotLoaded = errors.New("keys not loaded: call security.Init with a KeyProvider")
+)
+
+// Init carga y valida las claves del proveedor; hasta entonces no se puede cifrar ni firmar.
+func Init(p KeyProvider) error {
+	kr, err := p.MasterKeys()
+	if err != nil {
+		return fmt.Errorf("%s: master keys: %w", p.Name(), err)
+	}
+	set, err := p.SigningKeys()
+	if err != nil {
+		return fmt.Errorf("%s: jwt signing keys: %w", p.Name(), err)
+	}
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keyring, jwtKeys = kr, set
+	return nil
+}
+
+func masterKeyring() (*Keyring, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if keyring == nil {
+		return nil, errKeysNotLoaded
+	}
+	return keyring, nil
+}
+
+func signingKeys() (*SigningKeySet, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if jwtKeys == nil {
+		return nil, errKeysNotLoaded
+	}
+	return jwtKeys, nil
+}
+
+// ProviderFromEnv elige el proveedor según KEY_PROVIDER: env (por defecto), file o transit.
+func ProviderFromEnv() (KeyProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("KEY_PROVIDER"))) {
+	case "", "env":
+		return EnvProvider{}, nil
+	case "file":
+		path := os.Getenv("KEY_FILE")
+		if path == "" {
+			return nil, errors.New("KEY_PROVIDER=file requires KEY_FILE")
+		}
+		return FileProvider{Path: path}, nil
+	case "transit":
+		return NewTransitProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown KEY_PROVIDER %q", os.Getenv("KEY_PROVIDER"))
+	}
+}
+
+// keyringFromMaterial construye un keyring a partir de claves en texto (32 caracteres o base64).
+func keyringFromMaterial(current int, material map[int]string) (*Keyring, error) {
+	keys := make(map[int][]byte, len(material))
+	for v, m := range material {
+		k, err := parseAESKey(m)
+		if err != nil {
+			return nil, fmt.Errorf("key v%d: %w", v, err)
+		}
+		keys[v] = k
+	}
+	return NewKeyring(current, keys)
+}
@@ -0,0 +1,60 @@
+// Proveedor de claves por variables de entorno (sin valores por defecto).
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvProvider lee:
+//
+//	AES_KEY            clave actual (32 caracteres o base64 de 32 bytes)
+//	AES_KEY_VERSION    versión de la clave actual (por defecto 1)
+//	AES_RETIRED_KEYS   claves retiradas "1:<clave>,2:<clave>" que solo se usan para descifrar
+//	JWT_SIGNING_KEYS   claves privadas PKCS#8 (base64 DER, Ed25519 o P-256) separadas por comas, la primera firma
+type EnvProvider struct{}
+
+func (EnvProvider) Name() string { return "env" }
+
+func (EnvProvider) MasterKeys() (*Keyring, error) {
+	cur := os.Getenv("AES_KEY")
+	if cur == "" {
+		return nil, errors.New("AES_KEY not set")
+	}
+	version, err := envKeyVersion()
+	if err != nil {
+		return nil, err
+	}
+	material, err := parseVersioned(os.Getenv("AES_RETIRED_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("AES_RETIRED_KEYS: %w", err)
+	}
+	if _, clash := material[version]; clash {
+		return nil, fmt.Errorf("AES_RETIRED_KEYS: version %d is the current one", version)
+	}
+	material[version] = cur
+	return keyringFromMaterial(version, material)
+}
+
+func (EnvProvider) SigningKeys() (*SigningKeySet, error) {
+	s := os.Getenv("JWT_SIGNING_KEYS")
+	if s == "" {
+		return nil, errors.New("JWT_SIGNING_KEYS not set")
+	}
+	return parseSigningKeySet(strings.Split(s, ","))
+}
+
+func envKeyVersion() (int, error) {
+	s := os.Getenv("AES_KEY_VERSION")
+	if s == "" {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("AES_KEY_VERSION: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,73 @@
+// Proveedor de claves desde un fichero JSON que solo puede leer su dueño.
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// FileProvider lee un fichero con este formato (permisos 0600 o 0400):
+//
+//	{"current_version": 2, "aes_keys": {"1": "<clave>", "2": "<clave>"}, "jwt_signing_keys": ["<PKCS#8 PEM o base64>"]}
+type FileProvider struct {
+	Path string
+}
+
+type keyFile struct {
+	CurrentVersion int               `json:"current_version"`
+	AESKeys        map[string]string `json:"aes_keys"`
+	JWTKeys        []string          `json:"jwt_signing_keys"`
+}
+
+func (p FileProvider) Name() string { return "file:" + p.Path }
+
+func (p FileProvider) MasterKeys() (*Keyring, error) {
+	f, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	material := make(map[int]string, len(f.AESKeys))
+	for vs, k := range f.AESKeys {
+		v, err := strconv.Atoi(vs)
+		if err != nil {
+			return nil, fmt.Errorf("aes_keys: bad version %q", vs)
+		}
+		material[v] = k
+	}
+	return keyringFromMaterial(f.CurrentVersion, material)
+}
+
+func (p FileProvider) SigningKeys() (*SigningKeySet, error) {
+	f, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	return parseSigningKeySet(f.JWTKeys)
+}
+
+func (p FileProvider) load() (*keyFile, error) {
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.New("key file is not a regular file")
+	}
+	// En Windows los bits de permisos no reflejan las ACL, así que no se comprueban.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("key file permissions %#o are too open (use 0600 or 0400)", info.Mode().Perm())
+	}
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse key file: %w", err)
+	}
+	return &f, nil
+}
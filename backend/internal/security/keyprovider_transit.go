@@ -0,0 +1,135 @@
+// Proveedor de claves vía un servicio de tránsito HTTP estilo KMS: las claves se guardan envueltas
+// y solo el servicio (que custodia la clave de envoltura) puede desenvolverlas al arrancar.
+package security
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TransitProvider desenvuelve el material con POST {Addr}/v1/transit/decrypt/{KeyName}
+// ({"ciphertext": "..."} -> {"data": {"plaintext": "<base64>"}}), autenticando con X-Vault-Token.
+type TransitProvider struct {
+	Addr    string
+	Token   string
+	KeyName string
+
+	CurrentVersion int
+	WrappedAESKeys map[int]string // versión -> ciphertext del servicio
+	WrappedJWTKeys []string       // claves PKCS#8 (DER) envueltas, la primera firma
+
+	Client *http.Client
+}
+
+// NewTransitProviderFromEnv lee KMS_ADDR, KMS_TOKEN, KMS_KEY_NAME, AES_KEY_WRAPPED, AES_KEY_VERSION,
+// AES_RETIRED_KEYS_WRAPPED ("1:<ciphertext>,...") y JWT_SIGNING_KEYS_WRAPPED ("<ciphertext>,...").
+func NewTransitProviderFromEnv() (*TransitProvider, error) {
+	p := &TransitProvider{
+		Addr:    strings.TrimRight(os.Getenv("KMS_ADDR"), "/"),
+		Token:   os.Getenv("KMS_TOKEN"),
+		KeyName: os.Getenv("KMS_KEY_NAME"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if p.Addr == "" || p.KeyName == "" {
+		return nil, errors.New("KEY_PROVIDER=transit requires KMS_ADDR and KMS_KEY_NAME")
+	}
+	version, err := envKeyVersion()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := parseVersioned(os.Getenv("AES_RETIRED_KEYS_WRAPPED"))
+	if err != nil {
+		return nil, fmt.Errorf("AES_RETIRED_KEYS_WRAPPED: %w", err)
+	}
+	cur := os.Getenv("AES_KEY_WRAPPED")
+	if cur == "" {
+		return nil, errors.New("AES_KEY_WRAPPED not set")
+	}
+	wrapped[version] = cur
+	p.CurrentVersion, p.WrappedAESKeys = version, wrapped
+	for _, ct := range strings.Split(os.Getenv("JWT_SIGNING_KEYS_WRAPPED"), ",") {
+		if ct = strings.TrimSpace(ct); ct != "" {
+			p.WrappedJWTKeys = append(p.WrappedJWTKeys, ct)
+		}
+	}
+	return p, nil
+}
+
+func (p *TransitProvider) Name() string { return "transit:" + p.Addr }
+
+func (p *TransitProvider) MasterKeys() (*Keyring, error) {
+	keys := make(map[int][]byte, len(p.WrappedAESKeys))
+	for v, ct := range p.WrappedAESKeys {
+		k, err := p.decrypt(ct)
+		if err != nil {
+			return nil, fmt.Errorf("unwrap key v%d: %w", v, err)
+		}
+		keys[v] = k
+	}
+	return NewKeyring(p.CurrentVersion, keys)
+}
+
+func (p *TransitProvider) SigningKeys() (*SigningKeySet, error) {
+	if len(p.WrappedJWTKeys) == 0 {
+		return nil, errors.New("JWT_SIGNING_KEYS_WRAPPED not set")
+	}
+	keys := make([]string, 0, len(p.WrappedJWTKeys))
+	for i, ct := range p.WrappedJWTKeys {
+		der, err := p.decrypt(ct)
+		if err != nil {
+			return nil, fmt.Errorf("unwrap signing key #%d: %w", i+1, err)
+		}
+		keys = append(keys, base64.StdEncoding.EncodeToString(der))
+	}
+	return parseSigningKeySet(keys)
+}
+
+func (p *TransitProvider) decrypt(ciphertext string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"ciphertext": ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.Addr+"/v1/transit/decrypt/"+url.PathEscape(p.KeyName), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("X-Vault-Token", p.Token)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("transit decrypt: status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("transit decrypt: %w", err)
+	}
+	plain, err := base64.StdEncoding.DecodeString(out.Data.Plaintext)
+	if err != nil || len(plain) == 0 {
+		return nil, errors.New("transit decrypt: empty or invalid plaintext")
+	}
+	return plain, nil
+}
@@ -0,0 +1,104 @@
+// Keyring de claves maestras versionadas: la actual cifra, las retiradas solo descifran.
+package security
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keyring agrupa varias claves maestras AES-256 identificadas por versión.
+type Keyring struct {
+	current int
+	keys    map[int][]byte
+}
+
+// NewKeyring valida que la versión actual exista y que todas las claves midan 32 bytes.
+func NewKeyring(current int, keys map[int][]byte) (*Keyring, error) {
+	if current <= 0 {
+		return nil, errors.New("key version must be positive")
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key version %d not in keyring", current)
+	}
+	for v, k := range keys {
+		if len(k) != 32 {
+			return nil, fmt.Errorf("key version %d must be 32 bytes", v)
+		}
+	}
+	return &Keyring{current: current, keys: keys}, nil
+}
+
+// Current devuelve la versión y la clave con la que se cifra todo lo nuevo.
+func (k *Keyring) Current() (int, []byte) { return k.current, k.keys[k.current] }
+
+// Key devuelve la clave de una versión concreta (actual o retirada).
+func (k *Keyring) Key(version int) ([]byte, error) {
+	key, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown key version %d", version)
+	}
+	return key, nil
+}
+
+// Versions lista las versiones disponibles en orden ascendente.
+func (k *Keyring) Versions() []int {
+	out := make([]int, 0, len(k.keys))
+	for v := range k.keys {
+		out = append(out, v)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// parseAESKey acepta 32 caracteres literales o base64 de 32 bytes.
+func parseAESKey(k string) ([]byte, error) {
+	if len(k) == 32 {
+		return []byte(k), nil
+	}
+	b, err := base64.StdEncoding.DecodeString(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, errors.New("AES_KEY debe ser 32 bytes")
+	}
+	return b, nil
+}
+
+// parseVersioned lee listas "1:<valor>,2:<valor>" (el valor puede contener ':').
+func parseVersioned(list string) (map[int]string, error) {
+	out := map[int]string{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		vs, val, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, errors.New("expected version:value")
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(vs))
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := out[v]; dup {
+			return nil, fmt.Errorf("version %d listed twice", v)
+		}
+		out[v] = strings.TrimSpace(val)
+	}
+	return out, nil
+}
+
+// CurrentKeyVersion devuelve la versión de la clave maestra activa.
+func CurrentKeyVersion() (int, error) {
+	kr, err := masterKeyring()
+	if err != nil {
+		return 0, err
+	}
+	v, _ := kr.Current()
+	return v, nil
+}
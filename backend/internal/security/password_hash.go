@@ -0,0 +1,133 @@
+// Hash de contraseñas de cuenta: Argon2id (formato PHC) por defecto; los hashes bcrypt antiguos
+// solo se verifican y se marcan para re-hashear en el siguiente login correcto.
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher calcula y verifica hashes de contraseñas de cuenta.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify compara en tiempo constante; err solo indica un hash ilegible, no una contraseña incorrecta.
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash indica que el hash es de otro algoritmo o de parámetros más débiles que los actuales.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idParams: Memory en KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLen     uint32
+	KeyLen      uint32
+}
+
+// DefaultArgon2idParams sigue la recomendación de RFC 9106 para entornos con poca memoria (64 MiB, t=3).
+var DefaultArgon2idParams = Argon2idParams{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLen: 16, KeyLen: 32}
+
+// Mínimos aceptados por configuración (OWASP: m=19 MiB, t=2, p=1).
+const (
+	minArgon2Memory     = 19 * 1024
+	minArgon2Iterations = 2
+)
+
+var errUnknownHash = errors.New("unknown password hash format")
+
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(p Argon2idParams) (*Argon2idHasher, error) {
+	if p.SaltLen == 0 {
+		p.SaltLen = DefaultArgon2idParams.SaltLen
+	}
+	if p.KeyLen == 0 {
+		p.KeyLen = DefaultArgon2idParams.KeyLen
+	}
+	switch {
+	case p.Memory < minArgon2Memory:
+		return nil, fmt.Errorf("argon2id memory must be at least %d KiB", minArgon2Memory)
+	case p.Iterations < minArgon2Iterations:
+		return nil, fmt.Errorf("argon2id iterations must be at least %d", minArgon2Iterations)
+	case p.Parallelism < 1:
+		return nil, errors.New("argon2id parallelism must be at least 1")
+	case p.SaltLen < 16 || p.KeyLen < 16:
+		return nil, errors.New("argon2id salt and key must be at least 16 bytes")
+	}
+	return &Argon2idHasher{params: p}, nil
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLen)
+	b64 := base64.RawStdEncoding.EncodeToString
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism, b64(salt), b64(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory < h.params.Memory || p.Iterations < h.params.Iterations || p.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLen || uint32(len(key)) < h.params.KeyLen
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// decodeArgon2id lee "$argon2id$v=19$m=65536,t=3,p=2$<sal>$<hash>".
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, nil, nil, errUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errUnknownHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,166 @@
+// Claves asimétricas de firma JWT (EdDSA / ES256): identificadas por kid y publicadas como JWKS.
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+)
+
+// SigningKey es una clave privada de firma con su kid (huella RFC 7638 de la parte pública).
+type SigningKey struct {
+	ID     string
+	Alg    string
+	Signer crypto.Signer
+	jwk    JWK
+}
+
+// SigningKeySet guarda las claves activas: la primera firma, todas verifican.
+// Para rotar se antepone la nueva y la anterior se mantiene hasta que caduquen sus tokens.
+type SigningKeySet struct {
+	keys []*SigningKey
+	byID map[string]*SigningKey
+}
+
+// JWK es la representación pública de una clave (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKSet es el documento servido en /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewSigningKey valida el tipo de clave (Ed25519 o ECDSA P-256) y calcula su kid.
+func NewSigningKey(signer crypto.Signer) (*SigningKey, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	var (
+		jwk   JWK
+		thumb string
+	)
+	switch pub := signer.Public().(type) {
+	case ed25519.PublicKey:
+		jwk = JWK{Kty: "OKP", Crv: "Ed25519", X: b64(pub), Alg: AlgEdDSA}
+		thumb = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 ECDSA keys are supported")
+		}
+		ecdhPub, err := pub.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		raw := ecdhPub.Bytes() // 0x04 || X || Y
+		jwk = JWK{Kty: "EC", Crv: "P-256", X: b64(raw[1:33]), Y: b64(raw[33:]), Alg: AlgES256}
+		thumb = fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, jwk.X, jwk.Y)
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", pub)
+	}
+	sum := sha256.Sum256([]byte(thumb))
+	jwk.Kid = b64(sum[:])
+	jwk.Use = "sig"
+	return &SigningKey{ID: jwk.Kid, Alg: jwk.Alg, Signer: signer, jwk: jwk}, nil
+}
+
+// ParseSigningKey decodifica una clave privada PKCS#8 en base64 (DER) o PEM.
+func ParseSigningKey(s string) (*SigningKey, error) {
+	s = strings.TrimSpace(s)
+	var der []byte
+	if strings.HasPrefix(s, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(s))
+		if block == nil {
+			return nil, errors.New("signing key: invalid PEM")
+		}
+		der = block.Bytes
+	} else {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("signing key: %w", err)
+		}
+		der = b
+	}
+	k, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("signing key: not a signer")
+	}
+	return NewSigningKey(signer)
+}
+
+// NewSigningKeySet exige al menos una clave y que no haya kids repetidos.
+func NewSigningKeySet(keys ...*SigningKey) (*SigningKeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no JWT signing keys configured")
+	}
+	set := &SigningKeySet{keys: keys, byID: make(map[string]*SigningKey, len(keys))}
+	for _, k := range keys {
+		if _, dup := set.byID[k.ID]; dup {
+			return nil, fmt.Errorf("signing key %s listed twice", k.ID)
+		}
+		set.byID[k.ID] = k
+	}
+	return set, nil
+}
+
+// parseSigningKeySet lee una lista de claves separadas por comas (la primera es la activa).
+func parseSigningKeySet(list []string) (*SigningKeySet, error) {
+	var keys []*SigningKey
+	for i, s := range list {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		k, err := ParseSigningKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("key #%d: %w", i+1, err)
+		}
+		keys = append(keys, k)
+	}
+	return NewSigningKeySet(keys...)
+}
+
+func (s *SigningKeySet) Active() *SigningKey { return s.keys[0] }
+
+func (s *SigningKeySet) Lookup(kid string) (*SigningKey, bool) {
+	k, ok := s.byID[kid]
+	return k, ok
+}
+
+func (s *SigningKeySet) JWKS() JWKSet {
+	out := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+	for _, k := range s.keys {
+		out.Keys = append(out.Keys, k.jwk)
+	}
+	return out
+}
+
+// PublicJWKS devuelve las claves públicas de verificación cargadas con Init.
+func PublicJWKS() (JWKSet, error) {
+	set, err := signingKeys()
+	if err != nil {
+		return JWKSet{}, err
+	}
+	return set.JWKS(), nil
+}
@@ -0,0 +1,159 @@
+// Cifrado por trozos para ficheros: AES-256-GCM sobre bloques de StreamChunkSize que se cifran y descifran
+// al vuelo, sin tener el fichero entero en memoria. Cada trozo lleva su número y la marca de último en el
+// nonce, así que reordenar, quitar o truncar trozos hace fallar el descifrado.
+package security
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// StreamChunkSize es el tamaño en claro de cada trozo (el último puede ser menor o vacío).
+const StreamChunkSize = 64 << 10
+
+const (
+	streamVersion = 1
+	chunkMore     = 0
+	chunkLast     = 1
+	chunkHeader   = 5 // marca + longitud del cifrado (uint32)
+)
+
+var errStreamCorrupt = errors.New("encrypted stream is corrupt or truncated")
+
+// streamNonce = número de trozo (8 bytes) | 0 0 0 | marca de último.
+func streamNonce(n uint64, flag byte) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, n)
+	nonce[11] = flag
+	return nonce
+}
+
+type encryptReader struct {
+	src  io.Reader
+	key  []byte
+	aead cipher.AEAD
+	aad  []byte
+	n    uint64
+	buf  bytes.Buffer
+	in   []byte
+	done bool
+}
+
+// NewEncryptReader devuelve un lector con el cifrado de src bajo key. La clave debe ser única por fichero
+// (los nonces son contadores). Los errores de src, incluidos los de un límite de tamaño, se propagan.
+func NewEncryptReader(key []byte, src io.Reader, aad []byte) io.Reader {
+	r := &encryptReader{src: src, key: key, aad: aad, in: make([]byte, StreamChunkSize)}
+	r.buf.WriteByte(streamVersion)
+	return r
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	return r.buf.Read(p)
+}
+
+// next lee un trozo en claro y deja su cifrado en buf.
+func (r *encryptReader) next() error {
+	n, err := io.ReadFull(r.src, r.in)
+	flag := byte(chunkMore)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		flag, r.done = chunkLast, true
+	case err != nil:
+		return err
+	}
+	if r.aead == nil {
+		if r.aead, err = newGCM(r.key); err != nil {
+			return err
+		}
+	}
+	sealed := r.aead.Seal(nil, streamNonce(r.n, flag), r.in[:n], r.aad)
+	r.n++
+	var hdr [chunkHeader]byte
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(sealed)))
+	r.buf.Write(hdr[:])
+	r.buf.Write(sealed)
+	return nil
+}
+
+type decryptReader struct {
+	src   io.Reader
+	key   []byte
+	aead  cipher.AEAD
+	aad   []byte
+	n     uint64
+	out   []byte
+	begun bool
+	done  bool
+}
+
+// NewDecryptReader es la inversa de NewEncryptReader: devuelve error (nunca datos sin autenticar) si el
+// cifrado está manipulado, truncado o tiene datos de más tras el último trozo.
+func NewDecryptReader(key []byte, src io.Reader, aad []byte) io.Reader {
+	return &decryptReader{src: src, key: key, aad: aad}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptReader) next() error {
+	if !r.begun {
+		var v [1]byte
+		if _, err := io.ReadFull(r.src, v[:]); err != nil || v[0] != streamVersion {
+			return errStreamCorrupt
+		}
+		r.begun = true
+	}
+	var hdr [chunkHeader]byte
+	if _, err := io.ReadFull(r.src, hdr[:]); err != nil {
+		return errStreamCorrupt
+	}
+	flag, size := hdr[0], binary.BigEndian.Uint32(hdr[1:])
+	var err error
+	if r.aead == nil {
+		if r.aead, err = newGCM(r.key); err != nil {
+			return err
+		}
+	}
+	if (flag != chunkMore && flag != chunkLast) || int(size) > StreamChunkSize+r.aead.Overhead() {
+		return errStreamCorrupt
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		return errStreamCorrupt
+	}
+	if r.out, err = r.aead.Open(sealed[:0], streamNonce(r.n, flag), sealed, r.aad); err != nil {
+		return errStreamCorrupt
+	}
+	r.n++
+	if flag == chunkLast {
+		var extra [1]byte
+		if _, err := io.ReadFull(r.src, extra[:]); err != io.EOF {
+			return errStreamCorrupt
+		}
+		r.done = true
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+// Caso de uso de adjuntos: ficheros cifrados por trozos con una clave propia por fichero (envuelta con la
+// clave de datos del usuario) y guardados en un BlobStore. Subida y descarga van en streaming, sin tener
+// el fichero entero en memoria, y cada usuario tiene una cuota de almacenamiento.
+package usecase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"password-danie/internal/blobstore"
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+	"password-danie/internal/security"
+)
+
+const (
+	maxAttachmentName  = 255
+	attachmentKeyBytes = 16
+	defaultContentType = "application/octet-stream"
+)
+
+// AttachmentLimits se cuentan en bytes en claro.
+type AttachmentLimits struct {
+	MaxFileSize int64 // por fichero
+	Quota       int64 // por usuario, sumando todos sus adjuntos
+}
+
+func DefaultAttachmentLimits() AttachmentLimits {
+	return AttachmentLimits{MaxFileSize: 25 << 20, Quota: 100 << 20}
+}
+
+// La API responde 413 a estos dos.
+var (
+	ErrAttachmentTooLarge = errors.New("attachment exceeds the maximum file size")
+	ErrQuotaExceeded      = errors.New("attachment storage quota exceeded")
+)
+
+type Attachments struct {
+	repo   repository.AttachmentRepo
+	vault  *Vault
+	store  blobstore.BlobStore
+	limits AttachmentLimits
+	audit  *Audit
+}
+
+// NewAttachments se engancha también al vault: borrar del todo una entrada borra sus adjuntos. audit puede ser nil.
+func NewAttachments(repo repository.AttachmentRepo, vault *Vault, store blobstore.BlobStore, limits AttachmentLimits, audit *Audit) *Attachments {
+	a := &Attachments{repo: repo, vault: vault, store: store, limits: limits, audit: audit}
+	vault.attachments = a
+	return a
+}
+
+func (a *Attachments) Limits() AttachmentLimits { return a.limits }
+
+// Upload cifra body al vuelo y lo guarda como adjunto de la entrada; devuelve sus metadatos en claro.
+func (a *Attachments) Upload(userID, secretID int64, name, contentType string, body io.Reader, ip, userAgent string) (*domain.Attachment, error) {
+	att, err := a.upload(userID, secretID, name, contentType, body)
+	a.record(userID, domain.AuditAttachUpload, secretID, ip, userAgent, err)
+	return att, err
+}
+
+// Open devuelve los metadatos y un lector con el contenido descifrado; el llamador debe cerrarlo. El
+// primer trozo se descifra aquí, así que un fichero manipulado falla antes de empezar a responder.
+func (a *Attachments) Open(userID, secretID, id int64, ip, userAgent string) (*domain.Attachment, io.ReadCloser, error) {
+	att, rc, err := a.open(userID, secretID, id)
+	a.record(userID, domain.AuditAttachGet, secretID, ip, userAgent, err)
+	return att, rc, err
+}
+
+func (a *Attachments) Delete(userID, secretID, id int64, ip, userAgent string) error {
+	err := a.delete(userID, secretID, id)
+	a.record(userID, domain.AuditAttachDelete, secretID, ip, userAgent, err)
+	return err
+}
+
+// List devuelve los adjuntos de la entrada con nombre y tipo descifrados, y lo que el usuario lleva gastado.
+func (a *Attachments) List(userID, secretID int64) ([]domain.Attachment, int64, error) {
+	if _, err := a.secret(userID, secretID); err != nil {
+		return nil, 0, err
+	}
+	items, err := a.repo.ListBySecret(userID, secretID)
+	if err != nil {
+		return nil, 0, err
+	}
+	dek, err := a.vault.dataKey(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		if err := openAttachmentMeta(dek, &items[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	used, err := a.repo.UsedBytes(userID)
+	return items, used, err
+}
+
+func (a *Attachments) record(userID int64, action string, secretID int64, ip, userAgent string, err error) {
+	result, detail := auditOutcome(err)
+	a.audit.Record(domain.AuditEvent{UserID: userID, IP: ip, UserAgent: userAgent, Action: action,
+		TargetType: "secret", TargetID: secretID, Result: result, Detail: detail})
+}
+
+func (a *Attachments) upload(userID, secretID int64, name, contentType string, body io.Reader) (*domain.Attachment, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAttachmentName {
+		return nil, fmt.Errorf("file name required (at most %d bytes)", maxAttachmentName)
+	}
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	if _, err := a.secret(userID, secretID); err != nil {
+		return nil, err
+	}
+	used, err := a.repo.UsedBytes(userID)
+	if err != nil {
+		return nil, err
+	}
+	// El tope de la subida es lo que sea menor: el tamaño máximo de fichero o lo que queda de cuota.
+	src := &cappedReader{r: body, left: a.limits.MaxFileSize, err: ErrAttachmentTooLarge}
+	if left := a.limits.Quota - used; left < src.left {
+		src.left, src.err = max(left, 0), ErrQuotaExceeded
+	}
+	dek, err := a.vault.dataKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := security.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	att := &domain.Attachment{UserID: userID, SecretID: secretID, BlobKey: randomToken(attachmentKeyBytes), Name: name, ContentType: contentType}
+	if _, err := a.store.Put(att.BlobKey, security.NewEncryptReader(fileKey, src, attachmentAAD(att, "content"))); err != nil {
+		return nil, err
+	}
+	att.Size = src.n
+	sealed := *att
+	if err := sealAttachmentMeta(dek, fileKey, &sealed); err != nil {
+		a.removeBlob(att.BlobKey)
+		return nil, err
+	}
+	id, ok, err := a.repo.CreateWithinQuota(&sealed, a.limits.Quota)
+	if err != nil || !ok {
+		// Otra subida a la vez se quedó con la cuota que quedaba.
+		a.removeBlob(att.BlobKey)
+		if err == nil {
+			err = ErrQuotaExceeded
+		}
+		return nil, err
+	}
+	att.ID = id
+	return att, nil
+}
+
+func (a *Attachments) open(userID, secretID, id int64) (*domain.Attachment, io.ReadCloser, error) {
+	att, dek, err := a.get(userID, secretID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileKey, err := security.DecryptWithKey(dek, att.KeyCipher, att.KeyIV, attachmentAAD(att, "key"))
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := a.store.Open(att.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain := bufio.NewReaderSize(security.NewDecryptReader(fileKey, rc, attachmentAAD(att, "content")), security.StreamChunkSize)
+	if _, err := plain.Peek(1); err != nil && err != io.EOF {
+		rc.Close()
+		return nil, nil, err
+	}
+	return att, readCloser{plain, rc}, nil
+}
+
+func (a *Attachments) delete(userID, secretID, id int64) error {
+	att, _, err := a.get(userID, secretID, id)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.Delete(userID, id); err != nil {
+		return err
+	}
+	a.removeBlob(att.BlobKey)
+	return nil
+}
+
+// cascade borra la entrada con deleteSecret y después sus adjuntos. Los blobs se listan antes: con las
+// claves foráneas activas, borrar la entrada ya se lleva las filas. Sin Attachments solo se borra la entrada.
+func (a *Attachments) cascade(userID, secretID int64, deleteSecret func() error) error {
+	if a == nil {
+		return deleteSecret()
+	}
+	files, err := a.repo.ListBySecret(userID, secretID)
+	if err != nil {
+		return err
+	}
+	if err := deleteSecret(); err != nil {
+		return err
+	}
+	if err := a.repo.DeleteBySecret(userID, secretID); err != nil {
+		return err
+	}
+	for _, f := range files {
+		a.removeBlob(f.BlobKey)
+	}
+	return nil
+}
+
+// get devuelve el adjunto con los metadatos descifrados y la clave de datos del usuario.
+func (a *Attachments) get(userID, secretID, id int64) (*domain.Attachment, []byte, error) {
+	att, err := a.repo.GetByID(userID, secretID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if att == nil {
+		return nil, nil, errors.New("not found")
+	}
+	dek, err := a.vault.dataKey(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := openAttachmentMeta(dek, att); err != nil {
+		return nil, nil, err
+	}
+	return att, dek, nil
+}
+
+// secret comprueba que la entrada exista, sea del usuario y no sea zero-knowledge.
+func (a *Attachments) secret(userID, secretID int64) (*domain.Secret, error) {
+	s, err := a.vault.secrets.GetByID(userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, errors.New("not found")
+	}
+	if s.EncScheme == domain.SchemeClient {
+		return nil, errZeroKnowledgeVault
+	}
+	return s, nil
+}
+
+// removeBlob no falla: un blob que no se pudo borrar queda huérfano, pero ya no es accesible.
+func (a *Attachments) removeBlob(key string) {
+	if err := a.store.Delete(key); err != nil {
+		log.Printf("attachment blob %s: %v", key, err)
+	}
+}
+
+// attachmentAAD liga cada cifrado del adjunto a su dueño, su entrada, su blob y su parte.
+func attachmentAAD(att *domain.Attachment, part string) []byte {
+	return []byte(fmt.Sprintf("password-danie/attachment/v1|user=%d|secret=%d|blob=%s|part=%s", att.UserID, att.SecretID, att.BlobKey, part))
+}
+
+func sealAttachmentMeta(dek, fileKey []byte, att *domain.Attachment) error {
+	var err error
+	if att.KeyCipher, att.KeyIV, err = security.EncryptWithKey(dek, fileKey, attachmentAAD(att, "key")); err != nil {
+		return err
+	}
+	if att.Name, err = security.SealString(dek, att.Name, attachmentAAD(att, "name")); err != nil {
+		return err
+	}
+	att.ContentType, err = security.SealString(dek, att.ContentType, attachmentAAD(att, "content_type"))
+	return err
+}
+
+func openAttachmentMeta(dek []byte, att *domain.Attachment) error {
+	var err error
+	if att.Name, err = security.OpenString(dek, att.Name, attachmentAAD(att, "name")); err != nil {
+		return err
+	}
+	att.ContentType, err = security.OpenString(dek, att.ContentType, attachmentAAD(att, "content_type"))
+	return err
+}
+
+// cappedReader deja pasar hasta left bytes y devuelve err si el origen trae más.
+type cappedReader struct {
+	r    io.Reader
+	left int64
+	n    int64
+	err  error
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > c.left+1 {
+		p = p[:c.left+1]
+	}
+	n, err := c.r.Read(p)
+	if int64(n) > c.left {
+		return 0, c.err
+	}
+	c.left -= int64(n)
+	c.n += int64(n)
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,90 @@
+// Caso de uso del registro de auditoría: los demás casos de uso anotan eventos (login, reset, cambios
+// en el vault), cada usuario consulta los suyos y Verify recorre la cadena de hashes entera.
+package usecase
+
+import (
+	"log"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+// Audit es seguro con receptor nil: sin Audit no se registra nada.
+type Audit struct {
+	repo repository.AuditRepo
+}
+
+func NewAudit(repo repository.AuditRepo) *Audit { return &Audit{repo: repo} }
+
+// AuditReport es el resultado de Verify. BrokenAt es el id del primer evento que no cuadra (0 = cadena íntegra).
+type AuditReport struct {
+	Checked  int    `json:"checked"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Record añade un evento. Un fallo al auditar se registra en el log pero no tumba la operación auditada.
+func (a *Audit) Record(e domain.AuditEvent) {
+	if a == nil {
+		return
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if err := a.repo.Append(&e); err != nil {
+		log.Printf("audit %s: %v", e.Action, err)
+	}
+}
+
+func (a *Audit) List(userID int64, limit, offset int) ([]domain.AuditEvent, int, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return a.repo.ListByUser(userID, limit, offset)
+}
+
+// Verify recalcula la cadena desde el primer evento. Detecta filas editadas, borradas o insertadas a
+// mano; no detecta que se quiten los últimos eventos (para eso hay que guardar el último hash fuera).
+func (a *Audit) Verify(batchSize int) (*AuditReport, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	rep := &AuditReport{}
+	prev := domain.AuditGenesis
+	var after int64
+	for {
+		events, err := a.repo.Chain(after, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range events {
+			e := &events[i]
+			switch {
+			case e.PrevHash != prev:
+				rep.BrokenAt, rep.Reason = e.ID, "prev_hash does not match the previous event"
+			case e.Digest() != e.Hash:
+				rep.BrokenAt, rep.Reason = e.ID, "hash does not match the event contents"
+			}
+			if rep.BrokenAt != 0 {
+				return rep, nil
+			}
+			rep.Checked++
+			prev, after = e.Hash, e.ID
+		}
+		if len(events) < batchSize {
+			return rep, nil
+		}
+	}
+}
+
+// auditOutcome traduce el error de la operación auditada a result y detail.
+func auditOutcome(err error) (string, string) {
+	if err != nil {
+		return domain.AuditResultFailure, err.Error()
+	}
+	return domain.AuditResultSuccess, ""
+}
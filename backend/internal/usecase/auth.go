@@ -1,46 +1,454 @@
-// Caso de uso de autenticación: registro (bcrypt) y login (bcrypt + JWT).
+// Caso de uso de autenticación: registro (Argon2id), login (hash + segundo factor + JWT + refresh token rotatorio)
+// y prelogin del modo zero-knowledge.
 package usecase
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-webauthn/webauthn/protocol"
 
 	"password-danie/internal/domain"
+	"password-danie/internal/policy"
 	"password-danie/internal/repository"
 	"password-danie/internal/security"
 )
 
 type Auth struct {
-	users repository.UserRepo
+	users    repository.UserRepo
+	tokens   repository.RefreshTokenRepo
+	sessions *Sessions
+	mfa      *MFA
+	passkeys *Passkeys
+	throttle *Throttle
+	audit    *Audit
+	hasher   security.PasswordHasher
+	policy   policy.Policy
+	// clave HMAC de los parámetros de relleno de Prelogin (ver PreloginKey)
+	preloginKey []byte
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// LoginResult es lo que devuelven Login y Refresh: un access token corto y un refresh token de un solo uso.
+// Si la cuenta tiene segundo factor, Login solo rellena MFAToken y MFAMethods (y ExpiresIn es la vida del reto).
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+	MFAMethods   []string
+	ExpiresIn    time.Duration
+	User         *domain.User
+}
+
+var (
+	errInvalidRefresh = errors.New("invalid refresh token")
+	errRefreshReuse   = errors.New("refresh token reuse detected")
+)
+
+// Métodos de segundo factor que puede ofrecer Login.
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodWebAuthn = "webauthn"
+)
+
+// NewAuth: mfa, passkeys, throttle y audit pueden ser nil (sin segundo factor, sin passkeys, sin límite de
+// intentos o sin auditoría). Sin preloginKey, Prelogin falla.
+func NewAuth(users repository.UserRepo, tokens repository.RefreshTokenRepo, sessions *Sessions, mfa *MFA, passkeys *Passkeys,
+	throttle *Throttle, audit *Audit, hasher security.PasswordHasher, pol policy.Policy, preloginKey []byte, accessTTL, refreshTTL time.Duration) *Auth {
+	return &Auth{users: users, tokens: tokens, sessions: sessions, mfa: mfa, passkeys: passkeys, throttle: throttle, audit: audit,
+		hasher: hasher, policy: pol, preloginKey: preloginKey, accessTTL: accessTTL, refreshTTL: refreshTTL}
 }
 
-func NewAuth(users repository.UserRepo) *Auth { return &Auth{users: users} }
+// Register crea una cuenta "server". Los avisos de la política (p. ej. contraseña filtrada en modo
+// aviso) no impiden el alta y se devuelven para mostrarlos.
+func (a *Auth) Register(email, password string) (*domain.User, []policy.Reason, error) {
+	warnings, err := a.policy.Check(password, email)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := a.users.Create(email, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := a.users.GetByID(id)
+	return u, warnings, err
+}
 
-func (a *Auth) Register(email, password string) (*domain.User, error) {
-	if len(password) < 8 {
-		return nil, errors.New("password too short")
+// RegisterZeroKnowledge crea una cuenta cuyo vault se cifra en el cliente. authKey no es la contraseña
+// maestra sino un hash de autenticación derivado por el cliente con kdf; el servidor lo trata como contraseña.
+func (a *Auth) RegisterZeroKnowledge(email, authKey string, kdf domain.KDFParams) (*domain.User, error) {
+	if err := validateAuthKey(authKey); err != nil {
+		return nil, err
+	}
+	if err := validateKDF(kdf); err != nil {
+		return nil, err
+	}
+	hash, err := a.hasher.Hash(authKey)
+	if err != nil {
+		return nil, err
 	}
-	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	id, err := a.users.Create(email, string(hash))
+	id, err := a.users.CreateZeroKnowledge(email, hash, kdf)
 	if err != nil {
 		return nil, err
 	}
 	return a.users.GetByID(id)
 }
 
-func (a *Auth) Login(email, password string) (string, *domain.User, error) {
+// Prelogin devuelve los parámetros KDF con los que un cliente zero-knowledge deriva su clave antes de
+// autenticarse. La respuesta tiene la misma forma para cualquier email: las cuentas "server" y los emails
+// sin cuenta reciben parámetros de relleno estables (decoyKDF), así que no revela si la cuenta existe ni
+// su modo. El modo llega en la respuesta del login.
+func (a *Auth) Prelogin(email string) (*domain.KDFParams, error) {
+	u, err := a.users.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || !u.ZeroKnowledge() {
+		return decoyKDF(a.preloginKey, email)
+	}
+	kdf := u.KDF
+	return &kdf, nil
+}
+
+// Login verifica la contraseña. Cada intento fallido y cada login completo quedan en la auditoría.
+func (a *Auth) Login(email, password, ip, userAgent string) (*LoginResult, error) {
 	u, err := a.users.GetByEmail(email)
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+	// Con la cuenta o la IP bloqueadas ni se verifica la contraseña (es lo caro).
+	if err := a.throttle.Check(ThrottleLogin, email, ip); err != nil {
+		a.auditLogin(u, email, ip, userAgent, "password", err)
+		return nil, err
+	}
+	if u == nil {
+		// Se verifica igualmente contra un hash de relleno para que un email desconocido tarde lo mismo
+		// que una contraseña incorrecta.
+		if h := a.dummyHash(); h != "" {
+			_, _ = a.hasher.Verify(password, h)
+		}
+		a.failLogin(email, ip)
+		err := errors.New("invalid credentials")
+		a.auditLogin(nil, email, ip, userAgent, "password", err)
+		return nil, err
+	}
+	ok, err := a.hasher.Verify(password, u.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		a.failLogin(email, ip)
+		err := errors.New("invalid credentials")
+		a.auditLogin(u, email, ip, userAgent, "password", err)
+		return nil, err
+	}
+	// Hashes antiguos (bcrypt o parámetros más débiles): se re-hashean ahora que tenemos la contraseña.
+	// Si falla no se bloquea el login; se reintentará en el siguiente.
+	if a.hasher.NeedsRehash(u.PasswordHash) {
+		if hash, err := a.hasher.Hash(password); err != nil {
+			log.Printf("rehash password for user %d: %v", u.ID, err)
+		} else if err := a.users.RehashPassword(u.ID, u.PasswordHash, hash); err != nil {
+			log.Printf("rehash password for user %d: %v", u.ID, err)
+		}
+	}
+	methods, err := a.mfaMethods(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		token, err := a.mfa.challenge(u.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAToken: token, MFAMethods: methods, ExpiresIn: a.mfa.challengeTTL}, nil
+	}
+	a.auditLogin(u, email, ip, userAgent, "password", nil)
+	return a.startSession(u)
+}
+
+// dummyHash es un hash con los parámetros actuales del hasher que no corresponde a ninguna cuenta. Se
+// calcula la primera vez que hace falta; si falla, Login no lo verifica.
+func (a *Auth) dummyHash() string {
+	a.dummyOnce.Do(func() {
+		h, err := a.hasher.Hash("password-danie/dummy")
+		if err != nil {
+			log.Printf("dummy password hash: %v", err)
+			return
+		}
+		a.dummy = h
+	})
+	return a.dummy
+}
+
+// mfaMethods lista los segundos factores activos del usuario (vacío = login de un paso).
+func (a *Auth) mfaMethods(userID int64) ([]string, error) {
+	if a.mfa == nil {
+		return nil, nil
+	}
+	var methods []string
+	totp, err := a.mfa.required(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp {
+		methods = append(methods, MFAMethodTOTP)
+	}
+	if a.passkeys != nil {
+		has, err := a.passkeys.registered(userID)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			methods = append(methods, MFAMethodWebAuthn)
+		}
+	}
+	return methods, nil
+}
+
+// LoginMFA completa un login con segundo factor: canjea el reto de Login con un código TOTP o de recuperación.
+func (a *Auth) LoginMFA(mfaToken, code, ip, userAgent string) (*LoginResult, error) {
+	if a.mfa == nil {
+		return nil, errInvalidMFAToken
+	}
+	const method = "password+code"
+	userID, err := a.mfa.redeem(mfaToken, func(userID int64) error {
+		return a.guardFactor(userID, ip, userAgent, method, func() error { return a.mfa.verify(userID, code) })
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.loginUser(userID, ip, userAgent, method)
+}
+
+// BeginPasskeyMFA inicia la aserción de una passkey como segundo factor del reto de Login.
+func (a *Auth) BeginPasskeyMFA(mfaToken string) (*protocol.CredentialAssertion, string, error) {
+	if a.mfa == nil || a.passkeys == nil {
+		return nil, "", errInvalidMFAToken
+	}
+	userID, err := a.mfa.pending(mfaToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return a.passkeys.beginFor(userID)
+}
+
+// LoginPasskeyMFA completa el reto de Login con la aserción de una passkey.
+func (a *Auth) LoginPasskeyMFA(mfaToken, sessionToken string, response []byte, ip, userAgent string) (*LoginResult, error) {
+	if a.mfa == nil || a.passkeys == nil {
+		return nil, errInvalidMFAToken
+	}
+	const method = "password+webauthn"
+	userID, err := a.mfa.redeem(mfaToken, func(userID int64) error {
+		return a.guardFactor(userID, ip, userAgent, method, func() error { return a.passkeys.verifyFor(userID, sessionToken, response) })
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.loginUser(userID, ip, userAgent, method)
+}
+
+// LoginPasskey es el login sin contraseña: la passkey (con verificación de usuario) basta.
+func (a *Auth) LoginPasskey(sessionToken string, response []byte, ip, userAgent string) (*LoginResult, error) {
+	if a.passkeys == nil {
+		return nil, errPasskeyRejected
+	}
+	userID, err := a.passkeys.FinishLogin(sessionToken, response)
+	if err != nil {
+		a.auditLogin(nil, "", ip, userAgent, "passkey", err)
+		return nil, err
+	}
+	return a.loginUser(userID, ip, userAgent, "passkey")
+}
+
+// guardFactor aplica el límite de intentos de la cuenta al segundo factor: un segundo factor fallido
+// cuenta como un fallo de login, así que tener la contraseña no da intentos ilimitados de código.
+func (a *Auth) guardFactor(userID int64, ip, userAgent, method string, verify func() error) error {
+	u, err := a.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errInvalidMFAToken
+	}
+	if err := a.throttle.Check(ThrottleLogin, u.Email, ""); err != nil {
+		a.auditLogin(u, u.Email, ip, userAgent, method, err)
+		return err
+	}
+	err = verify()
+	if failedFactor(err) {
+		a.failLogin(u.Email, "")
+		a.auditLogin(u, u.Email, ip, userAgent, method, err)
+	}
+	return err
+}
+
+// auditLogin anota un intento de login; u es nil si no se sabe de qué cuenta es.
+func (a *Auth) auditLogin(u *domain.User, email, ip, userAgent, method string, err error) {
+	e := domain.AuditEvent{Actor: email, IP: ip, UserAgent: userAgent, Action: domain.AuditLogin, Detail: method}
+	if u != nil {
+		e.UserID, e.Actor, e.TargetType, e.TargetID = u.ID, u.Email, "user", u.ID
+	}
+	e.Result = domain.AuditResultSuccess
+	if err != nil {
+		e.Result, e.Detail = domain.AuditResultFailure, method+": "+err.Error()
+	}
+	a.audit.Record(e)
+}
+
+// failLogin anota el fallo; si el contador no se puede guardar se registra y el login falla igual.
+func (a *Auth) failLogin(email, ip string) {
+	if err := a.throttle.Fail(ThrottleLogin, email, ip); err != nil {
+		log.Printf("throttle login: %v", err)
+	}
+}
+
+func (a *Auth) loginUser(userID int64, ip, userAgent, method string) (*LoginResult, error) {
+	u, err := a.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("invalid credentials")
+	}
+	a.auditLogin(u, u.Email, ip, userAgent, method, nil)
+	return a.startSession(u)
+}
+
+// startSession abre una sesión tras autenticar; su id es también la familia de sus refresh tokens.
+func (a *Auth) startSession(u *domain.User) (*LoginResult, error) {
+	a.throttle.Succeed(ThrottleLogin, u.Email)
+	sess, err := a.sessions.Start(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	refresh, t := a.newRefreshToken(u.ID, sess.ID)
+	if err := a.tokens.Create(t); err != nil {
+		return nil, err
+	}
+	return a.issue(u, sess.ID, refresh)
+}
+
+// ChangePassword cambia la contraseña maestra de una cuenta "server" y cierra las demás sesiones.
+// En zero-knowledge el cambio implica re-cifrar el vault en el cliente, así que no se hace aquí.
+func (a *Auth) ChangePassword(userID int64, sessionID, current, next string) ([]policy.Reason, error) {
+	u, err := a.users.GetByID(userID)
+	if err != nil {
+		return nil, err
 	}
 	if u == nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, errors.New("not found")
+	}
+	if u.ZeroKnowledge() {
+		return nil, errors.New("zero-knowledge account: the master password is changed from the client")
 	}
-	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
-		return "", nil, errors.New("invalid credentials")
+	ok, err := a.hasher.Verify(current, u.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid credentials")
+	}
+	warnings, err := a.policy.Check(next, u.Email)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := a.hasher.Hash(next)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.users.UpdatePassword(u.ID, hash); err != nil {
+		return nil, err
+	}
+	_, err = a.sessions.LogoutOthers(u.ID, sessionID)
+	return warnings, err
+}
+
+// Refresh canjea un refresh token por un par nuevo. El token usado deja de valer; si alguien presenta
+// uno ya canjeado (robado o reenviado), se revoca toda su familia y hay que volver a hacer login.
+func (a *Auth) Refresh(refreshToken string) (*LoginResult, error) {
+	t, err := a.tokens.GetByHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.RevokedAt != nil || time.Now().After(t.ExpiresAt) {
+		return nil, errInvalidRefresh
+	}
+	if t.UsedAt != nil {
+		return nil, a.reuseDetected(t)
+	}
+	if err := a.sessions.Check(t.FamilyID); err != nil {
+		return nil, errInvalidRefresh
+	}
+	u, err := a.users.GetByID(t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errInvalidRefresh
+	}
+	refresh, next := a.newRefreshToken(u.ID, t.FamilyID)
+	ok, err := a.tokens.Rotate(t.ID, next)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Otra petición lo canjeó (o revocó) entre la lectura y la rotación.
+		return nil, a.reuseDetected(t)
+	}
+	return a.issue(u, t.FamilyID, refresh)
+}
+
+// reuseDetected revoca la sesión entera: también dejan de valer sus access tokens ya emitidos.
+func (a *Auth) reuseDetected(t *domain.RefreshToken) error {
+	if err := a.sessions.revoke(t.FamilyID); err != nil {
+		return err
+	}
+	return errRefreshReuse
+}
+
+func (a *Auth) issue(u *domain.User, sessionID, refresh string) (*LoginResult, error) {
+	access, err := security.GenerateAccessToken(u.ID, u.Email, sessionID, a.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AccessToken: access, RefreshToken: refresh, ExpiresIn: a.accessTTL, User: u}, nil
+}
+
+// newRefreshToken genera el token opaco (lo que ve el cliente) y la fila con su hash.
+func (a *Auth) newRefreshToken(userID int64, family string) (string, *domain.RefreshToken) {
+	token := randomToken(32)
+	return token, &domain.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(a.refreshTTL),
+	}
+}
+
+// hashRefreshToken: el token tiene 256 bits aleatorios, así que basta un SHA-256 (sin sal ni KDF lento).
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateAuthKey exige un hash derivado (>= 32 bytes en base64), nunca una contraseña en claro.
+func validateAuthKey(authKey string) error {
+	raw, err := base64.StdEncoding.DecodeString(authKey)
+	if err != nil || len(raw) < 32 {
+		return errors.New("zero-knowledge password must be a base64 derived key of at least 32 bytes")
 	}
-	token, err := security.GenerateAccessToken(u.ID, u.Email, 15*time.Minute)
-	return token, u, err
+	return nil
 }
@@ -0,0 +1,191 @@
+// Caso de uso de rotación de la clave maestra: re-envuelve claves de datos y re-cifra secretos legados y TOTP por lotes.
+package usecase
+
+import (
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+	"password-danie/internal/security"
+)
+
+type KeyRotation struct {
+	repo      repository.RotationRepo
+	batchSize int
+	pause     time.Duration // respiro entre lotes para no acaparar la base de datos
+}
+
+func NewKeyRotation(repo repository.RotationRepo, batchSize int, pause time.Duration) *KeyRotation {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &KeyRotation{repo: repo, batchSize: batchSize, pause: pause}
+}
+
+// Prepare devuelve el trabajo a ejecutar: reanuda uno pendiente o crea uno nuevo si hay filas
+// cifradas con una versión distinta de la actual. Devuelve nil si no hay nada que rotar.
+func (k *KeyRotation) Prepare() (*domain.KeyRotation, error) {
+	target, err := security.CurrentKeyVersion()
+	if err != nil {
+		return nil, err
+	}
+	j, err := k.repo.Latest()
+	if err != nil {
+		return nil, err
+	}
+	if j != nil && j.Status != domain.RotationDone {
+		if j.TargetVersion != target {
+			// La clave cambió otra vez a mitad de rotación: se recorre todo hacia la nueva versión.
+			j.TargetVersion = target
+			j.Phase = domain.RotationPhaseUserKeys
+			j.LastID = 0
+		}
+		j.Status = domain.RotationRunning
+		j.Error = ""
+		return j, k.repo.SaveProgress(j)
+	}
+	if j != nil && j.TargetVersion == target {
+		return nil, nil
+	}
+	keys, err := k.repo.UserKeysBatch(0, target, 1)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := k.repo.LegacySecretsBatch(0, target, 1)
+	if err != nil {
+		return nil, err
+	}
+	totps, err := k.repo.TOTPBatch(0, target, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 && len(secrets) == 0 && len(totps) == 0 {
+		return nil, nil
+	}
+	return k.repo.Start(target)
+}
+
+// Run procesa el trabajo hasta terminarlo, guardando el cursor tras cada lote para poder reanudar.
+func (k *KeyRotation) Run(j *domain.KeyRotation) error {
+	for j.Phase != domain.RotationPhaseDone {
+		var (
+			n   int
+			err error
+		)
+		switch j.Phase {
+		case domain.RotationPhaseUserKeys:
+			n, err = k.rewrapBatch(j)
+		case domain.RotationPhaseSecrets:
+			n, err = k.reencryptBatch(j)
+		case domain.RotationPhaseTOTP:
+			n, err = k.reencryptTOTPBatch(j)
+		default:
+			j.Phase = domain.RotationPhaseDone
+		}
+		if err != nil {
+			j.Status = domain.RotationFailed
+			j.Error = err.Error()
+			_ = k.repo.SaveProgress(j)
+			return err
+		}
+		if n == 0 && j.Phase != domain.RotationPhaseDone {
+			j.Phase = nextRotationPhase(j.Phase)
+			j.LastID = 0
+		}
+		if err := k.repo.SaveProgress(j); err != nil {
+			return err
+		}
+		if n > 0 && k.pause > 0 {
+			time.Sleep(k.pause)
+		}
+	}
+	j.Status = domain.RotationDone
+	j.FinishedAt = time.Now()
+	return k.repo.SaveProgress(j)
+}
+
+func nextRotationPhase(phase string) string {
+	switch phase {
+	case domain.RotationPhaseUserKeys:
+		return domain.RotationPhaseSecrets
+	case domain.RotationPhaseSecrets:
+		return domain.RotationPhaseTOTP
+	}
+	return domain.RotationPhaseDone
+}
+
+func (k *KeyRotation) rewrapBatch(j *domain.KeyRotation) (int, error) {
+	batch, err := k.repo.UserKeysBatch(j.LastID, j.TargetVersion, k.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, uk := range batch {
+		dek, err := security.UnwrapKey(uk.KeyVersion, uk.WrappedKey, uk.WrappedIV)
+		if err != nil {
+			return 0, err
+		}
+		wrapped, iv, version, err := security.WrapKey(dek)
+		if err != nil {
+			return 0, err
+		}
+		next := uk
+		next.WrappedKey, next.WrappedIV, next.KeyVersion = wrapped, iv, version
+		// Si otra escritura se adelantó, la fila ya no encaja y la recogerá una rotación posterior.
+		if _, err := k.repo.RewrapUserKey(&next, uk.WrappedKey); err != nil {
+			return 0, err
+		}
+		j.LastID = uk.UserID
+		j.Processed++
+	}
+	return len(batch), nil
+}
+
+func (k *KeyRotation) reencryptBatch(j *domain.KeyRotation) (int, error) {
+	batch, err := k.repo.LegacySecretsBatch(j.LastID, j.TargetVersion, k.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range batch {
+		plain, err := security.Decrypt(s.KeyVersion, s.PasswordCipher, s.PasswordIV)
+		if err != nil {
+			return 0, err
+		}
+		c, iv, version, err := security.Encrypt(plain)
+		if err != nil {
+			return 0, err
+		}
+		next := s
+		next.PasswordCipher, next.PasswordIV, next.KeyVersion = c, iv, version
+		if _, err := k.repo.ReencryptSecret(&next, s.PasswordCipher); err != nil {
+			return 0, err
+		}
+		j.LastID = s.ID
+		j.Processed++
+	}
+	return len(batch), nil
+}
+
+func (k *KeyRotation) reencryptTOTPBatch(j *domain.KeyRotation) (int, error) {
+	batch, err := k.repo.TOTPBatch(j.LastID, j.TargetVersion, k.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range batch {
+		plain, err := security.Decrypt(t.KeyVersion, t.SecretCipher, t.SecretIV)
+		if err != nil {
+			return 0, err
+		}
+		c, iv, version, err := security.Encrypt(plain)
+		if err != nil {
+			return 0, err
+		}
+		next := t
+		next.SecretCipher, next.SecretIV, next.KeyVersion = c, iv, version
+		if _, err := k.repo.ReencryptTOTP(&next, t.SecretCipher); err != nil {
+			return 0, err
+		}
+		j.LastID = t.UserID
+		j.Processed++
+	}
+	return len(batch), nil
+}
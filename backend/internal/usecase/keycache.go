@@ -0,0 +1,63 @@
+// Caché LRU acotada de claves de datos ya desenvueltas, para no descifrarlas en cada petición.
+package usecase
+
+import (
+	"container/list"
+	"sync"
+)
+
+const defaultDataKeyCacheSize = 1024
+
+type dataKeyCache struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[int64]*list.Element
+}
+
+type dataKeyEntry struct {
+	userID int64
+	key    []byte
+}
+
+func newDataKeyCache(max int) *dataKeyCache {
+	if max <= 0 {
+		max = defaultDataKeyCacheSize
+	}
+	return &dataKeyCache{max: max, ll: list.New(), items: make(map[int64]*list.Element)}
+}
+
+func (c *dataKeyCache) get(userID int64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[userID]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*dataKeyEntry).key, true
+	}
+	return nil, false
+}
+
+func (c *dataKeyCache) put(userID int64, key []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[userID]; ok {
+		e.Value.(*dataKeyEntry).key = key
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[userID] = c.ll.PushFront(&dataKeyEntry{userID: userID, key: key})
+	for c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*dataKeyEntry).userID)
+	}
+}
+
+func (c *dataKeyCache) remove(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[userID]; ok {
+		c.ll.Remove(e)
+		delete(c.items, userID)
+	}
+}
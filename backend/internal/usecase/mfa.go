@@ -0,0 +1,282 @@
+// Caso de uso del segundo factor: alta de TOTP (RFC 6238) confirmada con un primer código, códigos de
+// recuperación de un solo uso y retos de login que se canjean con el segundo factor.
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/otp"
+	"password-danie/internal/repository"
+	"password-danie/internal/security"
+)
+
+const (
+	recoveryCodeCount      = 10
+	maxChallengeAttempts   = 5
+	totpSkew               = 1 // pasos de 30 s aceptados a cada lado por deriva de reloj
+	defaultMFAChallengeTTL = 5 * time.Minute
+)
+
+var (
+	errInvalidMFACode     = errors.New("invalid authentication code")
+	errMFACodeReused      = errors.New("authentication code already used")
+	errInvalidMFAToken    = errors.New("invalid or expired mfa token")
+	errTOTPAlreadyEnabled = errors.New("totp already enabled")
+	errTOTPNotEnabled     = errors.New("totp not enabled")
+)
+
+type MFA struct {
+	repo         repository.MFARepo
+	users        repository.UserRepo
+	issuer       string // nombre que muestran las apps de autenticación
+	challengeTTL time.Duration
+}
+
+func NewMFA(repo repository.MFARepo, users repository.UserRepo, issuer string, challengeTTL time.Duration) *MFA {
+	if challengeTTL <= 0 {
+		challengeTTL = defaultMFAChallengeTTL
+	}
+	return &MFA{repo: repo, users: users, issuer: issuer, challengeTTL: challengeTTL}
+}
+
+// TOTPEnrollment es lo que necesita el cliente para dar de alta el TOTP en su app (la URI va en un QR).
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAStatus resume el segundo factor de una cuenta.
+type MFAStatus struct {
+	TOTPEnabled       bool `json:"totp_enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Enroll genera un secreto nuevo pendiente de confirmar. Repetirlo antes de confirmar lo sustituye.
+func (m *MFA) Enroll(userID int64) (*TOTPEnrollment, error) {
+	u, err := m.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("not found")
+	}
+	secret, err := otp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	c, iv, version, err := security.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	ok, err := m.repo.SaveTOTP(&domain.TOTP{UserID: userID, SecretCipher: c, SecretIV: iv, KeyVersion: version})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errTOTPAlreadyEnabled
+	}
+	return &TOTPEnrollment{Secret: secret, URI: otp.URI(m.issuer, u.Email, secret)}, nil
+}
+
+// Confirm activa el TOTP con un primer código válido y devuelve los códigos de recuperación en claro
+// (es la única vez que se muestran).
+func (m *MFA) Confirm(userID int64, code string) ([]string, error) {
+	t, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New("totp enrollment not started")
+	}
+	if t.Enabled() {
+		return nil, errTOTPAlreadyEnabled
+	}
+	step, err := m.validateTOTP(t, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := m.repo.EnableTOTP(userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errTOTPAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// Disable quita el segundo factor; exige un código TOTP o de recuperación.
+func (m *MFA) Disable(userID int64, code string) error {
+	if err := m.verify(userID, code); err != nil {
+		return err
+	}
+	return m.repo.DeleteTOTP(userID)
+}
+
+func (m *MFA) Status(userID int64) (*MFAStatus, error) {
+	t, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	st := &MFAStatus{TOTPEnabled: t != nil && t.Enabled()}
+	if st.TOTPEnabled {
+		if st.RecoveryCodesLeft, err = m.repo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// required dice si el login de userID necesita segundo factor.
+func (m *MFA) required(userID int64) (bool, error) {
+	t, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return false, err
+	}
+	return t != nil && t.Enabled(), nil
+}
+
+// challenge crea el reto de login y devuelve su token opaco (se guarda solo el hash, como un refresh token).
+func (m *MFA) challenge(userID int64) (string, error) {
+	token := randomToken(32)
+	err := m.repo.CreateChallenge(&domain.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(m.challengeTTL),
+	})
+	return token, err
+}
+
+// pending devuelve el usuario de un reto aún válido sin gastarlo (para iniciar la aserción de una passkey).
+func (m *MFA) pending(token string) (int64, error) {
+	c, err := m.repo.GetChallenge(hashRefreshToken(token))
+	if err != nil {
+		return 0, err
+	}
+	if c == nil || c.UsedAt != nil || time.Now().After(c.ExpiresAt) {
+		return 0, errInvalidMFAToken
+	}
+	return c.UserID, nil
+}
+
+// redeem canjea el reto si verify acepta el segundo factor del usuario y lo devuelve. Cada fallo cuenta
+// como intento; al llegar al máximo el reto deja de valer y hay que repetir la contraseña.
+func (m *MFA) redeem(token string, verify func(userID int64) error) (int64, error) {
+	c, err := m.repo.GetChallenge(hashRefreshToken(token))
+	if err != nil {
+		return 0, err
+	}
+	if c == nil || c.UsedAt != nil || time.Now().After(c.ExpiresAt) {
+		return 0, errInvalidMFAToken
+	}
+	if err := verify(c.UserID); err != nil {
+		if failedFactor(err) {
+			if ferr := m.repo.FailChallenge(c.ID, maxChallengeAttempts); ferr != nil {
+				return 0, ferr
+			}
+		}
+		return 0, err
+	}
+	ok, err := m.repo.ConsumeChallenge(c.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errInvalidMFAToken
+	}
+	return c.UserID, nil
+}
+
+// failedFactor distingue un segundo factor incorrecto (cuenta como intento) de un fallo interno.
+func failedFactor(err error) bool {
+	for _, e := range []error{errInvalidMFACode, errMFACodeReused, errTOTPNotEnabled, errPasskeyRejected, errPasskeyCloned, errWebAuthnSession} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify acepta un código TOTP (una sola vez por paso) o un código de recuperación sin usar.
+func (m *MFA) verify(userID int64, code string) error {
+	t, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if t == nil || !t.Enabled() {
+		return errTOTPNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) != otp.Digits {
+		ok, err := m.repo.UseRecoveryCode(userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+	step, err := m.validateTOTP(t, code)
+	if err != nil {
+		return err
+	}
+	ok, err := m.repo.UseStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errMFACodeReused
+	}
+	return nil
+}
+
+func (m *MFA) validateTOTP(t *domain.TOTP, code string) (int64, error) {
+	secret, err := security.Decrypt(t.KeyVersion, t.SecretCipher, t.SecretIV)
+	if err != nil {
+		return 0, err
+	}
+	step, ok, err := otp.Validate(string(secret), code, time.Now(), totpSkew)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errInvalidMFACode
+	}
+	return step, nil
+}
+
+// newRecoveryCodes genera códigos de 80 bits con formato xxxx-xxxx-xxxx-xxxx y sus hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(enc.EncodeToString(raw))
+		codes[i] = s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignora guiones, espacios y mayúsculas para que el usuario pueda teclearlo como quiera.
+func hashRecoveryCode(code string) string {
+	norm := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,323 @@
+// Caso de uso de passkeys (WebAuthn): alta de credenciales, login sin contraseña con passkeys descubribles
+// y uso como segundo factor tras la contraseña. Cada aserción actualiza el contador de firmas; si retrocede
+// se rechaza (posible autenticador clonado).
+package usecase
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+const defaultWebAuthnSessionTTL = 5 * time.Minute
+
+var (
+	errWebAuthnSession = errors.New("invalid or expired webauthn session")
+	errPasskeyRejected = errors.New("passkey verification failed")
+	errPasskeyCloned   = errors.New("passkey signature counter went backwards: possible cloned authenticator")
+)
+
+type Passkeys struct {
+	repo       repository.WebAuthnRepo
+	users      repository.UserRepo
+	rp         *webauthn.WebAuthn
+	sessionTTL time.Duration
+}
+
+func NewPasskeys(repo repository.WebAuthnRepo, users repository.UserRepo, rp *webauthn.WebAuthn, sessionTTL time.Duration) *Passkeys {
+	if sessionTTL <= 0 {
+		sessionTTL = defaultWebAuthnSessionTTL
+	}
+	return &Passkeys{repo: repo, users: users, rp: rp, sessionTTL: sessionTTL}
+}
+
+// BeginRegistration devuelve las opciones para navigator.credentials.create() y el token de la ceremonia.
+// Se pide passkey descubrible para que sirva también para el login sin contraseña.
+func (p *Passkeys) BeginRegistration(userID int64) (*protocol.CredentialCreation, string, error) {
+	u, err := p.user(userID, true)
+	if err != nil {
+		return nil, "", err
+	}
+	exclude := make([]protocol.CredentialDescriptor, 0, len(u.creds))
+	for _, c := range u.creds {
+		exclude = append(exclude, c.Descriptor())
+	}
+	opts, sd, err := p.rp.BeginRegistration(u,
+		webauthn.WithExclusions(exclude),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		}))
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := p.saveSession(userID, domain.WebAuthnRegister, sd)
+	return opts, token, err
+}
+
+// FinishRegistration verifica la respuesta del autenticador y guarda la credencial.
+func (p *Passkeys) FinishRegistration(userID int64, token, name string, response []byte) (*domain.WebAuthnCredential, error) {
+	sd, err := p.consumeSession(token, domain.WebAuthnRegister, userID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.user(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errPasskeyRejected
+	}
+	cred, err := p.rp.CreateCredential(u, *sd, parsed)
+	if err != nil {
+		return nil, errPasskeyRejected
+	}
+	c := &domain.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+		Name:            name,
+	}
+	for _, t := range cred.Transport {
+		c.Transports = append(c.Transports, string(t))
+	}
+	if err := p.repo.CreateCredential(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *Passkeys) List(userID int64) ([]domain.WebAuthnCredential, error) {
+	return p.repo.ListCredentials(userID)
+}
+
+func (p *Passkeys) Delete(userID, id int64) error {
+	ok, err := p.repo.DeleteCredential(userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+// BeginLogin prepara un login sin contraseña: sin usuario, el navegador ofrece las passkeys del sitio.
+func (p *Passkeys) BeginLogin() (*protocol.CredentialAssertion, string, error) {
+	opts, sd, err := p.rp.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := p.saveSession(0, domain.WebAuthnLogin, sd)
+	return opts, token, err
+}
+
+// FinishLogin verifica la aserción de un login sin contraseña y devuelve el usuario.
+func (p *Passkeys) FinishLogin(token string, response []byte) (int64, error) {
+	sd, err := p.consumeSession(token, domain.WebAuthnLogin, 0)
+	if err != nil {
+		return 0, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return 0, errPasskeyRejected
+	}
+	var owner *passkeyUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		id, err := p.repo.UserByHandle(base64.RawURLEncoding.EncodeToString(userHandle))
+		if err != nil || id == 0 {
+			return nil, errPasskeyRejected
+		}
+		if owner, err = p.user(id, false); err != nil {
+			return nil, err
+		}
+		return owner, nil
+	}
+	cred, err := p.rp.ValidateDiscoverableLogin(handler, *sd, parsed)
+	if err != nil {
+		return 0, errPasskeyRejected
+	}
+	if err := p.track(owner, cred); err != nil {
+		return 0, err
+	}
+	return owner.id, nil
+}
+
+// beginFor prepara una aserción limitada a las passkeys de un usuario (segundo factor).
+func (p *Passkeys) beginFor(userID int64) (*protocol.CredentialAssertion, string, error) {
+	u, err := p.user(userID, false)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(u.creds) == 0 {
+		return nil, "", errors.New("no passkeys registered")
+	}
+	opts, sd, err := p.rp.BeginLogin(u)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := p.saveSession(userID, domain.WebAuthnMFA, sd)
+	return opts, token, err
+}
+
+// verifyFor comprueba la aserción de segundo factor de userID.
+func (p *Passkeys) verifyFor(userID int64, token string, response []byte) error {
+	sd, err := p.consumeSession(token, domain.WebAuthnMFA, userID)
+	if err != nil {
+		return err
+	}
+	u, err := p.user(userID, false)
+	if err != nil {
+		return err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return errPasskeyRejected
+	}
+	cred, err := p.rp.ValidateLogin(u, *sd, parsed)
+	if err != nil {
+		return errPasskeyRejected
+	}
+	return p.track(u, cred)
+}
+
+// registered dice si el usuario tiene alguna passkey (entonces el login con contraseña pide segundo factor).
+func (p *Passkeys) registered(userID int64) (bool, error) {
+	n, err := p.repo.CountCredentials(userID)
+	return n > 0, err
+}
+
+// track guarda el nuevo contador de firmas. Un contador que no avanza (salvo autenticadores que siempre
+// envían 0) o una escritura concurrente con otro valor indican un clon: se rechaza el login.
+func (p *Passkeys) track(u *passkeyUser, cred *webauthn.Credential) error {
+	if cred.Authenticator.CloneWarning {
+		return errPasskeyCloned
+	}
+	for _, c := range u.stored {
+		if !bytes.Equal(c.CredentialID, cred.ID) {
+			continue
+		}
+		ok, err := p.repo.UpdateSignCount(c.ID, c.SignCount, cred.Authenticator.SignCount, cred.Flags.BackupState)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errPasskeyCloned
+		}
+		return nil
+	}
+	return errPasskeyRejected
+}
+
+func (p *Passkeys) saveSession(userID int64, purpose string, sd *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(sd)
+	if err != nil {
+		return "", err
+	}
+	token := randomToken(32)
+	err = p.repo.CreateSession(&domain.WebAuthnSession{
+		TokenHash: hashRefreshToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(p.sessionTTL),
+	})
+	return token, err
+}
+
+// consumeSession gasta la sesión de la ceremonia (un solo uso) y comprueba propósito, usuario y caducidad.
+func (p *Passkeys) consumeSession(token, purpose string, userID int64) (*webauthn.SessionData, error) {
+	s, err := p.repo.ConsumeSession(hashRefreshToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.Purpose != purpose || s.UserID != userID || time.Now().After(s.ExpiresAt) {
+		return nil, errWebAuthnSession
+	}
+	var sd webauthn.SessionData
+	if err := json.Unmarshal([]byte(s.Data), &sd); err != nil {
+		return nil, err
+	}
+	return &sd, nil
+}
+
+// user carga el usuario con sus credenciales; create asigna un user handle aleatorio si aún no tiene.
+func (p *Passkeys) user(userID int64, create bool) (*passkeyUser, error) {
+	u, err := p.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("not found")
+	}
+	handle, err := p.repo.Handle(userID)
+	if err != nil {
+		return nil, err
+	}
+	if handle == "" && create {
+		fresh := make([]byte, 32)
+		if _, err := rand.Read(fresh); err != nil {
+			return nil, err
+		}
+		// Si otra petición lo creó a la vez, INSERT OR IGNORE conserva el suyo y se relee.
+		if err := p.repo.CreateHandle(userID, base64.RawURLEncoding.EncodeToString(fresh)); err != nil {
+			return nil, err
+		}
+		if handle, err = p.repo.Handle(userID); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(handle)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := p.repo.ListCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	pu := &passkeyUser{id: u.ID, email: u.Email, handle: raw, stored: stored}
+	for _, c := range stored {
+		wc := webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Flags:           webauthn.CredentialFlags{BackupEligible: c.BackupEligible, BackupState: c.BackupState},
+			Authenticator:   webauthn.Authenticator{AAGUID: c.AAGUID, SignCount: c.SignCount},
+		}
+		for _, t := range c.Transports {
+			wc.Transport = append(wc.Transport, protocol.AuthenticatorTransport(t))
+		}
+		pu.creds = append(pu.creds, wc)
+	}
+	return pu, nil
+}
+
+// passkeyUser adapta un usuario a la interfaz webauthn.User.
+type passkeyUser struct {
+	id     int64
+	email  string
+	handle []byte
+	creds  []webauthn.Credential
+	stored []domain.WebAuthnCredential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte                         { return u.handle }
+func (u *passkeyUser) WebAuthnName() string                       { return u.email }
+func (u *passkeyUser) WebAuthnDisplayName() string                { return u.email }
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential { return u.creds }
@@ -1,4 +1,6 @@
 // Caso de uso de password reset: generar token y confirmar con valores (no punteros) en el dominio.
+// En modo zero-knowledge el servidor no puede re-cifrar el vault: confirmar exige nuevos parámetros KDF
+// y borra las entradas, que quedan ilegibles sin la contraseña maestra anterior.
 package usecase
 
 import (
@@ -7,17 +9,53 @@ import (
 	"errors"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"password-danie/internal/domain"
+	"password-danie/internal/policy"
 	"password-danie/internal/repository"
+	"password-danie/internal/security"
 )
 
 type PasswordReset struct {
-	users repository.UserRepo
+	users    repository.UserRepo
+	secrets  repository.SecretRepo
+	sessions *Sessions
+	throttle *Throttle
+	audit    *Audit
+	hasher   security.PasswordHasher
+	policy   policy.Policy
 }
 
-func NewPasswordReset(users repository.UserRepo) *PasswordReset { return &PasswordReset{users: users} }
+// NewPasswordReset: throttle y audit pueden ser nil (sin límite de solicitudes o sin auditoría).
+func NewPasswordReset(users repository.UserRepo, secrets repository.SecretRepo, sessions *Sessions, throttle *Throttle, audit *Audit,
+	hasher security.PasswordHasher, pol policy.Policy) *PasswordReset {
+	return &PasswordReset{users: users, secrets: secrets, sessions: sessions, throttle: throttle, audit: audit, hasher: hasher,
+		policy: pol}
+}
+
+// ZeroKnowledgeReset acompaña a Confirm cuando la cuenta es zero-knowledge.
+type ZeroKnowledgeReset struct {
+	KDF       domain.KDFParams
+	WipeVault bool // confirmación explícita de que se pierden todas las entradas
+}
+
+// Request genera el token de reset. Cada solicitud cuenta como intento, exista o no la cuenta,
+// para que no sirva para inundar de correos ni para probar direcciones sin límite.
+func (pr *PasswordReset) Request(email, ip, userAgent string) (string, error) {
+	token, err := pr.request(email, ip)
+	if pr.audit != nil {
+		u, _ := pr.users.GetByEmail(email)
+		pr.record(domain.AuditResetRequest, u, email, ip, userAgent, err)
+	}
+	return token, err
+}
 
-func (pr *PasswordReset) Request(email string) (string, error) {
+func (pr *PasswordReset) request(email, ip string) (string, error) {
+	if err := pr.throttle.Check(ThrottleReset, email, ip); err != nil {
+		return "", err
+	}
+	if err := pr.throttle.Fail(ThrottleReset, email, ip); err != nil {
+		return "", err
+	}
 	u, err := pr.users.GetByEmail(email)
 	if err != nil {
 		return "", err
@@ -33,17 +71,96 @@ func (pr *PasswordReset) Request(email string) (string, error) {
 	return token, nil
 }
 
-func (pr *PasswordReset) Confirm(token, newPassword string) error {
+// ResetResult: VaultWiped solo aplica a cuentas zero-knowledge; Warnings son avisos de la política.
+type ResetResult struct {
+	VaultWiped int64
+	Warnings   []policy.Reason
+}
+
+// Confirm fija la nueva contraseña. Para cuentas zero-knowledge newPassword es el nuevo hash de
+// autenticación del cliente, zk es obligatorio y se devuelve cuántas entradas se borraron.
+func (pr *PasswordReset) Confirm(token, newPassword string, zk *ZeroKnowledgeReset, ip, userAgent string) (*ResetResult, error) {
+	var u *domain.User
+	if pr.audit != nil {
+		u, _ = pr.users.GetByResetToken(token) // antes de confirmar: después el token ya no existe
+	}
+	res, err := pr.confirm(token, newPassword, zk)
+	if pr.audit != nil {
+		email := ""
+		if u != nil {
+			email = u.Email
+		}
+		pr.record(domain.AuditResetConfirm, u, email, ip, userAgent, err)
+	}
+	return res, err
+}
+
+// record anota el evento; u es nil si la solicitud no corresponde a ninguna cuenta.
+func (pr *PasswordReset) record(action string, u *domain.User, email, ip, userAgent string, err error) {
+	result, detail := auditOutcome(err)
+	e := domain.AuditEvent{Actor: email, IP: ip, UserAgent: userAgent, Action: action, Result: result, Detail: detail}
+	if u != nil {
+		e.UserID, e.TargetType, e.TargetID = u.ID, "user", u.ID
+	}
+	pr.audit.Record(e)
+}
+
+func (pr *PasswordReset) confirm(token, newPassword string, zk *ZeroKnowledgeReset) (*ResetResult, error) {
 	u, err := pr.users.GetByResetToken(token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Validar existencia y expiración usando valores
 	if u == nil || u.ResetToken == "" || u.ResetExpiresAt.IsZero() || time.Now().After(u.ResetExpiresAt) {
-		return errors.New("invalid or expired token")
+		return nil, errors.New("invalid or expired token")
+	}
+	res := &ResetResult{}
+	if !u.ZeroKnowledge() {
+		if res.Warnings, err = pr.policy.Check(newPassword, u.Email); err != nil {
+			return nil, err
+		}
+		hash, err := pr.hasher.Hash(newPassword)
+		if err != nil {
+			return nil, err
+		}
+		if err := pr.users.UpdatePassword(u.ID, hash); err != nil {
+			return nil, err
+		}
+		return res, pr.revokeSessions(u.ID)
+	}
+
+	if zk == nil {
+		return nil, errors.New("zero-knowledge account: new kdf parameters required")
+	}
+	if !zk.WipeVault {
+		return nil, errors.New("zero-knowledge account: reset erases every vault entry, confirm with wipe_vault")
+	}
+	if err := validateAuthKey(newPassword); err != nil {
+		return nil, err
 	}
-	hash, _ := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	return pr.users.UpdatePassword(u.ID, string(hash))
+	if err := validateKDF(zk.KDF); err != nil {
+		return nil, err
+	}
+	if res.VaultWiped, err = pr.secrets.DeleteAll(u.ID); err != nil {
+		return nil, err
+	}
+	if err := pr.users.UpdateKDF(u.ID, zk.KDF); err != nil {
+		return res, err
+	}
+	hash, err := pr.hasher.Hash(newPassword)
+	if err != nil {
+		return res, err
+	}
+	if err := pr.users.UpdatePassword(u.ID, hash); err != nil {
+		return res, err
+	}
+	return res, pr.revokeSessions(u.ID)
+}
+
+// revokeSessions cierra todas las sesiones abiertas con la contraseña anterior.
+func (pr *PasswordReset) revokeSessions(userID int64) error {
+	_, err := pr.sessions.LogoutAll(userID)
+	return err
 }
 
 func randomToken(n int) string {
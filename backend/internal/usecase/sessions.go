@@ -0,0 +1,144 @@
+// Caso de uso de sesiones: alta en el login, comprobación en cada petición (caché en memoria + BD)
+// y revocación (logout, logout en todos los dispositivos, reset de contraseña, reutilización de refresh).
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+const (
+	defaultSessionCacheTTL  = 30 * time.Second
+	defaultSessionCacheSize = 10000
+)
+
+var errSessionRevoked = errors.New("session revoked")
+
+type Sessions struct {
+	sessions repository.SessionRepo
+	tokens   repository.RefreshTokenRepo
+	cache    *sessionCache
+}
+
+// NewSessions: cacheTTL acota cuánto tarda una instancia en ver una revocación hecha por otra
+// (las revocaciones locales se aplican al momento).
+func NewSessions(sessions repository.SessionRepo, tokens repository.RefreshTokenRepo, cacheTTL time.Duration) *Sessions {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultSessionCacheTTL
+	}
+	return &Sessions{sessions: sessions, tokens: tokens, cache: newSessionCache(cacheTTL, defaultSessionCacheSize)}
+}
+
+func (s *Sessions) Start(userID int64) (*domain.Session, error) {
+	sess := &domain.Session{ID: randomToken(16), UserID: userID}
+	if err := s.sessions.Create(sess); err != nil {
+		return nil, err
+	}
+	s.cache.put(sess.ID, false)
+	return sess, nil
+}
+
+// Check devuelve error si la sesión no existe o está revocada.
+func (s *Sessions) Check(sessionID string) error {
+	if sessionID == "" {
+		return errSessionRevoked
+	}
+	if revoked, ok := s.cache.get(sessionID); ok {
+		if revoked {
+			return errSessionRevoked
+		}
+		return nil
+	}
+	sess, err := s.sessions.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+	revoked := sess == nil || sess.Revoked()
+	s.cache.put(sessionID, revoked)
+	if revoked {
+		return errSessionRevoked
+	}
+	return nil
+}
+
+// Logout revoca una sesión del usuario (sus access tokens y su familia de refresh tokens).
+func (s *Sessions) Logout(userID int64, sessionID string) error {
+	sess, err := s.sessions.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.UserID != userID {
+		return errors.New("not found")
+	}
+	return s.revoke(sessionID)
+}
+
+// LogoutAll revoca todas las sesiones del usuario y devuelve cuántas estaban activas.
+func (s *Sessions) LogoutAll(userID int64) (int, error) {
+	return s.LogoutOthers(userID, "")
+}
+
+// LogoutOthers revoca todas las sesiones del usuario salvo keepID (p. ej. la que acaba de cambiar la contraseña).
+func (s *Sessions) LogoutOthers(userID int64, keepID string) (int, error) {
+	ids, err := s.sessions.RevokeUser(userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.cache.put(id, true)
+	}
+	return len(ids), s.tokens.RevokeUser(userID, keepID)
+}
+
+func (s *Sessions) revoke(sessionID string) error {
+	if err := s.sessions.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.cache.put(sessionID, true)
+	return s.tokens.RevokeFamily(sessionID)
+}
+
+// sessionCache recuerda el estado de las sesiones consultadas. Una revocada no vuelve a ser válida, así que
+// se recuerda sin caducidad; una activa solo durante ttl. Si se llena, se vacía y se vuelve a la BD.
+type sessionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[string]sessionState
+}
+
+type sessionState struct {
+	revoked bool
+	until   time.Time
+}
+
+func newSessionCache(ttl time.Duration, max int) *sessionCache {
+	return &sessionCache{ttl: ttl, max: max, entries: make(map[string]sessionState)}
+}
+
+func (c *sessionCache) get(id string) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.entries[id]
+	if !ok {
+		return false, false
+	}
+	if !st.revoked && time.Now().After(st.until) {
+		delete(c.entries, id)
+		return false, false
+	}
+	return st.revoked, true
+}
+
+func (c *sessionCache) put(id string, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		c.entries = make(map[string]sessionState)
+	}
+	c.entries[id] = sessionState{revoked: revoked, until: time.Now().Add(c.ttl)}
+}
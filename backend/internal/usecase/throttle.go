@@ -0,0 +1,167 @@
+// Caso de uso de protección contra fuerza bruta: contadores de fallos por cuenta y por IP con espera
+// exponencial. Pasados los fallos gratuitos, cada fallo bloquea la clave el doble que el anterior hasta
+// un máximo; un login correcto limpia el contador de la cuenta (el de la IP solo caduca).
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+// Ámbitos de los contadores: los fallos de login no cuentan para el reset y al revés.
+const (
+	ThrottleLogin = "login"
+	ThrottleReset = "reset"
+)
+
+// ThrottlePolicy fija cuántos fallos se toleran y cuánto se espera después.
+type ThrottlePolicy struct {
+	FreeAttempts int           // fallos seguidos sin espera
+	BaseDelay    time.Duration // espera tras el primer fallo de pago; se dobla en cada uno
+	MaxDelay     time.Duration // bloqueo máximo
+	Window       time.Duration // sin fallos durante este tiempo, el contador vuelve a cero
+}
+
+// DefaultAccountThrottle y DefaultIPThrottle: la IP tolera más fallos porque puede agrupar a muchos usuarios (NAT).
+func DefaultAccountThrottle() ThrottlePolicy {
+	return ThrottlePolicy{FreeAttempts: 5, BaseDelay: time.Second, MaxDelay: 15 * time.Minute, Window: time.Hour}
+}
+
+func DefaultIPThrottle() ThrottlePolicy {
+	return ThrottlePolicy{FreeAttempts: 20, BaseDelay: time.Second, MaxDelay: 15 * time.Minute, Window: time.Hour}
+}
+
+// LockedError indica que la cuenta o la IP están bloqueadas; la API responde 429 con Retry-After.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Throttle es seguro con receptor nil: sin Throttle no se limita nada.
+type Throttle struct {
+	repo    repository.AttemptRepo
+	account ThrottlePolicy
+	ip      ThrottlePolicy
+}
+
+func NewThrottle(repo repository.AttemptRepo, account, ip ThrottlePolicy) *Throttle {
+	return &Throttle{repo: repo, account: account, ip: ip}
+}
+
+// Check devuelve *LockedError si la cuenta o la IP tienen un bloqueo vigente en ese ámbito.
+func (t *Throttle) Check(scope, email, ip string) error {
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range t.keys(scope, email, ip) {
+		a, err := t.repo.Get(k.key)
+		if err != nil {
+			return err
+		}
+		if a != nil && a.Locked(now) {
+			wait = max(wait, a.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail anota un fallo para la cuenta y la IP (ip vacía = solo la cuenta) y bloquea las que pasen del límite.
+func (t *Throttle) Fail(scope, email, ip string) error {
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	for _, k := range t.keys(scope, email, ip) {
+		n, err := t.repo.Fail(k.key, now, k.policy.Window)
+		if err != nil {
+			return err
+		}
+		if d := k.policy.delay(n); d > 0 {
+			if err := t.repo.Lock(k.key, now.Add(d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Succeed limpia el contador de la cuenta. El de la IP no: una cuenta propia no debe servir para
+// resetear el contador de una IP que prueba contraseñas de otras.
+func (t *Throttle) Succeed(scope, email string) {
+	if t == nil {
+		return
+	}
+	if err := t.repo.Reset(accountKey(scope, email)); err != nil {
+		log.Printf("throttle reset %s: %v", scope, err)
+	}
+}
+
+// Unlock quita contadores y bloqueos de una cuenta y/o una IP en todos los ámbitos (uso de administración).
+func (t *Throttle) Unlock(email, ip string) error {
+	if t == nil {
+		return nil
+	}
+	for _, scope := range []string{ThrottleLogin, ThrottleReset} {
+		for _, k := range t.keys(scope, email, ip) {
+			if err := t.repo.Reset(k.key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Locked lista los bloqueos vigentes.
+func (t *Throttle) Locked() ([]domain.Attempts, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return t.repo.ListLocked(time.Now())
+}
+
+type throttleKey struct {
+	key    string
+	policy ThrottlePolicy
+}
+
+// keys devuelve las claves afectadas (cuenta y/o IP, según vengan vacías o no) con su política.
+func (t *Throttle) keys(scope, email, ip string) []throttleKey {
+	var keys []throttleKey
+	if email != "" {
+		keys = append(keys, throttleKey{accountKey(scope, email), t.account})
+	}
+	if ip != "" {
+		keys = append(keys, throttleKey{scope + ":ip:" + ip, t.ip})
+	}
+	return keys
+}
+
+func accountKey(scope, email string) string {
+	return scope + ":account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// delay es la espera tras el fallo número n: 0 dentro de los gratuitos, luego BaseDelay·2^k hasta MaxDelay.
+func (p ThrottlePolicy) delay(n int) time.Duration {
+	extra := n - p.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < extra && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
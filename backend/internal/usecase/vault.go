@@ -1,56 +1,429 @@
-// Caso de uso del vault: CRUD de secretos con cifrado AES-GCM y soporte de búsqueda/filtrado.
+// Caso de uso del vault: CRUD de secretos con cifrado por sobres (clave de datos por usuario) y búsqueda/filtrado
+// sobre índices ciegos.
+// Las cuentas zero-knowledge solo guardan blobs opacos cifrados en el cliente.
 package usecase
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
 
+	"password-danie/internal/breach"
 	"password-danie/internal/domain"
+	"password-danie/internal/generator"
+	"password-danie/internal/otp"
 	"password-danie/internal/repository"
 	"password-danie/internal/security"
 )
 
 type Vault struct {
-	secrets repository.SecretRepo
+	secrets  repository.SecretRepo
+	keys     repository.KeyRepo
+	users    repository.UserRepo
+	breaches breach.Dataset // nil = no se marcan contraseñas filtradas
+	audit    *Audit
+	cache    *dataKeyCache
+
+	attachments *Attachments // lo fija NewAttachments; nil = sin adjuntos
+	history     *History     // lo fija NewHistory; nil = sin historial de versiones
+}
+
+// NewVault: audit puede ser nil (sin registro de auditoría).
+func NewVault(secrets repository.SecretRepo, keys repository.KeyRepo, users repository.UserRepo, breaches breach.Dataset, audit *Audit) *Vault {
+	return &Vault{secrets: secrets, keys: keys, users: users, breaches: breaches, audit: audit,
+		cache: newDataKeyCache(defaultDataKeyCacheSize)}
+}
+
+var (
+	errZeroKnowledgeVault = errors.New("zero-knowledge vault: send an encrypted blob")
+	errServerVault        = errors.New("blob is only accepted in zero-knowledge mode")
+	errPasswordGenerate   = errors.New("password_plain and generate are mutually exclusive")
+)
+
+// CreateSecretInput es una entrada nueva del vault en modo servidor. Type vacío es login; Fields son los
+// campos propios del tipo (ver itemSchemas) y CustomFields los personalizados, en orden. OTPURI es una URI
+// otpauth:// con la semilla TOTP/HOTP de la cuenta ("" = sin semilla). Con Generate (y sin PasswordPlain,
+// que es un error) la contraseña se genera con esas opciones.
+type CreateSecretInput struct {
+	Type          string
+	Username      string
+	PasswordPlain string
+	URL           string
+	Notes         string
+	Icon          string
+	Title         *string
+	OTPURI        string
+	Fields        map[string]string
+	CustomFields  []CustomFieldInput
+	Generate      *generator.Options
 }
 
-func NewVault(secrets repository.SecretRepo) *Vault { return &Vault{secrets: secrets} }
+// UpdateSecretInput son los cambios de una entrada: lo que venga nil se queda como está. OTPURI vacío
+// quita la semilla y Fields se mezcla con los guardados (mergeFields). CustomFields sustituye la lista
+// de campos personalizados (nil la deja como está; ver applyCustomFields).
+type UpdateSecretInput struct {
+	Username      *string
+	PasswordPlain *string
+	URL           *string
+	Notes         *string
+	Icon          *string
+	Title         *string
+	OTPURI        *string
+	Fields        map[string]string
+	CustomFields  []CustomFieldInput
+}
 
-func (v *Vault) Create(userID int64, username, passwordPlain, url, notes, icon string, title *string) (int64, error) {
-	if username == "" {
-		return 0, errors.New("username required")
+// Create, CreateOpaque, Update, UpdateOpaque, Delete, Reveal y OTP dejan un evento de auditoría con su resultado.
+// Si Create genera la contraseña, la devuelve.
+func (v *Vault) Create(userID int64, in CreateSecretInput, ip, userAgent string) (int64, string, error) {
+	if in.Type == "" {
+		in.Type = domain.ItemLogin
+	}
+	if in.Type != domain.ItemLogin && in.Generate != nil {
+		return 0, "", errPasswordNotLogin
+	}
+	if in.PasswordPlain != "" && in.Generate != nil {
+		return 0, "", errPasswordGenerate
+	}
+	generated := ""
+	if in.Generate != nil {
+		res, err := generator.Generate(*in.Generate)
+		if err != nil {
+			return 0, "", err
+		}
+		in.PasswordPlain, generated = res.Value, res.Value
 	}
-	cipher, iv, err := security.Encrypt([]byte(passwordPlain))
+	id, err := v.create(userID, in)
+	v.record(userID, domain.AuditSecretCreate, id, ip, userAgent, err)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, generated, nil
+}
+
+func (v *Vault) CreateOpaque(userID int64, blob, ip, userAgent string) (int64, error) {
+	id, err := v.createOpaque(userID, blob)
+	v.record(userID, domain.AuditSecretCreate, id, ip, userAgent, err)
+	return id, err
+}
+
+// El tipo de una entrada no cambia en Update. Con historial, los valores anteriores quedan como versión
+// (ver History).
+func (v *Vault) Update(userID, id int64, in UpdateSecretInput, ip, userAgent string) error {
+	err := v.update(userID, id, in)
+	v.record(userID, domain.AuditSecretUpdate, id, ip, userAgent, err)
+	return err
+}
+
+func (v *Vault) UpdateOpaque(userID, id int64, blob, ip, userAgent string) error {
+	err := v.updateOpaque(userID, id, blob)
+	v.record(userID, domain.AuditSecretUpdate, id, ip, userAgent, err)
+	return err
+}
+
+// Delete manda la entrada a la papelera; Purge (o la purga automática) la borra del todo.
+func (v *Vault) Delete(userID, id int64, ip, userAgent string) error {
+	err := v.trash(userID, id)
+	v.record(userID, domain.AuditSecretDelete, id, ip, userAgent, err)
+	return err
+}
+
+// Revealed es lo que devuelve Reveal: la contraseña en los logins, los campos secretos en el resto de
+// tipos y los valores de los campos personalizados hidden por su ID.
+type Revealed struct {
+	Password     string            `json:"password,omitempty"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+}
+
+// Reveal descifra la parte secreta de una entrada y deja constancia de quién la ha leído.
+func (v *Vault) Reveal(userID, id int64, ip, userAgent string) (*Revealed, error) {
+	plain, err := v.reveal(userID, id, ip, userAgent)
+	v.record(userID, domain.AuditSecretReveal, id, ip, userAgent, err)
+	return plain, err
+}
+
+// OTP devuelve el código actual de la semilla guardada en la entrada. En HOTP cada petición gasta el
+// contador (como pulsar el botón de un token físico).
+func (v *Vault) OTP(userID, id int64, ip, userAgent string) (*OTPCode, error) {
+	code, err := v.otpCode(userID, id)
+	v.record(userID, domain.AuditSecretOTP, id, ip, userAgent, err)
+	return code, err
+}
+
+func (v *Vault) record(userID int64, action string, id int64, ip, userAgent string, err error) {
+	result, detail := auditOutcome(err)
+	v.audit.Record(domain.AuditEvent{UserID: userID, IP: ip, UserAgent: userAgent, Action: action,
+		TargetType: "secret", TargetID: id, Result: result, Detail: detail})
+}
+
+func (v *Vault) create(userID int64, in CreateSecretInput) (int64, error) {
+	if in.Type == domain.ItemLogin {
+		if in.Username == "" {
+			return 0, errors.New("username required")
+		}
+		if in.PasswordPlain == "" {
+			return 0, errors.New("password required")
+		}
+	} else if in.PasswordPlain != "" {
+		return 0, errPasswordNotLogin
+	}
+	seed, err := normalizeOTP(in.OTPURI)
 	if err != nil {
 		return 0, err
 	}
+	if err := v.requireMode(userID, domain.VaultModeServer); err != nil {
+		return 0, err
+	}
 	t := ""
-	if title != nil {
-		t = *title
+	if in.Title != nil {
+		t = *in.Title
+	}
+	plain := &domain.Secret{
+		UserID:    userID,
+		Type:      in.Type,
+		Fields:    in.Fields,
+		Username:  in.Username,
+		URL:       in.URL,
+		URLDomain: extractDomain(in.URL),
+		Notes:     in.Notes,
+		Icon:      in.Icon,
+		Title:     t,
+		Breached:  v.breached(in.PasswordPlain),
+		OTP:       seed,
+	}
+	if err := validateItem(plain); err != nil {
+		return 0, err
+	}
+	if plain.CustomFields, err = applyCustomFields(plain, in.CustomFields); err != nil {
+		return 0, err
+	}
+	// La clave se obtiene antes de la transacción: build solo cifra, no toca la base de datos.
+	dek, err := v.dataKey(userID)
+	if err != nil {
+		return 0, err
+	}
+	return v.secrets.CreateSealed(userID, func(id int64) (*domain.Secret, error) {
+		plain.ID = id
+		return v.sealWith(dek, plain, []byte(in.PasswordPlain))
+	})
+}
+
+// createOpaque guarda una entrada zero-knowledge tal cual llega: el servidor nunca la descifra.
+func (v *Vault) createOpaque(userID int64, blob string) (int64, error) {
+	if err := v.requireMode(userID, domain.VaultModeZeroKnowledge); err != nil {
+		return 0, err
 	}
-	s := &domain.Secret{
-		UserID:         userID,
-		Username:       username,
-		URL:            url,
-		URLDomain:      extractDomain(url),
-		Notes:          notes,
-		Icon:           icon,
-		Title:          t,
-		PasswordCipher: cipher,
-		PasswordIV:     iv,
+	if err := validateBlob(blob); err != nil {
+		return 0, err
 	}
-	return v.secrets.Create(s)
+	return v.secrets.Create(&domain.Secret{UserID: userID, EncScheme: domain.SchemeClient, Blob: blob})
 }
 
 func (v *Vault) Get(userID, id int64) (*domain.Secret, error) {
-	return v.secrets.GetByID(userID, id)
+	s, err := v.secrets.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if s, err = v.decoded(s); err != nil || s == nil {
+		return nil, err
+	}
+	return concealed(s), nil
+}
+
+func (v *Vault) reveal(userID, id int64, ip, userAgent string) (*Revealed, error) {
+	cur, err := v.secrets.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cur == nil {
+		return nil, errors.New("not found")
+	}
+	out := &Revealed{}
+	if cur.Type == domain.ItemLogin {
+		plain, err := v.open(cur)
+		if err != nil {
+			return nil, err
+		}
+		out.Password = string(plain)
+	}
+	if cur, err = v.decoded(cur); err != nil {
+		return nil, err
+	}
+	if cur.Type != domain.ItemLogin {
+		out.Fields = secretFields(cur)
+	}
+	out.CustomFields = hiddenCustomValues(cur)
+	if err := v.secrets.LogReveal(userID, id, ip, userAgent); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OTPCode es el código de un momento dado; Remaining (segundos hasta el siguiente) no aplica a HOTP.
+type OTPCode struct {
+	Code      string `json:"code"`
+	Type      string `json:"type"`
+	Period    int    `json:"period,omitempty"`
+	Remaining int    `json:"remaining,omitempty"`
+}
+
+func (v *Vault) otpCode(userID, id int64) (*OTPCode, error) {
+	// HOTP: si otra petición avanza el contador entre la lectura y la escritura se vuelve a leer,
+	// para no entregar dos veces el mismo código.
+	for attempt := 0; attempt < maxOTPAttempts; attempt++ {
+		code, done, err := v.nextOTP(userID, id)
+		if err != nil || done {
+			return code, err
+		}
+	}
+	return nil, errors.New("otp counter changed concurrently, retry")
+}
+
+// maxOTPAttempts acota los reintentos de otpCode cuando el contador HOTP cambia a la vez.
+const maxOTPAttempts = 3
+
+// nextOTP calcula el código de la semilla guardada. En HOTP el contador avanzado se guarda solo si la
+// semilla no cambió desde la lectura; si cambió devuelve done=false para que se reintente.
+func (v *Vault) nextOTP(userID, id int64) (*OTPCode, bool, error) {
+	stored, err := v.secrets.GetByID(userID, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if stored == nil {
+		return nil, false, errors.New("not found")
+	}
+	if stored.EncScheme == domain.SchemeClient {
+		return nil, false, errZeroKnowledgeVault
+	}
+	cur, err := v.decoded(stored)
+	if err != nil {
+		return nil, false, err
+	}
+	if cur.OTP == "" {
+		return nil, false, errors.New("entry has no otp seed")
+	}
+	k, err := otp.ParseURI(cur.OTP)
+	if err != nil {
+		return nil, false, err
+	}
+	code, remaining, err := k.Generate(time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	if k.Type != otp.TypeHOTP {
+		return &OTPCode{Code: code, Type: k.Type, Period: k.Period, Remaining: remaining}, true, nil
+	}
+	// El código solo se entrega si el contador avanzado queda guardado. Solo se reescribe la semilla,
+	// cifrada con el mismo esquema que la fila, para no pisar una edición hecha a la vez.
+	k.Counter++
+	dek, err := v.dataKey(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	next, err := security.SealString(dek, k.URI(), fieldAAD(stored, fieldOTP))
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := v.secrets.SwapOTP(userID, id, stored.OTP, next)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &OTPCode{Code: code, Type: k.Type}, true, nil
+}
+
+// normalizeOTP valida la URI otpauth:// y la reescribe con todos sus parámetros explícitos.
+func normalizeOTP(uri string) (string, error) {
+	if uri == "" {
+		return "", nil
+	}
+	k, err := otp.ParseURI(uri)
+	if err != nil {
+		return "", err
+	}
+	return k.URI(), nil
 }
 
-func (v *Vault) List(userID int64, q, domain string, limit, offset int) ([]domain.Secret, int, error) {
-	filter := repository.ListFilter{Q: q, Domain: domain, Limit: limit, Offset: offset}
-	return v.secrets.List(userID, filter)
+// List filtra por texto (q), dominio e itemType (vacío = todos los tipos).
+func (v *Vault) List(userID int64, q, urlDomain, itemType string, limit, offset int) ([]domain.Secret, int, error) {
+	if _, ok := itemSchemas[itemType]; itemType != "" && !ok {
+		return nil, 0, fmt.Errorf("unknown item type %q", itemType)
+	}
+	if q != "" || urlDomain != "" || itemType != "" {
+		// Sobre blobs opacos no hay nada que buscar: en zero-knowledge se filtra en el cliente.
+		if err := v.requireMode(userID, domain.VaultModeServer); err == errZeroKnowledgeVault {
+			return nil, 0, errors.New("search is not available in zero-knowledge mode")
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+	filter := repository.ListFilter{Q: q, Domain: urlDomain, Type: itemType, Limit: limit, Offset: offset}
+	if q != "" || urlDomain != "" {
+		var err error
+		if filter.QueryTokens, filter.DomainIndex, err = v.blindFilter(userID, q, urlDomain); err != nil {
+			return nil, 0, err
+		}
+	}
+	items, total, err := v.secrets.List(userID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		d, err := v.decoded(&items[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		items[i] = *concealed(d)
+	}
+	return items, total, nil
 }
 
-func (v *Vault) Update(userID, id int64, username, passwordPlain, url, notes, icon, title *string) error {
+func (v *Vault) update(userID, id int64, in UpdateSecretInput) error {
+	return v.change(userID, id, false, func(cur *domain.Secret) (*string, error) {
+		var err error
+		if in.Username != nil {
+			cur.Username = *in.Username
+		}
+		if in.URL != nil {
+			cur.URL = *in.URL
+			cur.URLDomain = extractDomain(cur.URL)
+		}
+		if in.Notes != nil {
+			cur.Notes = *in.Notes
+		}
+		if in.Icon != nil {
+			cur.Icon = *in.Icon
+		}
+		if in.Title != nil {
+			cur.Title = *in.Title
+		}
+		if in.OTPURI != nil {
+			if cur.OTP, err = normalizeOTP(*in.OTPURI); err != nil {
+				return nil, err
+			}
+		}
+		if in.Fields != nil {
+			cur.Fields = mergeFields(cur.Fields, in.Fields)
+		}
+		if err := validateItem(cur); err != nil {
+			return nil, err
+		}
+		if in.CustomFields != nil {
+			if cur.CustomFields, err = applyCustomFields(cur, in.CustomFields); err != nil {
+				return nil, err
+			}
+		}
+		return in.PasswordPlain, nil
+	})
+}
+
+// change aplica mutate sobre la entrada descifrada y la guarda junto con la versión anterior, si hay
+// historial y algo que guardar (all guarda todos los campos que cambian). mutate devuelve la contraseña
+// nueva o nil si no cambia.
+func (v *Vault) change(userID, id int64, all bool, mutate func(cur *domain.Secret) (*string, error)) error {
 	// Fetch, mutate, then persist
 	cur, err := v.secrets.GetByID(userID, id)
 	if err != nil {
@@ -59,33 +432,195 @@ func (v *Vault) Update(userID, id int64, username, passwordPlain, url, notes, ic
 	if cur == nil {
 		return errors.New("not found")
 	}
-	if username != nil {
-		cur.Username = *username
+	if cur.EncScheme == domain.SchemeClient {
+		return errZeroKnowledgeVault
 	}
-	if url != nil {
-		cur.URL = *url
-		cur.URLDomain = extractDomain(cur.URL)
+	if cur, err = v.decoded(cur); err != nil {
+		return err
 	}
-	if notes != nil {
-		cur.Notes = *notes
+	prev := *cur
+	prev.Fields = maps.Clone(cur.Fields)
+	prev.CustomFields = slices.Clone(cur.CustomFields)
+	passwordPlain, err := mutate(cur)
+	if err != nil {
+		return err
 	}
-	if icon != nil {
-		cur.Icon = *icon
+	var password, prevPassword []byte
+	if passwordPlain != nil {
+		if cur.Type != domain.ItemLogin {
+			return errPasswordNotLogin
+		}
+		password = []byte(*passwordPlain)
+		cur.Breached = v.breached(*passwordPlain)
+		if v.history != nil {
+			if prevPassword, err = v.open(&prev); err != nil {
+				return err
+			}
+		}
 	}
-	if title != nil {
-		cur.Title = *title
+	sealed, err := v.sealed(cur, password)
+	if err != nil {
+		return err
 	}
-	if passwordPlain != nil {
-		c, iv, err := security.Encrypt([]byte(*passwordPlain))
+	dek, err := v.dataKey(userID)
+	if err != nil {
+		return err
+	}
+	rev, err := v.history.revision(dek, &prev, cur, prevPassword, password, all)
+	if err != nil {
+		return err
+	}
+	if err := v.secrets.UpdateWithRevision(sealed, rev); err != nil {
+		return err
+	}
+	if rev != nil {
+		v.history.pruneAfterUpdate(userID, id)
+	}
+	return nil
+}
+
+// updateOpaque sustituye el blob de una entrada zero-knowledge.
+func (v *Vault) updateOpaque(userID, id int64, blob string) error {
+	cur, err := v.secrets.GetByID(userID, id)
+	if err != nil {
+		return err
+	}
+	if cur == nil {
+		return errors.New("not found")
+	}
+	if cur.EncScheme != domain.SchemeClient {
+		return errServerVault
+	}
+	if err := validateBlob(blob); err != nil {
+		return err
+	}
+	cur.Blob = blob
+	return v.secrets.Update(cur)
+}
+
+// UpgradeLegacy migra por lotes las entradas antiguas (contraseña con la clave maestra, campos en
+// claro o cifrados sin ligar a su fila) a SchemeBound. Es idempotente y no pisa entradas que el
+// usuario edite a la vez.
+func (v *Vault) UpgradeLegacy(batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	var (
+		after    int64
+		upgraded int
+	)
+	for {
+		batch, err := v.secrets.ListByScheme([]int{domain.SchemeMasterKey, domain.SchemeDataKey, domain.SchemeSealed}, after, batchSize)
 		if err != nil {
-			return err
+			return upgraded, err
+		}
+		if len(batch) == 0 {
+			return upgraded, nil
+		}
+		for i := range batch {
+			after = batch[i].ID
+			prev := batch[i].EncScheme
+			cur, err := v.decoded(&batch[i])
+			if err != nil {
+				return upgraded, err
+			}
+			sealed, err := v.sealed(cur, nil)
+			if err != nil {
+				return upgraded, err
+			}
+			ok, err := v.secrets.UpgradeScheme(sealed, prev)
+			if err != nil {
+				return upgraded, err
+			}
+			if ok {
+				upgraded++
+			}
 		}
-		cur.PasswordCipher = c
-		cur.PasswordIV = iv
 	}
-	return v.secrets.Update(cur)
 }
 
-func (v *Vault) Delete(userID, id int64) error {
-	return v.secrets.Delete(userID, id)
+// breached consulta el volcado de filtraciones. En el vault solo se marca la entrada: un fallo de
+// lectura se registra y no impide guardarla.
+func (v *Vault) breached(password string) bool {
+	if v.breaches == nil {
+		return false
+	}
+	n, err := v.breaches.Count(password)
+	if err != nil {
+		log.Printf("breach lookup: %v", err)
+		return false
+	}
+	return n > 0
+}
+
+// requireMode comprueba que la cuenta use el modo de vault que espera la operación.
+func (v *Vault) requireMode(userID int64, mode string) error {
+	u, err := v.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errors.New("user not found")
+	}
+	if u.VaultMode == mode {
+		return nil
+	}
+	if u.ZeroKnowledge() {
+		return errZeroKnowledgeVault
+	}
+	return errServerVault
+}
+
+// --- cifrado por sobres ---
+
+// dataKey devuelve la clave de datos del usuario, creándola y envolviéndola la primera vez.
+func (v *Vault) dataKey(userID int64) ([]byte, error) {
+	if k, ok := v.cache.get(userID); ok {
+		return k, nil
+	}
+	uk, err := v.keys.GetUserKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	if uk == nil {
+		fresh, err := security.NewDataKey()
+		if err != nil {
+			return nil, err
+		}
+		wrapped, iv, version, err := security.WrapKey(fresh)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.keys.CreateUserKey(&domain.UserKey{UserID: userID, WrappedKey: wrapped, WrappedIV: iv, KeyVersion: version}); err != nil {
+			return nil, err
+		}
+		// Releer: si otra petición creó la clave a la vez, gana la que quedó guardada.
+		if uk, err = v.keys.GetUserKey(userID); err != nil {
+			return nil, err
+		}
+		if uk == nil {
+			return nil, errors.New("data key not stored")
+		}
+	}
+	k, err := security.UnwrapKey(uk.KeyVersion, uk.WrappedKey, uk.WrappedIV)
+	if err != nil {
+		return nil, err
+	}
+	v.cache.put(userID, k)
+	return k, nil
+}
+
+// open descifra la contraseña según el esquema con el que se guardó.
+func (v *Vault) open(s *domain.Secret) ([]byte, error) {
+	switch s.EncScheme {
+	case domain.SchemeMasterKey:
+		return security.Decrypt(s.KeyVersion, s.PasswordCipher, s.PasswordIV)
+	case domain.SchemeClient:
+		return nil, errors.New("zero-knowledge entry: decrypt it on the client")
+	}
+	k, err := v.dataKey(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return security.DecryptWithKey(k, s.PasswordCipher, s.PasswordIV, fieldAAD(s, fieldPassword))
 }
@@ -0,0 +1,143 @@
+// Campos personalizados de las entradas: lista ordenada que se sustituye entera en cada cambio. Cada
+// campo conserva su ID, así que reordenar o renombrar no obliga a reenviar los valores ocultos.
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"password-danie/internal/domain"
+)
+
+const (
+	maxCustomFields    = 50
+	maxCustomNameLen   = 100
+	maxCustomValueLen  = 10000
+	customFieldIDBytes = 8
+)
+
+// CustomFieldInput es un campo personalizado tal como llega en la petición. ID vacío es un campo nuevo;
+// con ID, lo que venga vacío (Name, Kind) o nil (Value) conserva lo guardado: así se reordena sin
+// reenviar los valores hidden, que el cliente no ve.
+type CustomFieldInput struct {
+	ID    string
+	Name  string
+	Kind  string
+	Value *string
+}
+
+// applyCustomFields construye la nueva lista a partir de la actual: los campos se quedan en el orden de
+// in, los IDs que no aparecen se borran y los que no existen son un error.
+func applyCustomFields(s *domain.Secret, in []CustomFieldInput) ([]domain.CustomField, error) {
+	if len(in) > maxCustomFields {
+		return nil, fmt.Errorf("at most %d custom fields per entry", maxCustomFields)
+	}
+	cur := map[string]domain.CustomField{}
+	for _, f := range s.CustomFields {
+		cur[f.ID] = f
+	}
+	seen := map[string]bool{}
+	out := make([]domain.CustomField, 0, len(in))
+	for i, f := range in {
+		field := domain.CustomField{ID: f.ID, Name: strings.TrimSpace(f.Name), Kind: f.Kind}
+		if f.ID == "" {
+			field.ID = randomToken(customFieldIDBytes)
+		} else {
+			prev, ok := cur[f.ID]
+			if !ok {
+				return nil, fmt.Errorf("custom field %d: unknown id %q", i, f.ID)
+			}
+			if seen[f.ID] {
+				return nil, fmt.Errorf("custom field %d: duplicated id %q", i, f.ID)
+			}
+			field.Value = prev.Value
+			if field.Name == "" {
+				field.Name = prev.Name
+			}
+			if field.Kind == "" {
+				field.Kind = prev.Kind
+			}
+		}
+		seen[field.ID] = true
+		if field.Kind == "" {
+			field.Kind = domain.CustomText
+		}
+		if f.Value != nil {
+			field.Value = *f.Value
+		}
+		if err := checkCustomField(s, &field); err != nil {
+			return nil, fmt.Errorf("custom field %d: %w", i, err)
+		}
+		out = append(out, field)
+	}
+	return out, nil
+}
+
+// checkCustomField valida nombre y valor según el tipo de campo y normaliza los booleanos.
+func checkCustomField(s *domain.Secret, f *domain.CustomField) error {
+	if f.Name == "" {
+		return errors.New("name required")
+	}
+	if len(f.Name) > maxCustomNameLen {
+		return fmt.Errorf("name longer than %d characters", maxCustomNameLen)
+	}
+	if len(f.Value) > maxCustomValueLen {
+		return fmt.Errorf("value longer than %d characters", maxCustomValueLen)
+	}
+	switch f.Kind {
+	case domain.CustomText, domain.CustomHidden:
+	case domain.CustomBoolean:
+		if f.Value == "" {
+			f.Value = "false"
+		}
+		b, err := strconv.ParseBool(f.Value)
+		if err != nil {
+			return errors.New("boolean value must be true or false")
+		}
+		f.Value = strconv.FormatBool(b)
+	case domain.CustomLinked:
+		if !linkTargets(s.Type)[f.Value] {
+			return fmt.Errorf("linked field %q does not exist in %s items", f.Value, s.Type)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", f.Kind)
+	}
+	return nil
+}
+
+// linkTargets son los campos de la entrada a los que puede apuntar un campo linked.
+func linkTargets(itemType string) map[string]bool {
+	out := map[string]bool{"username": true, "url": true, "notes": true, "title": true}
+	if itemType == domain.ItemLogin {
+		out["password"] = true
+	}
+	for name := range itemSchemas[itemType].fields {
+		out[name] = true
+	}
+	return out
+}
+
+// hiddenCustomValues son los valores de los campos hidden por ID (lo que añade Reveal).
+func hiddenCustomValues(s *domain.Secret) map[string]string {
+	out := map[string]string{}
+	for _, f := range s.CustomFields {
+		if f.Kind == domain.CustomHidden && f.Value != "" {
+			out[f.ID] = f.Value
+		}
+	}
+	return out
+}
+
+// customSearchValues: los nombres de todos los campos y los valores de texto entran en la búsqueda.
+func customSearchValues(s *domain.Secret) []string {
+	var out []string
+	for _, f := range s.CustomFields {
+		out = append(out, f.Name)
+		if f.Kind == domain.CustomText {
+			out = append(out, f.Value)
+		}
+	}
+	return out
+}
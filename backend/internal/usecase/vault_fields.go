@@ -0,0 +1,253 @@
+// Cifrado de los campos sensibles de un Secret e índices ciegos para filtrar por dominio y buscar por tokens.
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/security"
+)
+
+const (
+	blindIndexPurpose = "vault/blind-index"
+	minSearchPrefix   = 3  // "git" encuentra "github": se indexan prefijos desde 3 caracteres
+	maxSearchPrefix   = 32 // tokens más largos se indexan/consultan truncados
+)
+
+// Nombres de campo que entran en los datos asociados de cada cifrado.
+const (
+	fieldPassword = "password"
+	fieldUsername = "username"
+	fieldURL      = "url"
+	fieldNotes    = "notes"
+	fieldIcon     = "icon"
+	fieldTitle    = "title"
+	fieldOTP      = "otp"
+	fieldPayload  = "payload"
+	fieldCustom   = "custom_fields"
+)
+
+// fieldAAD liga un cifrado a su dueño, su entrada y su campo: copiado a otra fila, a otro usuario o
+// a otra columna deja de descifrar. Los esquemas anteriores a SchemeBound no usan datos asociados.
+func fieldAAD(s *domain.Secret, field string) []byte {
+	if s.EncScheme != domain.SchemeBound {
+		return nil
+	}
+	return []byte(fmt.Sprintf("password-danie/secret/v1|user=%d|secret=%d|field=%s", s.UserID, s.ID, field))
+}
+
+// sensitiveFields enumera los campos de texto que se guardan cifrados.
+func sensitiveFields(s *domain.Secret) map[string]*string {
+	return map[string]*string{
+		fieldUsername: &s.Username,
+		fieldURL:      &s.URL,
+		fieldNotes:    &s.Notes,
+		fieldIcon:     &s.Icon,
+		fieldTitle:    &s.Title,
+	}
+}
+
+// sealed devuelve la fila lista para guardar en SchemeBound a partir de plain (campos en claro e id
+// asignado). Si password es nil se conserva la contraseña actual, re-cifrándola si hace falta.
+func (v *Vault) sealed(plain *domain.Secret, password []byte) (*domain.Secret, error) {
+	if password == nil && plain.EncScheme != domain.SchemeBound {
+		var err error
+		if password, err = v.open(plain); err != nil {
+			return nil, err
+		}
+	}
+	dek, err := v.dataKey(plain.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return v.sealWith(dek, plain, password)
+}
+
+// sealWith hace el trabajo de sealed con la clave de datos ya resuelta (sin acceso a base de datos).
+func (v *Vault) sealWith(dek []byte, plain *domain.Secret, password []byte) (*domain.Secret, error) {
+	idx, err := security.DeriveKey(dek, blindIndexPurpose)
+	if err != nil {
+		return nil, err
+	}
+	out := *plain
+	out.EncScheme = domain.SchemeBound
+	out.KeyVersion = 0
+	if password != nil {
+		if out.PasswordCipher, out.PasswordIV, err = security.EncryptWithKey(dek, password, fieldAAD(&out, fieldPassword)); err != nil {
+			return nil, err
+		}
+	}
+	for name, f := range sensitiveFields(&out) {
+		if *f, err = security.SealString(dek, *f, fieldAAD(&out, name)); err != nil {
+			return nil, err
+		}
+	}
+	// La semilla OTP es opcional: vacía se guarda vacía (las filas anteriores a la columna no la tienen).
+	if out.OTP != "" {
+		if out.OTP, err = security.SealString(dek, out.OTP, fieldAAD(&out, fieldOTP)); err != nil {
+			return nil, err
+		}
+	}
+	// Los campos del tipo y los personalizados van cada uno en un JSON cifrado; sin campos, vacío.
+	if out.Payload, err = sealJSON(dek, &out, fieldPayload, plain.Fields, len(plain.Fields)); err != nil {
+		return nil, err
+	}
+	if out.CustomPayload, err = sealJSON(dek, &out, fieldCustom, plain.CustomFields, len(plain.CustomFields)); err != nil {
+		return nil, err
+	}
+	out.Fields = nil
+	out.CustomFields = nil
+	out.HasOTP = false
+	out.URLDomain = ""
+	out.DomainIndex = ""
+	if plain.URLDomain != "" {
+		out.DomainIndex = security.BlindIndex(idx, "domain", plain.URLDomain)
+	}
+	out.SearchTokens = nil
+	for _, t := range indexTokens(searchValues(plain)...) {
+		out.SearchTokens = append(out.SearchTokens, security.BlindIndex(idx, "token", t))
+	}
+	return &out, nil
+}
+
+// decoded devuelve la entrada con los campos en claro; las filas sin campos cifrados salen tal cual.
+// La contraseña y el esquema no cambian, así que open sigue funcionando sobre el resultado.
+func (v *Vault) decoded(s *domain.Secret) (*domain.Secret, error) {
+	if s == nil || (s.EncScheme != domain.SchemeSealed && s.EncScheme != domain.SchemeBound) {
+		return s, nil
+	}
+	dek, err := v.dataKey(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	out := *s
+	for name, f := range sensitiveFields(&out) {
+		if *f, err = security.OpenString(dek, *f, fieldAAD(s, name)); err != nil {
+			return nil, err
+		}
+	}
+	if out.OTP != "" {
+		if out.OTP, err = security.OpenString(dek, out.OTP, fieldAAD(s, fieldOTP)); err != nil {
+			return nil, err
+		}
+	}
+	out.HasOTP = out.OTP != ""
+	out.Fields = nil
+	if err := openJSON(dek, s, fieldPayload, out.Payload, &out.Fields); err != nil {
+		return nil, err
+	}
+	out.CustomFields = nil
+	if err := openJSON(dek, s, fieldCustom, out.CustomPayload, &out.CustomFields); err != nil {
+		return nil, err
+	}
+	out.URLDomain = extractDomain(out.URL)
+	out.DomainIndex = ""
+	out.SearchTokens = nil
+	return &out, nil
+}
+
+// sealJSON sella v como JSON en el campo field; con n == 0 no hay nada que guardar y devuelve "".
+func sealJSON(dek []byte, s *domain.Secret, field string, v any, n int) (string, error) {
+	if n == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return security.SealString(dek, string(raw), fieldAAD(s, field))
+}
+
+// openJSON es la inversa de sealJSON; sealed vacío deja v como está.
+func openJSON(dek []byte, s *domain.Secret, field, sealed string, v any) error {
+	if sealed == "" {
+		return nil
+	}
+	raw, err := security.OpenString(dek, sealed, fieldAAD(s, field))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(raw), v)
+}
+
+// searchValues son los textos que se indexan: los campos de siempre más los no secretos del tipo y los
+// personalizados de texto.
+func searchValues(s *domain.Secret) []string {
+	out := []string{s.Username, s.URL, s.Notes, s.Title}
+	out = append(out, publicValues(s)...)
+	return append(out, customSearchValues(s)...)
+}
+
+// blindFilter calcula los índices ciegos de la consulta q y del dominio para el usuario.
+func (v *Vault) blindFilter(userID int64, q, urlDomain string) (tokens []string, domainIdx string, err error) {
+	dek, err := v.dataKey(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	idx, err := security.DeriveKey(dek, blindIndexPurpose)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, t := range queryTokens(q) {
+		tokens = append(tokens, security.BlindIndex(idx, "token", t))
+	}
+	if d := strings.ToLower(strings.TrimSpace(urlDomain)); d != "" {
+		domainIdx = security.BlindIndex(idx, "domain", d)
+	}
+	return tokens, domainIdx, nil
+}
+
+// splitTokens separa en palabras alfanuméricas en minúsculas.
+func splitTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// indexTokens genera los tokens a indexar: cada palabra y sus prefijos desde minSearchPrefix.
+func indexTokens(fields ...string) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	for _, f := range fields {
+		for _, w := range splitTokens(f) {
+			r := []rune(w)
+			if len(r) > maxSearchPrefix {
+				r = r[:maxSearchPrefix]
+			}
+			if len(r) < minSearchPrefix {
+				add(string(r))
+				continue
+			}
+			for n := minSearchPrefix; n <= len(r); n++ {
+				add(string(r[:n]))
+			}
+		}
+	}
+	return out
+}
+
+// queryTokens normaliza la consulta igual que indexTokens, pero sin expandir prefijos.
+func queryTokens(q string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, w := range splitTokens(q) {
+		r := []rune(w)
+		if len(r) > maxSearchPrefix {
+			r = r[:maxSearchPrefix]
+		}
+		if t := string(r); !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
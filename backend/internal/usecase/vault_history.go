@@ -0,0 +1,300 @@
+// Historial de versiones de las entradas del vault: antes de cada cambio se guardan, sellados con la clave
+// de datos, los valores anteriores de los campos que cambian. Las versiones se listan, se revelan y se
+// restauran como valor actual; cuántas se conservan lo decide HistoryPolicy.
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/security"
+)
+
+// HistoryPolicy: sin AllFields solo dejan versión los cambios de contraseña, semilla OTP, campos secretos
+// del tipo y campos personalizados hidden.
+type HistoryPolicy struct {
+	MaxRevisions int           // por entrada; 0 = sin límite
+	MaxAge       time.Duration // 0 = sin límite
+	AllFields    bool          // también usuario, URL, notas, icono, título y el resto de campos
+}
+
+func DefaultHistoryPolicy() HistoryPolicy {
+	return HistoryPolicy{MaxRevisions: 20}
+}
+
+type History struct {
+	vault  *Vault
+	policy HistoryPolicy
+}
+
+// NewHistory se engancha al vault: a partir de ahí cada Update guarda la versión anterior.
+func NewHistory(vault *Vault, policy HistoryPolicy) *History {
+	h := &History{vault: vault, policy: policy}
+	vault.history = h
+	return h
+}
+
+// RevisionValues son los valores de antes del cambio, solo de los campos que cambiaron (nil = no cambió).
+// En Fields, "" indica que el campo no existía.
+type RevisionValues struct {
+	Password     *string               `json:"password,omitempty"`
+	Username     *string               `json:"username,omitempty"`
+	URL          *string               `json:"url,omitempty"`
+	Notes        *string               `json:"notes,omitempty"`
+	Icon         *string               `json:"icon,omitempty"`
+	Title        *string               `json:"title,omitempty"`
+	OTPURI       *string               `json:"otp_uri,omitempty"`
+	Fields       map[string]string     `json:"fields,omitempty"`
+	CustomFields *[]domain.CustomField `json:"custom_fields,omitempty"`
+}
+
+// Revision es lo que devuelve Reveal: los metadatos de la versión y sus valores.
+type Revision struct {
+	domain.SecretRevision
+	RevisionValues
+}
+
+// List devuelve las versiones de la entrada, de la más reciente a la más antigua, sin valores.
+func (h *History) List(userID, secretID int64) ([]domain.SecretRevision, error) {
+	if err := h.entry(userID, secretID); err != nil {
+		return nil, err
+	}
+	if err := h.prune(userID, secretID); err != nil {
+		return nil, err
+	}
+	revs, err := h.vault.secrets.ListRevisions(userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := h.vault.dataKey(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revs {
+		vals, err := openRevision(dek, &revs[i])
+		if err != nil {
+			return nil, err
+		}
+		revs[i].Changed = vals.changed()
+	}
+	return revs, nil
+}
+
+// Reveal descifra una versión; como el revelado de la entrada, queda auditado y en secret_reveals.
+func (h *History) Reveal(userID, secretID, id int64, ip, userAgent string) (*Revision, error) {
+	rev, err := h.reveal(userID, secretID, id, ip, userAgent)
+	h.vault.record(userID, domain.AuditHistoryReveal, secretID, ip, userAgent, err)
+	return rev, err
+}
+
+// Restore vuelve a poner los valores de la versión como actuales. Lo que se sobrescribe queda a su vez
+// como versión nueva (con todos los campos, sea cual sea AllFields), así que restaurar se puede deshacer.
+func (h *History) Restore(userID, secretID, id int64, ip, userAgent string) error {
+	err := h.restore(userID, secretID, id)
+	h.vault.record(userID, domain.AuditHistoryRestore, secretID, ip, userAgent, err)
+	return err
+}
+
+func (h *History) reveal(userID, secretID, id int64, ip, userAgent string) (*Revision, error) {
+	rev, vals, err := h.get(userID, secretID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.vault.secrets.LogReveal(userID, secretID, ip, userAgent); err != nil {
+		return nil, err
+	}
+	return &Revision{SecretRevision: *rev, RevisionValues: *vals}, nil
+}
+
+func (h *History) restore(userID, secretID, id int64) error {
+	_, vals, err := h.get(userID, secretID, id)
+	if err != nil {
+		return err
+	}
+	return h.vault.change(userID, secretID, true, func(cur *domain.Secret) (*string, error) {
+		set := func(dst *string, v *string) {
+			if v != nil {
+				*dst = *v
+			}
+		}
+		set(&cur.Username, vals.Username)
+		set(&cur.Notes, vals.Notes)
+		set(&cur.Icon, vals.Icon)
+		set(&cur.Title, vals.Title)
+		set(&cur.OTP, vals.OTPURI)
+		if vals.URL != nil {
+			cur.URL = *vals.URL
+			cur.URLDomain = extractDomain(cur.URL)
+		}
+		if vals.Fields != nil {
+			cur.Fields = mergeFields(cur.Fields, vals.Fields)
+		}
+		if vals.CustomFields != nil {
+			cur.CustomFields = *vals.CustomFields
+		}
+		if err := validateItem(cur); err != nil {
+			return nil, err
+		}
+		return vals.Password, nil
+	})
+}
+
+// get devuelve la versión (si no ha caducado) con sus valores descifrados.
+func (h *History) get(userID, secretID, id int64) (*domain.SecretRevision, *RevisionValues, error) {
+	if err := h.entry(userID, secretID); err != nil {
+		return nil, nil, err
+	}
+	if err := h.prune(userID, secretID); err != nil {
+		return nil, nil, err
+	}
+	rev, err := h.vault.secrets.GetRevision(userID, secretID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rev == nil {
+		return nil, nil, errors.New("not found")
+	}
+	dek, err := h.vault.dataKey(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	vals, err := openRevision(dek, rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	rev.Changed = vals.changed()
+	return rev, vals, nil
+}
+
+// entry comprueba que la entrada exista, sea del usuario y no sea zero-knowledge (no tiene historial).
+func (h *History) entry(userID, secretID int64) error {
+	s, err := h.vault.secrets.GetByID(userID, secretID)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return errors.New("not found")
+	}
+	if s.EncScheme == domain.SchemeClient {
+		return errZeroKnowledgeVault
+	}
+	return nil
+}
+
+// prune aplica la retención a la entrada. Sin History no hay nada que podar.
+func (h *History) prune(userID, secretID int64) error {
+	if h == nil {
+		return nil
+	}
+	var before time.Time
+	if h.policy.MaxAge > 0 {
+		before = time.Now().Add(-h.policy.MaxAge)
+	}
+	return h.vault.secrets.PruneRevisions(userID, secretID, h.policy.MaxRevisions, before)
+}
+
+// revision compara la entrada antes y después del cambio y devuelve la versión sellada con los valores
+// anteriores, o nil si no hay nada que guardar (o no hay History). all fuerza a guardar todos los campos.
+func (h *History) revision(dek []byte, prev, next *domain.Secret, prevPassword, nextPassword []byte, all bool) (*domain.SecretRevision, error) {
+	if h == nil {
+		return nil, nil
+	}
+	all = all || h.policy.AllFields
+	vals := &RevisionValues{}
+	if nextPassword != nil && !bytes.Equal(prevPassword, nextPassword) {
+		p := string(prevPassword)
+		vals.Password = &p
+	}
+	track := func(dst **string, old, cur string, tracked bool) {
+		if tracked && old != cur {
+			*dst = &old
+		}
+	}
+	track(&vals.Username, prev.Username, next.Username, all)
+	track(&vals.URL, prev.URL, next.URL, all)
+	track(&vals.Notes, prev.Notes, next.Notes, all)
+	track(&vals.Icon, prev.Icon, next.Icon, all)
+	track(&vals.Title, prev.Title, next.Title, all)
+	track(&vals.OTPURI, prev.OTP, next.OTP, true)
+	schema := itemSchemas[prev.Type].fields
+	for _, fields := range []map[string]string{prev.Fields, next.Fields} {
+		for k := range fields {
+			f := schema[k]
+			if f.derived || !(all || f.secret) || prev.Fields[k] == next.Fields[k] {
+				continue
+			}
+			if vals.Fields == nil {
+				vals.Fields = map[string]string{}
+			}
+			vals.Fields[k] = prev.Fields[k]
+		}
+	}
+	if !slices.Equal(prev.CustomFields, next.CustomFields) && (all || !maps.Equal(hiddenCustomValues(prev), hiddenCustomValues(next))) {
+		// Lista vacía y no nil: así "sin campos" también se restaura.
+		old := append([]domain.CustomField{}, prev.CustomFields...)
+		vals.CustomFields = &old
+	}
+	if len(vals.changed()) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(vals)
+	if err != nil {
+		return nil, err
+	}
+	rev := &domain.SecretRevision{UserID: next.UserID, SecretID: next.ID}
+	if rev.Payload, err = security.SealString(dek, string(raw), historyAAD(rev)); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// changed enumera los campos de la versión; los del tipo van como "fields.<nombre>".
+func (r *RevisionValues) changed() []string {
+	out := []string{}
+	for name, v := range map[string]*string{fieldPassword: r.Password, fieldUsername: r.Username, fieldURL: r.URL,
+		fieldNotes: r.Notes, fieldIcon: r.Icon, fieldTitle: r.Title, "otp_uri": r.OTPURI} {
+		if v != nil {
+			out = append(out, name)
+		}
+	}
+	for k := range r.Fields {
+		out = append(out, "fields."+k)
+	}
+	if r.CustomFields != nil {
+		out = append(out, fieldCustom)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// historyAAD liga cada versión a su dueño y su entrada.
+func historyAAD(rev *domain.SecretRevision) []byte {
+	return []byte(fmt.Sprintf("password-danie/secret-history/v1|user=%d|secret=%d", rev.UserID, rev.SecretID))
+}
+
+func openRevision(dek []byte, rev *domain.SecretRevision) (*RevisionValues, error) {
+	raw, err := security.OpenString(dek, rev.Payload, historyAAD(rev))
+	if err != nil {
+		return nil, err
+	}
+	var vals RevisionValues
+	if err := json.Unmarshal([]byte(raw), &vals); err != nil {
+		return nil, err
+	}
+	return &vals, nil
+}
+
+// pruneAfterUpdate: la entrada ya está guardada, así que un fallo al podar solo se registra.
+func (h *History) pruneAfterUpdate(userID, secretID int64) {
+	if err := h.prune(userID, secretID); err != nil {
+		log.Printf("secret %d history: %v", secretID, err)
+	}
+}
@@ -0,0 +1,288 @@
+// Esquemas de los tipos de entrada del vault: qué campos admite cada tipo, cuáles son obligatorios, cuáles
+// son secretos (solo salen al revelar) y cómo se validan y normalizan.
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"password-danie/internal/domain"
+)
+
+type itemField struct {
+	required bool
+	secret   bool                         // no sale en la vista de la entrada, solo al revelar
+	derived  bool                         // lo calcula el servidor (finish); no se puede enviar
+	check    func(string) (string, error) // normaliza y valida; nil = texto libre
+}
+
+type itemSchema struct {
+	fields map[string]itemField
+	finish func(fields map[string]string) error // validación entre campos y campos derivados
+}
+
+// itemSchemas: login y note no tienen campos propios (usan usuario, contraseña, URL y notas).
+var itemSchemas = map[string]itemSchema{
+	domain.ItemLogin: {},
+	domain.ItemNote:  {},
+	domain.ItemCard: {fields: map[string]itemField{
+		"cardholder": {},
+		"brand":      {},
+		"number":     {required: true, secret: true, check: checkCardNumber},
+		"last4":      {derived: true},
+		"exp_month":  {check: checkMonth},
+		"exp_year":   {check: checkYear},
+		"cvv":        {secret: true, check: checkDigits(3, 4)},
+	}, finish: finishCard},
+	domain.ItemIdentity: {fields: map[string]itemField{
+		"full_name":       {required: true},
+		"email":           {check: checkEmail},
+		"phone":           {},
+		"address":         {},
+		"city":            {},
+		"postal_code":     {},
+		"country":         {},
+		"birth_date":      {check: checkDate},
+		"document_number": {secret: true},
+	}},
+	domain.ItemSSHKey: {fields: map[string]itemField{
+		"private_key": {required: true, secret: true},
+		"passphrase":  {secret: true},
+		"public_key":  {},
+		"fingerprint": {derived: true},
+	}, finish: finishSSHKey},
+	domain.ItemAPIKey: {fields: map[string]itemField{
+		"key":        {required: true, secret: true},
+		"secret":     {secret: true},
+		"key_id":     {},
+		"expires_at": {check: checkDate},
+	}},
+}
+
+var errPasswordNotLogin = errors.New("password_plain is only accepted for login items")
+
+// validateItem comprueba la entrada contra el esquema de su tipo. Normaliza s.Fields en el sitio: quita
+// los vacíos, aplica check y rellena los derivados.
+func validateItem(s *domain.Secret) error {
+	schema, ok := itemSchemas[s.Type]
+	if !ok {
+		return fmt.Errorf("unknown item type %q", s.Type)
+	}
+	if s.Type == domain.ItemNote && strings.TrimSpace(s.Notes) == "" {
+		return errors.New("notes required")
+	}
+	out := map[string]string{}
+	for name, value := range s.Fields {
+		f, ok := schema.fields[name]
+		if !ok {
+			return fmt.Errorf("unknown field %q for %s items", name, s.Type)
+		}
+		if f.derived {
+			continue // se recalcula abajo
+		}
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		if f.check != nil {
+			var err error
+			if value, err = f.check(value); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+		out[name] = value
+	}
+	for name, f := range schema.fields {
+		if f.required && out[name] == "" {
+			return fmt.Errorf("field %s required for %s items", name, s.Type)
+		}
+	}
+	if schema.finish != nil {
+		if err := schema.finish(out); err != nil {
+			return err
+		}
+	}
+	s.Fields = nil
+	if len(out) > 0 {
+		s.Fields = out
+	}
+	return nil
+}
+
+// mergeFields aplica un cambio parcial: cada clave enviada sustituye a la guardada y "" la borra.
+func mergeFields(cur, changes map[string]string) map[string]string {
+	out := make(map[string]string, len(cur)+len(changes))
+	for k, v := range cur {
+		out[k] = v
+	}
+	for k, v := range changes {
+		if v == "" {
+			delete(out, k)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// concealed devuelve la entrada sin los campos secretos de su tipo ni los valores de los campos
+// personalizados hidden (la vista normal).
+func concealed(s *domain.Secret) *domain.Secret {
+	out := *s
+	if len(s.Fields) > 0 {
+		out.Fields = map[string]string{}
+		for k, v := range s.Fields {
+			if !itemSchemas[s.Type].fields[k].secret {
+				out.Fields[k] = v
+			}
+		}
+	}
+	if len(s.CustomFields) > 0 {
+		out.CustomFields = make([]domain.CustomField, len(s.CustomFields))
+		for i, f := range s.CustomFields {
+			if f.Kind == domain.CustomHidden {
+				f.Value = ""
+			}
+			out.CustomFields[i] = f
+		}
+	}
+	return &out
+}
+
+// secretFields devuelve solo los campos secretos (lo que entrega Reveal en los tipos que no son login).
+func secretFields(s *domain.Secret) map[string]string {
+	out := map[string]string{}
+	for k, v := range s.Fields {
+		if itemSchemas[s.Type].fields[k].secret {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// publicValues son los campos no secretos que entran en el índice de búsqueda.
+func publicValues(s *domain.Secret) []string {
+	var out []string
+	for k, v := range s.Fields {
+		if !itemSchemas[s.Type].fields[k].secret {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// --- validadores ---
+
+// checkCardNumber admite espacios y guiones y exige 12-19 dígitos con el dígito de control de Luhn.
+func checkCardNumber(v string) (string, error) {
+	n := strings.NewReplacer(" ", "", "-", "").Replace(v)
+	if _, err := checkDigits(12, 19)(n); err != nil {
+		return "", err
+	}
+	sum := 0
+	for i := range n {
+		d := int(n[len(n)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	if sum%10 != 0 {
+		return "", errors.New("invalid card number")
+	}
+	return n, nil
+}
+
+func finishCard(f map[string]string) error {
+	f["last4"] = f["number"][len(f["number"])-4:]
+	return nil
+}
+
+func checkDigits(min, max int) func(string) (string, error) {
+	return func(v string) (string, error) {
+		if len(v) < min || len(v) > max || strings.Trim(v, "0123456789") != "" {
+			return "", fmt.Errorf("must be %d to %d digits", min, max)
+		}
+		return v, nil
+	}
+}
+
+func checkMonth(v string) (string, error) {
+	m, err := strconv.Atoi(v)
+	if err != nil || m < 1 || m > 12 {
+		return "", errors.New("must be a month between 1 and 12")
+	}
+	return fmt.Sprintf("%02d", m), nil
+}
+
+// checkYear acepta el año con 2 o 4 cifras (sin signo) y lo guarda con 4.
+func checkYear(v string) (string, error) {
+	if _, err := checkDigits(2, 4)(v); err != nil || len(v) == 3 {
+		return "", errors.New("must be a year like 2030")
+	}
+	y, _ := strconv.Atoi(v)
+	if len(v) == 2 {
+		y += 2000
+	}
+	if y < 1000 {
+		return "", errors.New("must be a year like 2030")
+	}
+	return strconv.Itoa(y), nil
+}
+
+func checkEmail(v string) (string, error) {
+	a, err := mail.ParseAddress(v)
+	if err != nil || a.Address != v {
+		return "", errors.New("invalid email")
+	}
+	return v, nil
+}
+
+func checkDate(v string) (string, error) {
+	if _, err := time.Parse("2006-01-02", v); err != nil {
+		return "", errors.New("must be a date like 2006-01-02")
+	}
+	return v, nil
+}
+
+// finishSSHKey comprueba que la clave privada se pueda leer (con su passphrase si la tiene) y deriva de
+// ella la pública y la huella; una pública enviada que no corresponda se rechaza.
+func finishSSHKey(f map[string]string) error {
+	raw, err := ssh.ParseRawPrivateKey([]byte(f["private_key"]))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if f["passphrase"] == "" {
+			return errors.New("field passphrase required: private_key is encrypted")
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(f["private_key"]), []byte(f["passphrase"]))
+	}
+	if err != nil {
+		return errors.New("field private_key: not a valid private key (or wrong passphrase)")
+	}
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return fmt.Errorf("field private_key: %w", err)
+	}
+	pub := signer.PublicKey()
+	derived := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if given := f["public_key"]; given != "" {
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(given))
+		if err != nil {
+			return errors.New("field public_key: not a valid public key")
+		}
+		if ssh.FingerprintSHA256(parsed) != ssh.FingerprintSHA256(pub) {
+			return errors.New("field public_key does not match private_key")
+		}
+		derived = given // se conserva el comentario que traiga
+	}
+	f["public_key"] = derived
+	f["fingerprint"] = ssh.FingerprintSHA256(pub)
+	return nil
+}
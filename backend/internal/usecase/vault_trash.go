@@ -0,0 +1,101 @@
+// Papelera del vault: una entrada borrada deja de verse pero se puede recuperar hasta que se borra del
+// todo, a mano o con la purga automática tras el periodo de retención. El borrado definitivo se lleva
+// también los adjuntos y el historial de la entrada.
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"password-danie/internal/domain"
+)
+
+// ListTrash devuelve la papelera como List: lo último borrado primero y sin los campos secretos.
+func (v *Vault) ListTrash(userID int64, limit, offset int) ([]domain.Secret, int, error) {
+	items, total, err := v.secrets.ListTrash(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		d, err := v.decoded(&items[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		items[i] = *concealed(d)
+	}
+	return items, total, nil
+}
+
+// Restore saca la entrada de la papelera tal como estaba.
+func (v *Vault) Restore(userID, id int64, ip, userAgent string) error {
+	err := v.untrash(userID, id)
+	v.record(userID, domain.AuditSecretRestore, id, ip, userAgent, err)
+	return err
+}
+
+// Purge borra del todo una entrada de la papelera.
+func (v *Vault) Purge(userID, id int64, ip, userAgent string) error {
+	err := v.purge(userID, id)
+	v.record(userID, domain.AuditSecretPurge, id, ip, userAgent, err)
+	return err
+}
+
+// PurgeExpired borra del todo, por lotes, las entradas de cualquier usuario que llevan en la papelera más
+// de retention, y devuelve cuántas. Cada borrado queda auditado sin IP ni user agent.
+func (v *Vault) PurgeExpired(retention time.Duration, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	before := time.Now().Add(-retention)
+	purged := 0
+	for {
+		batch, err := v.secrets.ListExpiredTrash(before, batchSize)
+		if err != nil {
+			return purged, err
+		}
+		if len(batch) == 0 {
+			return purged, nil
+		}
+		for _, s := range batch {
+			err := v.purge(s.UserID, s.ID)
+			v.record(s.UserID, domain.AuditSecretPurge, s.ID, "", "", err)
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+}
+
+func (v *Vault) trash(userID, id int64) error {
+	ok, err := v.secrets.Trash(userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func (v *Vault) untrash(userID, id int64) error {
+	ok, err := v.secrets.Untrash(userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func (v *Vault) purge(userID, id int64) error {
+	s, err := v.secrets.GetTrashed(userID, id)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return errors.New("not found")
+	}
+	return v.attachments.cascade(userID, id, func() error { return v.secrets.Delete(userID, id) })
+}
@@ -0,0 +1,108 @@
+// Validaciones del modo zero-knowledge: parámetros KDF que guarda el servidor y blobs opacos del cliente.
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"password-danie/internal/domain"
+	"password-danie/internal/repository"
+)
+
+const (
+	minPBKDF2Iterations = 100_000
+	minArgon2Iterations = 2
+	minArgon2MemoryKiB  = 19_456 // recomendación OWASP para argon2id
+	maxArgon2Threads    = 16
+	minKDFSaltBytes     = 16
+	maxBlobBytes        = 64 << 10
+	preloginKeyName     = "prelogin-decoy"
+	preloginKeyBytes    = 32
+)
+
+// decoyProfiles son configuraciones KDF habituales en los clientes; cada email sin parámetros propios
+// recibe siempre la misma.
+var decoyProfiles = []domain.KDFParams{
+	{Algorithm: domain.KDFPBKDF2SHA256, Iterations: 600_000},
+	{Algorithm: domain.KDFArgon2id, Iterations: 3, Memory: 64 * 1024, Parallelism: 4},
+	{Algorithm: domain.KDFArgon2id, Iterations: 2, Memory: minArgon2MemoryKiB, Parallelism: 1},
+}
+
+// PreloginKey devuelve la clave de los parámetros de relleno de Prelogin, creándola la primera vez. Se
+// guarda aparte de la clave maestra para que esos parámetros no cambien al rotarla (las cuentas reales
+// tampoco cambian los suyos).
+func PreloginKey(keys repository.KeyRepo) ([]byte, error) {
+	key, err := keys.GetServerKey(preloginKeyName)
+	if err != nil || key != nil {
+		return key, err
+	}
+	key = make([]byte, preloginKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	// Si otra instancia la creó a la vez, gana la primera: se relee la guardada.
+	if err := keys.CreateServerKey(preloginKeyName, key); err != nil {
+		return nil, err
+	}
+	return keys.GetServerKey(preloginKeyName)
+}
+
+// decoyKDF da, para un email sin parámetros KDF propios, unos de relleno con la forma de los de una cuenta
+// real: perfil y salt salen de un HMAC del email, así que la misma petición recibe siempre la misma respuesta.
+func decoyKDF(key []byte, email string) (*domain.KDFParams, error) {
+	if len(key) == 0 {
+		return nil, errors.New("prelogin key not configured")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(email))
+	sum := mac.Sum(nil)
+	kdf := decoyProfiles[int(sum[minKDFSaltBytes])%len(decoyProfiles)]
+	kdf.Salt = base64.StdEncoding.EncodeToString(sum[:minKDFSaltBytes])
+	return &kdf, nil
+}
+
+// validateKDF comprueba que los parámetros sean lo bastante costosos; el servidor no deriva nada con ellos.
+func validateKDF(k domain.KDFParams) error {
+	salt, err := base64.StdEncoding.DecodeString(k.Salt)
+	if err != nil || len(salt) < minKDFSaltBytes {
+		return fmt.Errorf("kdf salt must be base64 of at least %d bytes", minKDFSaltBytes)
+	}
+	switch k.Algorithm {
+	case domain.KDFPBKDF2SHA256:
+		if k.Iterations < minPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2 iterations must be >= %d", minPBKDF2Iterations)
+		}
+		if k.Memory != 0 || k.Parallelism != 0 {
+			return errors.New("pbkdf2 does not take memory or parallelism")
+		}
+	case domain.KDFArgon2id:
+		if k.Iterations < minArgon2Iterations {
+			return fmt.Errorf("argon2id iterations must be >= %d", minArgon2Iterations)
+		}
+		if k.Memory < minArgon2MemoryKiB {
+			return fmt.Errorf("argon2id memory must be >= %d KiB", minArgon2MemoryKiB)
+		}
+		if k.Parallelism < 1 || k.Parallelism > maxArgon2Threads {
+			return fmt.Errorf("argon2id parallelism must be between 1 and %d", maxArgon2Threads)
+		}
+	default:
+		return fmt.Errorf("unsupported kdf %q", k.Algorithm)
+	}
+	return nil
+}
+
+// validateBlob solo comprueba forma y tamaño: el contenido es opaco para el servidor.
+func validateBlob(blob string) error {
+	raw, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil || len(raw) == 0 {
+		return errors.New("blob must be non-empty base64")
+	}
+	if len(raw) > maxBlobBytes {
+		return fmt.Errorf("blob exceeds %d bytes", maxBlobBytes)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+-- Auditoría de revelados: cada lectura de la contraseña en claro queda registrada.
+CREATE TABLE IF NOT EXISTS secret_reveals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    secret_id INTEGER NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    revealed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_reveals_user   ON secret_reveals(user_id);
+CREATE INDEX IF NOT EXISTS idx_secret_reveals_secret ON secret_reveals(secret_id);
@@ -0,0 +1,11 @@
+-- Cifrado por sobres: una clave de datos por usuario, guardada envuelta con la clave maestra.
+CREATE TABLE IF NOT EXISTS user_keys (
+    user_id INTEGER PRIMARY KEY,
+    wrapped_key TEXT NOT NULL,
+    wrapped_iv TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 0 = legado (AES_KEY directa), 1 = clave de datos del usuario
+ALTER TABLE secrets ADD COLUMN enc_scheme INTEGER NOT NULL DEFAULT 0;
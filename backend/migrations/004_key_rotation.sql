@@ -0,0 +1,19 @@
+-- Versionado de claves maestras y estado de los trabajos de rotación (reanudables).
+ALTER TABLE secrets ADD COLUMN key_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_keys ADD COLUMN key_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS key_rotations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_version INTEGER NOT NULL,
+    phase TEXT NOT NULL DEFAULT 'user_keys',
+    last_id INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'running',
+    error TEXT NOT NULL DEFAULT '',
+    started_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_secrets_key_version   ON secrets(enc_scheme, key_version);
+CREATE INDEX IF NOT EXISTS idx_user_keys_key_version ON user_keys(key_version);
@@ -0,0 +1,9 @@
+-- Modo zero-knowledge opcional: parámetros KDF por usuario y blobs opacos cifrados en el cliente.
+ALTER TABLE users ADD COLUMN vault_mode TEXT NOT NULL DEFAULT 'server';
+ALTER TABLE users ADD COLUMN kdf_algorithm TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN kdf_iterations INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN kdf_memory INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN kdf_parallelism INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN kdf_salt TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE secrets ADD COLUMN blob TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- Campos sensibles cifrados con la clave de datos + índices ciegos para filtrar por dominio y buscar por tokens.
+ALTER TABLE secrets ADD COLUMN domain_bidx TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS secret_search (
+    secret_id INTEGER NOT NULL,
+    token TEXT NOT NULL,
+    PRIMARY KEY(secret_id, token),
+    FOREIGN KEY(secret_id) REFERENCES secrets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_search_token ON secret_search(token);
+CREATE INDEX IF NOT EXISTS idx_secrets_domain_bidx ON secrets(user_id, domain_bidx);
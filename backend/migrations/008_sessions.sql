@@ -0,0 +1,11 @@
+-- Sesiones de login: cada access token lleva el id de su sesión (sid) y el middleware rechaza los de
+-- sesiones revocadas. El id coincide con la familia de refresh tokens nacida en ese login.
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
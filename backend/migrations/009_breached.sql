@@ -0,0 +1,2 @@
+-- Marca las entradas del vault cuya contraseña aparece en el volcado local de filtraciones (HIBP).
+ALTER TABLE secrets ADD COLUMN breached INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,37 @@
+-- Segundo factor TOTP. El secreto se cifra con la clave maestra (key_version, como los secretos
+-- legados) y last_step guarda el último paso aceptado para que un código no valga dos veces.
+-- enabled_at NULL = alta pendiente de confirmar con un primer código.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY,
+    secret_cipher TEXT NOT NULL,
+    secret_iv TEXT NOT NULL,
+    key_version INTEGER NOT NULL DEFAULT 1,
+    last_step INTEGER NOT NULL DEFAULT 0,
+    enabled_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Códigos de recuperación de un solo uso: solo se guarda su hash SHA-256.
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at DATETIME NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Retos de login: tras la contraseña se entrega un token opaco de vida corta que se canjea con el
+-- segundo factor. Intentos limitados y de un solo uso.
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at DATETIME NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_user ON mfa_recovery_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_totp_key_version ON user_totp(key_version);
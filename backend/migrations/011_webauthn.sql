@@ -0,0 +1,38 @@
+-- Passkeys (WebAuthn). Cada usuario tiene un user handle aleatorio (no se expone su id) y sus
+-- credenciales guardan la clave pública COSE y el contador de firmas para detectar clones.
+CREATE TABLE IF NOT EXISTS webauthn_users (
+    user_id INTEGER PRIMARY KEY,
+    handle TEXT NOT NULL UNIQUE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    credential_id TEXT NOT NULL UNIQUE,
+    public_key BLOB NOT NULL,
+    attestation_type TEXT NOT NULL DEFAULT '',
+    transports TEXT NOT NULL DEFAULT '',
+    aaguid BLOB NULL,
+    sign_count INTEGER NOT NULL DEFAULT 0,
+    backup_eligible INTEGER NOT NULL DEFAULT 0,
+    backup_state INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME NULL,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Estado de una ceremonia en curso (reto incluido). Se identifica con un token opaco del que solo
+-- se guarda el hash y se consume una sola vez. user_id 0 = login sin usuario (passkey descubrible).
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    purpose TEXT NOT NULL,
+    data TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);
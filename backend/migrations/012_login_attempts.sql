@@ -0,0 +1,10 @@
+-- Contadores de fallos para frenar fuerza bruta en login y reset. key es "<ámbito>:account:<email>"
+-- o "<ámbito>:ip:<ip>". El contador se reinicia si el último fallo es más antiguo que la ventana.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure DATETIME NOT NULL,
+    locked_until DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked ON login_attempts(locked_until);
@@ -0,0 +1,3 @@
+-- Semilla TOTP/HOTP de la cuenta guardada en la entrada: URI otpauth:// sellada con la clave de datos
+-- como el resto de campos sensibles ('' = sin semilla).
+ALTER TABLE secrets ADD COLUMN otp TEXT NOT NULL DEFAULT '';
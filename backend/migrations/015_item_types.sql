@@ -0,0 +1,5 @@
+-- Tipos de entrada: las filas existentes son logins (usuario, contraseña y URL en sus columnas de siempre).
+-- Los demás tipos guardan sus campos en payload, un JSON sellado con la clave de datos.
+ALTER TABLE secrets ADD COLUMN item_type TEXT NOT NULL DEFAULT 'login';
+ALTER TABLE secrets ADD COLUMN payload TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_secrets_user_type ON secrets(user_id, item_type);
@@ -0,0 +1,3 @@
+-- Campos personalizados de cada entrada: lista ordenada (nombre, valor, tipo) sellada como JSON con la
+-- clave de datos, igual que la contraseña. Vacío = sin campos.
+ALTER TABLE secrets ADD COLUMN custom_fields TEXT NOT NULL DEFAULT '';
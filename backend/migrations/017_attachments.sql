@@ -0,0 +1,16 @@
+-- Adjuntos de las entradas del vault. El contenido vive cifrado en el almacén de blobs (blob_key) y aquí
+-- quedan el nombre y el tipo sellados, el tamaño en claro (para la cuota) y la clave del fichero envuelta.
+CREATE TABLE IF NOT EXISTS attachments(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  secret_id INTEGER NOT NULL REFERENCES secrets(id) ON DELETE CASCADE,
+  blob_key TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  content_type TEXT NOT NULL DEFAULT '',
+  size INTEGER NOT NULL,
+  key_cipher TEXT NOT NULL,
+  key_iv TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_secret ON attachments(secret_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user ON attachments(user_id);
@@ -0,0 +1,10 @@
+-- Historial de versiones de las entradas del vault: antes de cada cambio se guardan los valores anteriores
+-- de los campos que cambian, sellados como JSON con la clave de datos del usuario.
+CREATE TABLE IF NOT EXISTS secret_history(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  secret_id INTEGER NOT NULL REFERENCES secrets(id) ON DELETE CASCADE,
+  payload TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_secret_history_secret ON secret_history(secret_id, id);
@@ -0,0 +1,4 @@
+-- Papelera: borrar una entrada solo marca deleted_at. Las entradas con deleted_at no salen en el vault y se
+-- borran de verdad al vaciarlas de la papelera o cuando pasa el periodo de retención.
+ALTER TABLE secrets ADD COLUMN deleted_at DATETIME NULL;
+CREATE INDEX IF NOT EXISTS idx_secrets_deleted_at ON secrets(deleted_at);
@@ -0,0 +1,7 @@
+-- Claves propias del servidor que no dependen de la clave maestra (p. ej. la de los parámetros KDF de
+-- relleno del prelogin): se generan una vez y no cambian al rotar la clave maestra.
+CREATE TABLE IF NOT EXISTS server_keys(
+  name TEXT PRIMARY KEY,
+  value BLOB NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -10,6 +10,8 @@ import (
 	"strings"
 )
 
+// ApplyMigrations aplica en orden los .sql de dir que aún no constan en schema_migrations.
+// Registrar cada fichero permite migraciones no idempotentes (ALTER TABLE ...).
 func ApplyMigrations(sqlDB *sql.DB, dir string) error {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -19,6 +21,12 @@ func ApplyMigrations(sqlDB *sql.DB, dir string) error {
 		}
 		return err
 	}
+	if _, err := sqlDB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
 	// ordenar por nombre para aplicar 001_, 002_, ...
 	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
 
@@ -26,33 +34,96 @@ func ApplyMigrations(sqlDB *sql.DB, dir string) error {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
 			continue
 		}
+		var applied int
+		if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name = ?`, e.Name()).Scan(&applied); err != nil {
+			return fmt.Errorf("check migration %s: %w", e.Name(), err)
+		}
+		if applied > 0 {
+			continue
+		}
 		path := filepath.Join(dir, e.Name())
 		b, err := os.ReadFile(path)
 		if err != nil {
 			return fmt.Errorf("read migration %s: %w", path, err)
 		}
-		sqlText := string(b)
-		stmts := splitSQL(sqlText)
-		for _, s := range stmts {
-			if strings.TrimSpace(s) == "" {
-				continue
-			}
-			if _, err := sqlDB.Exec(s); err != nil {
-				return fmt.Errorf("exec migration %s: %w", path, err)
-			}
+		if err := applyMigration(sqlDB, path, e.Name(), string(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration ejecuta las sentencias de un fichero y lo registra en una sola transacción: si algo
+// falla a medias no queda aplicado en parte y se reintenta entero en el siguiente arranque.
+func applyMigration(sqlDB *sql.DB, path, name, sqlText string) error {
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		return fmt.Errorf("begin migration %s: %w", path, err)
+	}
+	defer tx.Rollback()
+	for _, s := range splitSQL(sqlText) {
+		if strings.TrimSpace(s) == "" {
+			continue
 		}
+		if _, err := tx.Exec(s); err != nil {
+			return fmt.Errorf("exec migration %s: %w", path, err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations(name) VALUES(?)`, name); err != nil {
+		return fmt.Errorf("record migration %s: %w", path, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %s: %w", path, err)
 	}
 	return nil
 }
 
+// splitSQL separa por ";" salvo dentro del cuerpo BEGIN ... END de un CREATE TRIGGER.
+// Los comentarios "--" se quitan antes, para que un ";" en ellos no parta una sentencia.
 func splitSQL(sql string) []string {
-	parts := strings.Split(sql, ";")
+	parts := strings.Split(stripComments(sql), ";")
 	out := make([]string, 0, len(parts))
+	var pending string
 	for _, p := range parts {
+		if pending != "" {
+			p = pending + ";" + p
+			pending = ""
+		}
 		p = strings.TrimSpace(p)
+		upper := strings.ToUpper(p)
+		if strings.Contains(upper, "CREATE TRIGGER") && !strings.HasSuffix(upper, "END") {
+			pending = p
+			continue
+		}
 		if p != "" {
 			out = append(out, p)
 		}
 	}
+	if pending != "" {
+		out = append(out, pending)
+	}
 	return out
 }
+
+// stripComments quita los comentarios "--" hasta el final de la línea, salvo dentro de un literal '...'.
+func stripComments(sql string) string {
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'':
+			inString = !inString
+		case !inString && ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			if i < len(sql) {
+				b.WriteByte('\n')
+			}
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}